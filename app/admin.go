@@ -0,0 +1,388 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The admin operations live here once, adminServer exposes them over gRPC
+// and the admin* handlers over HTTP.
+
+const citiesServicePrefix = "/cities.CitiesService/"
+
+// maintenance rejects the calls of CitiesService and the REST data routes
+// while it is enabled. Other services, the admin one in particular, keep
+// working.
+type maintenance struct {
+	mu      sync.RWMutex
+	enabled bool
+	reason  string
+}
+
+func (m *maintenance) Get() (bool, string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled, m.reason
+}
+
+func (m *maintenance) Set(enabled bool, reason string) {
+	m.mu.Lock()
+	m.enabled, m.reason = enabled, reason
+	m.mu.Unlock()
+	log.Printf("maintenance mode enabled=%t reason=%q", enabled, reason)
+}
+
+func (m *maintenance) check(method string) error {
+	if !strings.HasPrefix(method, citiesServicePrefix) {
+		return nil
+	}
+	if enabled, reason := m.Get(); enabled {
+		return status.Errorf(codes.Unavailable, "server in maintenance: %s", reason)
+	}
+	return nil
+}
+
+func (m *maintenance) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := m.check(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (m *maintenance) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := m.check(info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// HTTP rejects the REST data routes with 503 and a grpcerr.HTTPError body
+// while maintenance is enabled.
+func (m *maintenance) HTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if enabled, reason := m.Get(); enabled {
+			err := status.Errorf(codes.Unavailable, "server in maintenance: %s", reason)
+			grpcerr.WriteJSON(w, err, ctxmeta.RequestID(r.Context()))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// errCancelledByAdmin is the cause of the calls cancelled through the admin
+// API, the client sees it as the status of the call.
+var errCancelledByAdmin = status.Error(codes.Canceled, "cancelled by the admin API")
+
+// callRegistry keeps the running CitiesService calls, so the calls with a
+// request-id can be cancelled from outside and the watchdog finds the ones
+// running too long.
+type callRegistry struct {
+	mu    sync.Mutex
+	next  int
+	calls map[int]*registeredCall
+}
+
+type registeredCall struct {
+	id     string
+	method string
+	start  time.Time
+	cancel context.CancelCauseFunc
+}
+
+func newCallRegistry() *callRegistry {
+	return &callRegistry{calls: make(map[int]*registeredCall)}
+}
+
+// track registers the call, done must be called when it ends.
+func (c *callRegistry) track(ctx context.Context, method string) (context.Context, func()) {
+	if !strings.HasPrefix(method, citiesServicePrefix) {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	c.mu.Lock()
+	c.next++
+	n := c.next
+	c.calls[n] = &registeredCall{id: requestID(ctx), method: method, start: time.Now(), cancel: cancel}
+	c.mu.Unlock()
+
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.calls, n)
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Cancel cancels every running call with the request id and returns how many
+// there were.
+func (c *callRegistry) Cancel(id string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, call := range c.calls {
+		if call.id == id && id != "-" {
+			call.cancel(errCancelledByAdmin)
+			n++
+		}
+	}
+	return n
+}
+
+// IDs returns the request ids of the running calls.
+func (c *callRegistry) IDs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[string]bool, len(c.calls))
+	ids := make([]string, 0, len(c.calls))
+	for _, call := range c.calls {
+		if call.id != "-" && !seen[call.id] {
+			seen[call.id] = true
+			ids = append(ids, call.id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Overdue returns the calls started before since.
+func (c *callRegistry) Overdue(since time.Time) []*registeredCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var overdue []*registeredCall
+	for _, call := range c.calls {
+		if call.start.Before(since) {
+			overdue = append(overdue, call)
+		}
+	}
+	return overdue
+}
+
+func (c *callRegistry) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, done := c.track(ctx, info.FullMethod)
+	defer done()
+	return handler(ctx, req)
+}
+
+func (c *callRegistry) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, done := c.track(ss.Context(), info.FullMethod)
+	defer done()
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// adminAuth requires the admin token from every AdminService call. It is a
+// credential of its own, not shared with anything the other services use.
+type adminAuth string
+
+func (token adminAuth) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, "/cities.AdminService/") {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := md.Get("authorization")
+	if len(v) == 0 || subtle.ConstantTimeCompare([]byte(v[0]), []byte("Bearer "+string(token))) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "admin token required")
+	}
+	return handler(ctx, req)
+}
+
+// HTTP requires the admin token from the requests to next, the /admin
+// routes of the REST server, as a bearer token in the Authorization header.
+func (token adminAuth) HTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+string(token))) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "admin token required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type adminServer struct {
+	maintenance *maintenance
+	calls       *callRegistry
+}
+
+func (a *adminServer) GetMaintenance(ctx context.Context, in *cities.EmptyMessage) (*cities.Maintenance, error) {
+	enabled, reason := a.maintenance.Get()
+	return &cities.Maintenance{Enabled: enabled, Reason: reason}, nil
+}
+
+func (a *adminServer) SetMaintenance(ctx context.Context, in *cities.Maintenance) (*cities.Maintenance, error) {
+	a.maintenance.Set(in.GetEnabled(), in.GetReason())
+	return a.GetMaintenance(ctx, &cities.EmptyMessage{})
+}
+
+func (a *adminServer) CancelCall(ctx context.Context, in *cities.CancelCallRequest) (*cities.CancelCallResponse, error) {
+	if in.GetRequestId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "request_id is required")
+	}
+	return &cities.CancelCallResponse{Cancelled: uint32(a.calls.Cancel(in.GetRequestId()))}, nil
+}
+
+func (a *adminServer) GetLatency(ctx context.Context, in *cities.EmptyMessage) (*cities.Latency, error) {
+	return &cities.Latency{ExtraMs: time.Duration(extraLatency.Load()).Milliseconds()}, nil
+}
+
+func (a *adminServer) SetLatency(ctx context.Context, in *cities.Latency) (*cities.Latency, error) {
+	if in.GetExtraMs() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "extra_ms must not be negative")
+	}
+	extraLatency.Store(int64(time.Duration(in.GetExtraMs()) * time.Millisecond))
+	log.Printf("extra latency set to %dms", in.GetExtraMs())
+	return a.GetLatency(ctx, &cities.EmptyMessage{})
+}
+
+func (a *adminServer) GetStaticSnapshot(ctx context.Context, in *cities.EmptyMessage) (*cities.StaticSnapshot, error) {
+	_, enabled := staticSnapshot()
+	return &cities.StaticSnapshot{Enabled: enabled}, nil
+}
+
+func (a *adminServer) SetStaticSnapshot(ctx context.Context, in *cities.StaticSnapshot) (*cities.StaticSnapshot, error) {
+	setStaticSnapshot(in.GetEnabled())
+	return a.GetStaticSnapshot(ctx, &cities.EmptyMessage{})
+}
+
+func (a *adminServer) ReloadConfig(ctx context.Context, in *cities.EmptyMessage) (*cities.EmptyMessage, error) {
+	if err := reloadLogSettings(); err != nil {
+		if errors.Is(err, errNoLogConfig) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "reload: %v", err)
+	}
+	return &cities.EmptyMessage{}, nil
+}
+
+// adminMaintenance shows the maintenance mode on GET and sets it on PUT, with
+// the body {"enabled":true,"reason":"..."}.
+func (a *adminServer) adminMaintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var in cities.Maintenance
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			grpcerr.WriteJSON(w, status.Error(codes.InvalidArgument, err.Error()), ctxmeta.RequestID(r.Context()))
+			return
+		}
+		a.SetMaintenance(r.Context(), &in)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	enabled, reason := a.maintenance.Get()
+	writeJSON(w, map[string]interface{}{"enabled": enabled, "reason": reason})
+}
+
+// adminCalls lists the request ids of the running calls on GET and cancels
+// the calls of ?request_id= on DELETE.
+func (a *adminServer) adminCalls(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, map[string][]string{"request_ids": a.calls.IDs()})
+	case http.MethodDelete:
+		out, err := a.CancelCall(r.Context(), &cities.CancelCallRequest{RequestId: r.URL.Query().Get("request_id")})
+		if err != nil {
+			grpcerr.WriteJSON(w, err, ctxmeta.RequestID(r.Context()))
+			return
+		}
+		writeJSON(w, map[string]uint32{"cancelled": out.GetCancelled()})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// adminLatency shows the extra latency on GET and sets it on PUT, with the
+// body {"extra_ms":250}.
+func (a *adminServer) adminLatency(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var in cities.Latency
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			grpcerr.WriteJSON(w, status.Error(codes.InvalidArgument, err.Error()), ctxmeta.RequestID(r.Context()))
+			return
+		}
+		if _, err := a.SetLatency(r.Context(), &in); err != nil {
+			grpcerr.WriteJSON(w, err, ctxmeta.RequestID(r.Context()))
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	out, _ := a.GetLatency(r.Context(), &cities.EmptyMessage{})
+	writeJSON(w, map[string]int64{"extra_ms": out.GetExtraMs()})
+}
+
+// adminStaticSnapshot shows the static snapshot mode on GET and sets it on
+// PUT, with the body {"enabled":true}.
+func (a *adminServer) adminStaticSnapshot(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var in cities.StaticSnapshot
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			grpcerr.WriteJSON(w, status.Error(codes.InvalidArgument, err.Error()), ctxmeta.RequestID(r.Context()))
+			return
+		}
+		a.SetStaticSnapshot(r.Context(), &in)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	out, _ := a.GetStaticSnapshot(r.Context(), &cities.EmptyMessage{})
+	writeJSON(w, map[string]bool{"enabled": out.GetEnabled()})
+}
+
+// adminReload re-reads LOG_CONFIG on POST.
+func (a *adminServer) adminReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := a.ReloadConfig(r.Context(), &cities.EmptyMessage{}); err != nil {
+		grpcerr.WriteJSON(w, err, ctxmeta.RequestID(r.Context()))
+		return
+	}
+	writeJSON(w, currentLogSettings())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Println("error marshalling result", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.Println("error writing result", err)
+	}
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-cancel/deadline"
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type admissionJob struct {
+	ctx  context.Context
+	run  func()
+	done chan struct{}
+	err  error
+}
+
+// admissionQueue runs unary handlers on a fixed pool of workers. Work waits
+// in a bounded queue, and a job whose caller has gone away, or whose deadline
+// leaves less than minBudget, is dropped when it is dequeued instead of
+// being executed.
+type admissionQueue struct {
+	jobs      chan *admissionJob
+	minBudget time.Duration
+	workers   int
+	// strict observes the handlers on the workers, nil without strict mode.
+	strict *strictCancellation
+}
+
+func newAdmissionQueue(workers, size int, minBudget time.Duration) *admissionQueue {
+	return &admissionQueue{
+		jobs:      make(chan *admissionJob, size),
+		minBudget: minBudget,
+		workers:   workers,
+	}
+}
+
+// serve runs the workers until ctx ends. The jobs still queued then are
+// left, their callers give up with the context of their call.
+func (q *admissionQueue) serve(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.worker(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *admissionQueue) worker(ctx context.Context) {
+	for {
+		var job *admissionJob
+		select {
+		case job = <-q.jobs:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := q.stale(job.ctx); err != nil {
+			rejectedStale.Add(1)
+			job.err = err
+			close(job.done)
+			continue
+		}
+
+		job.run()
+		close(job.done)
+	}
+}
+
+// run calls handler on the worker. A panic of the handler is not caught by
+// recoveryUnary, which runs on the goroutine of the call, so it is turned
+// into an Internal error here.
+func (q *admissionQueue) run(ctx context.Context, method string, handler grpc.UnaryHandler, req interface{}) (resp interface{}, err error) {
+	defer recovered(ctx, method, &err)
+	return q.strict.call(ctx, method, handler, req)
+}
+
+func (q *admissionQueue) stale(ctx context.Context) error {
+	if err := grpcerr.FromContext(ctx); err != nil {
+		return err
+	}
+
+	if remaining, ok := deadline.RemainingBudget(ctx); ok && remaining < q.minBudget {
+		return status.Error(codes.DeadlineExceeded, "deadline is exceeded while queued")
+	}
+
+	return nil
+}
+
+// Unary is the interceptor putting every unary call through the queue.
+func (q *admissionQueue) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var (
+		resp interface{}
+		err  error
+	)
+
+	job := &admissionJob{ctx: ctx, done: make(chan struct{})}
+	job.run = func() {
+		resp, err = q.run(ctx, info.FullMethod, handler, req)
+	}
+
+	select {
+	case q.jobs <- job:
+	default:
+		return nil, status.Error(codes.ResourceExhausted, "admission queue is full")
+	}
+
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+		// The worker drops the job when it gets to it.
+		return nil, grpcerr.FromContext(ctx)
+	}
+
+	if job.err != nil {
+		return nil, job.err
+	}
+	return resp, err
+}
+
+// Depth is the number of jobs waiting for a worker.
+func (q *admissionQueue) Depth() int {
+	return len(q.jobs)
+}
+
+// Cap is the number of jobs the queue holds.
+func (q *admissionQueue) Cap() int {
+	return cap(q.jobs)
+}
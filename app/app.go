@@ -0,0 +1,532 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"go-cancel/config"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// App is the whole cities stack: the gRPC server, the REST gateway and the
+// repository and middleware behind them, as the server command runs it.
+// Other programs embed it as a realistic upstream that honours
+// cancellation, for example in their integration tests:
+//
+//	cfg := config.DefaultServer()
+//	cfg.GRPCListen, cfg.RESTListen = "127.0.0.1:0", "127.0.0.1:0"
+//	a := app.New(cfg, nil)
+//	if err := a.Start(ctx); err != nil { ... }
+//	defer a.Stop(context.Background())
+//	conn, err := grpc.Dial(a.GRPCAddr(), grpc.WithInsecure())
+//
+// Some settings are process wide, the simulator, the GC and the metrics, so
+// a process runs one App at a time.
+type App struct {
+	cfg    config.Server
+	values map[string]string
+
+	servers        *supervisor
+	ready          *readiness
+	strict         *strictCancellation
+	cancelHandlers context.CancelCauseFunc
+	closers        []func() error
+	// background is the work Start runs beside the servers, it stops with
+	// them.
+	background sync.WaitGroup
+	restore    func()
+
+	done chan struct{}
+	err  error
+}
+
+// New returns the stack for cfg, values describes the settings for the
+// server info, usually config.Loader.Values. Nil reports none.
+func New(cfg config.Server, values map[string]string) *App {
+	return &App{cfg: cfg, values: values, done: make(chan struct{})}
+}
+
+// Start builds the stack and starts the servers, it returns once they all
+// listen, or with the error of the first one that failed. ctx is the root
+// of everything the stack does, handlers included: cancelling it cancels
+// the calls in flight and stops the servers without draining them.
+func (a *App) Start(ctx context.Context) (err error) {
+	cfg, values := a.cfg, a.values
+	select {
+	case <-a.done:
+		// A stopped App starts over.
+		a.done, a.err = make(chan struct{}), nil
+	default:
+	}
+
+	a.restore = saveProcessSettings()
+
+	// ctx ends once the servers stopped, background work started below
+	// stops then.
+	ctx, cancel := context.WithCancel(ctx)
+
+	// handlers is the root of the gRPC and REST handler contexts. A server
+	// failing cancels it, the servers stopping too.
+	handlers, cancelHandlers := context.WithCancelCause(ctx)
+	a.cancelHandlers = cancelHandlers
+	defer func() {
+		if err != nil {
+			a.close()
+			cancelHandlers(nil)
+			cancel()
+			a.background.Wait()
+			a.restore()
+		}
+	}()
+
+	ids, err := newIDGenerator(cfg.IDGenerator, cfg.NodeID)
+	if err != nil {
+		return err
+	}
+
+	propagationAlert = cfg.CancelPropagationAlert
+	softDeadlineFraction = cfg.SoftDeadline
+	configureDefaultSimulator(cfg.ListSize, cfg.StreamInterval)
+	if err := configureDefaultNames(cfg.NameLocale); err != nil {
+		return fmt.Errorf("invalid NAME_LOCALE: %w", err)
+	}
+	if cfg.StaticSnapshot {
+		setStaticSnapshot(true)
+	}
+	applyGCSettings(cfg.GCPercent, int64(cfg.GCMemoryLimit), int64(cfg.GCBallast))
+
+	// GRPC_LISTEN and REST_LISTEN take a comma separated list, e.g.
+	// "0.0.0.0:9099,[::]:9099" for separate IPv4 and IPv6 listeners.
+	grpcAddrs := listenAddrs(cfg.GRPCListen, cfg.GRPCDefaultListen())
+	restAddrs := listenAddrs(cfg.RESTListen, cfg.RESTDefaultListen())
+	// With SINGLE_PORT, gRPC shares the REST addresses.
+	if cfg.SinglePort {
+		grpcAddrs = restAddrs
+	}
+
+	if cfg.GRPCWebsocket && buildFlavor == "minimal" {
+		return errors.New("GRPC_WEBSOCKET needs the full build")
+	}
+
+	features := []string{"adaptive-limiter", "repository-retries", "simulator-presets", "city-encryption", "gateway-shedding", "netrpc", "request-coalescing", "grpc-health"}
+	if cfg.CompressionSkipThreshold > 0 {
+		features = append(features, "deadline-compression")
+	}
+	if cfg.GRPCWebsocket {
+		features = append(features, "grpc-websocket")
+	}
+	if cfg.GRPCReflection {
+		features = append(features, "grpc-reflection")
+	}
+	if cfg.RepositoryFixtures != "" {
+		features = append(features, "fixtures")
+	}
+	if cfg.PrimeCache {
+		features = append(features, "cache-priming")
+	}
+	if cfg.AdminToken != "" {
+		features = append(features, "admin-grpc")
+	}
+	if cfg.TLSCert != "" || cfg.TLSSelfSigned {
+		features = append(features, "grpc-tls")
+	}
+	if cfg.TLSClientCA != "" {
+		features = append(features, "grpc-mtls")
+	}
+	if cfg.SinglePort {
+		features = append(features, "single-port")
+	}
+	if cfg.AuditLog != "" {
+		features = append(features, "audit-log")
+	}
+	if cfg.GCBallast > 0 {
+		features = append(features, "gc-ballast")
+	}
+	if cfg.StrictCancellation != "off" {
+		features = append(features, "strict-cancellation")
+	}
+	if cfg.RepositoryLockSlots > 0 {
+		features = append(features, "lock-contention")
+	}
+	if cfg.AuthSecret != "" {
+		features = append(features, "auth")
+	}
+	if cfg.RBACPolicy != "" {
+		features = append(features, "rbac")
+	}
+	if cfg.HandlerCeiling > 0 {
+		features = append(features, "handler-watchdog")
+	}
+	if cfg.RateLimit > 0 {
+		features = append(features, "rate-limit")
+	}
+	if cfg.DefaultDeadline > 0 {
+		features = append(features, "default-deadline")
+	}
+	info := make(map[string]string, len(values))
+	for k, v := range values {
+		info[k] = v
+	}
+	for k, v := range gcConfig() {
+		info[k] = v
+	}
+	info["build"] = buildFlavor
+	info["grpc_listen"] = strings.Join(grpcAddrs, ",")
+	info["rest_listen"] = strings.Join(restAddrs, ",")
+	info["log_config"] = os.Getenv("LOG_CONFIG")
+	serverInfo := newServerInfo(info, features)
+
+	memRepo := newMemoryRepository(50*time.Millisecond, ids)
+	memRepo.conflictRate = cfg.RepositoryConflictRate
+	if cfg.RepositoryFixtures != "" {
+		snap, err := loadFixtureFile(cfg.RepositoryFixtures, ids)
+		if err != nil {
+			return err
+		}
+		memRepo.Restore(snap)
+	}
+
+	// Priming never makes run fail, a failed or cancelled priming
+	// only leaves List generating the cities itself.
+	var cache *listCache
+	if cfg.PrimeCache {
+		cache = &listCache{}
+		a.goBackground(func() {
+			start := time.Now()
+			if err := cache.Prime(ctx, cfg.PrimeTimeout); err != nil {
+				log.Printf("cache priming stopped after %s: %s", time.Since(start), status.Convert(err).Message())
+				return
+			}
+			infof("cache primed in %s", time.Since(start))
+		})
+	}
+
+	events := newBroker()
+	// The contention sits under the retries, a retried transaction waits in
+	// line again.
+	var stored CityRepository = memRepo
+	if cfg.RepositoryLockSlots > 0 {
+		stored = newContentionRepository(memRepo, cfg.RepositoryLockSlots, cfg.RepositoryLockQueue, cfg.RepositoryWriteHold, cfg.RepositoryReadHold)
+	}
+	retrying := newRetryingRepository(stored, 5, 10*time.Millisecond, 200*time.Millisecond)
+	repo := &publishingRepository{CityRepository: retrying, broker: events}
+	creator := newCreateBatcher(repo, 10, 20*time.Millisecond)
+	a.goBackground(func() { creator.run(ctx) })
+
+	stats := newStatsScheduler(repo, 30*time.Second, 10*time.Second)
+	a.goBackground(func() { stats.run(ctx) })
+
+	// The limiter decides how many unary calls run at once, the admission
+	// queue has a worker for each one it can let through.
+	limiter := newGradientLimiter(8, 2, 64, 200*time.Millisecond)
+	var streams grpc.StreamServerInterceptor
+	if cfg.StreamLimit > 0 {
+		streams = newStreamLimiter(cfg.StreamLimit).Stream
+	}
+	admission := newAdmissionQueue(64, 64, 10*time.Millisecond)
+	a.goBackground(func() { admission.serve(ctx) })
+	// Identical reads in flight share one execution, and so one slot of the
+	// limiter and of the admission queue. A shared call has no deadline of
+	// its own, List keeps its callers' with a soft deadline.
+	coalesced := []string{"/cities.CitiesService/Stats", "/cities.CitiesService/GetServerInfo"}
+	if cfg.SoftDeadline == 0 {
+		coalesced = append(coalesced, "/cities.CitiesService/List")
+	}
+	coalesce := newCoalescer(coalesceScope, coalesced...)
+
+	shed := newGatewayShedder(cfg.RESTMaxInflight, cfg.RESTMaxLatency, admission)
+	admin := &adminServer{maintenance: &maintenance{}, calls: newCallRegistry()}
+	if cfg.HandlerCeiling > 0 {
+		dir := cfg.WatchdogDumpDir
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		watchdog := newWatchdog(admin.calls, cfg.HandlerCeiling, dir, "/cities.CitiesService/WatchCities")
+		a.goBackground(func() { watchdog.run(ctx) })
+	}
+
+	opts := []Option{
+		WithRootContext(handlers),
+		WithConfig(cfg),
+		WithHealth(),
+		// Right behind the root, a panic anywhere below fails the call
+		// instead of the process.
+		WithInterceptors(recoveryUnary, recoveryStream),
+	}
+	tlsConfig, err := serverTLS(cfg)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, tlsOptions(tlsConfig)...)
+	// Strict mode sits right behind the root, the interceptors below are
+	// held to cancellation too.
+	a.strict = newStrictCancellation(cfg.StrictCancellation, cfg.StrictCancellationGrace)
+	if a.strict != nil {
+		opts = append(opts, WithInterceptors(a.strict.Unary, a.strict.Stream))
+	}
+	admission.strict, coalesce.strict = a.strict, a.strict
+	// Behind the normalization, which keeps a client deadline as it is. The
+	// cities streams outlast it by design, a default ListStream takes some
+	// 49s, their timeouts.max bounds them instead.
+	deadlines := newDefaultDeadline(cfg.DefaultDeadline,
+		"/cities.CitiesService/WatchCities",
+		"/cities.CitiesService/ListStream",
+		"/cities.CitiesService/ExportCities",
+		"/cities.CitiesService/TransformCities",
+		"/grpc.health.v1.Health/Watch",
+		"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+	)
+	opts = append(opts,
+		WithInterceptors(normalizeDeadlineUnary, normalizeDeadlineStream),
+		// Logs the deadline the client sent, not the default.
+		WithInterceptors(processingTimeUnary, processingTimeStream),
+		WithInterceptors(deadlines.Unary, deadlines.Stream),
+		WithInterceptors(simulatorUnary, simulatorStream),
+	)
+	// The audit log sits outside the admin auth and maintenance, the calls
+	// they reject are recorded too. It is flushed once the servers stopped.
+	var audit *auditLog
+	if cfg.AuditLog != "" {
+		audit, err = newAuditLog(cfg.AuditLog)
+		if err != nil {
+			return fmt.Errorf("invalid AUDIT_LOG: %w", err)
+		}
+		a.closers = append(a.closers, audit.Close)
+		opts = append(opts, WithInterceptors(audit.Unary, audit.Stream))
+	}
+	if cfg.AdminToken != "" {
+		opts = append(opts, WithInterceptors(adminAuth(cfg.AdminToken).Unary, nil))
+	}
+	// The REST routes answered outside the gateway are guarded the same.
+	guard := &restGuard{}
+	if cfg.AuthSecret != "" {
+		auth := newAuthenticator(cfg.AuthSecret, cfg.AuthPublicMethods)
+		opts = append(opts, WithInterceptors(auth.Unary, auth.Stream))
+		guard.Use(auth.Unary)
+	}
+	if cfg.RBACPolicy != "" {
+		rbac, err := newRBAC(cfg.RBACPolicy, cfg.RBACTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid RBAC_POLICY: %w", err)
+		}
+		opts = append(opts, WithInterceptors(rbac.Unary, rbac.Stream))
+		guard.Use(rbac.Unary)
+	}
+	// Behind the authentication, the callers it let in have a bucket each.
+	if cfg.RateLimit > 0 {
+		rate := newClientRateLimiter(cfg.RateLimit, cfg.RateLimitBurst, cfg.RateLimitByPeer)
+		opts = append(opts, WithInterceptors(rate.Unary, rate.Stream))
+		guard.Use(rate.Unary)
+	}
+	opts = append(opts, flavorOptions()...)
+	opts = append(opts,
+		WithInterceptors(admin.maintenance.Unary, admin.maintenance.Stream),
+		WithInterceptors(admin.calls.Unary, admin.calls.Stream),
+		WithInterceptors(coalesce.Unary, nil),
+		WithInterceptors(limiter.Unary, streams),
+		WithInterceptors(admission.Unary, nil),
+		WithInterceptors(methodTimeout(cities.MethodTimeouts).Unary, methodTimeout(cities.MethodTimeouts).Stream),
+		WithDeadlineCompression(cfg.CompressionSkipThreshold),
+		// Innermost, the responses are sealed before anything encodes them.
+		WithInterceptors(encryptionUnary, encryptionStream),
+	)
+	rpcServer := NewServer(opts...)
+	citiesSrv := &citiesServer{repo: repo, creator: creator, stats: stats, broker: events, info: serverInfo, cache: cache}
+	cities.RegisterCitiesServiceServer(rpcServer.Grpc, citiesSrv)
+	netRPC, err := newNetRPCServer(rpcServer, citiesSrv)
+	if err != nil {
+		return err
+	}
+	if cfg.AdminToken != "" {
+		cities.RegisterAdminServiceServer(rpcServer.Grpc, admin)
+	}
+
+	a.goBackground(func() { watchLogReload(ctx) })
+
+	// A server failing outside a shutdown cuts the calls in flight short,
+	// the other servers stop too.
+	servers := newSupervisor(ctx, func(err error) {
+		cancelHandlers(status.Errorf(codes.Unavailable, "server failing: %s", err))
+	})
+	a.servers = servers
+
+	ready := newReadiness(rpcServer, "grpc", "rest")
+	a.ready = ready
+	listenGRPC := func() ([]net.Listener, error) { return listenAll("grpc", grpcAddrs) }
+	listenREST := func() ([]net.Listener, error) { return listenAll("rest", restAddrs) }
+	if cfg.SinglePort {
+		shared := newSharedPort(restAddrs, cfg.RESTReadHeaderTimeout)
+		listenGRPC, listenREST = shared.GRPC, shared.REST
+	}
+	servers.Go("grpc", func(ctx context.Context) error {
+		return runRpcServer(ctx, listenGRPC, rpcServer, cfg.ShutdownTimeout, ready)
+	})
+
+	// The tunnel is one more listener of the gRPC server, runRpcServer
+	// stops it.
+	var tunnel *wsListener
+	if cfg.GRPCWebsocket {
+		tunnel = newWsListener("/grpc-ws")
+		servers.Go("grpc-websocket", func(ctx context.Context) error {
+			return rpcServer.Grpc.Serve(tunnel)
+		})
+	}
+
+	// The REST gateway is one more listener of the gRPC server too.
+	gateway, err := newRestGateway(ctx)
+	if err != nil {
+		return err
+	}
+	servers.Go("rest-gateway", func(ctx context.Context) error {
+		return rpcServer.Grpc.Serve(gateway.Listener())
+	})
+
+	// With REST_TLS the REST server presents the certificate of gRPC.
+	var restTLS *tls.Config
+	if cfg.RESTTLS {
+		restTLS = tlsConfig
+	}
+	servers.Go("rest", func(ctx context.Context) error {
+		return runRestServer(ctx, handlers, cfg, listenREST, restTLS, rpcServer, tunnel, gateway, guard, memRepo, admin, shed, netRPC, audit, ready)
+	})
+
+	go func() {
+		a.err = errors.Join(servers.Wait(), a.strict.Err())
+		gateway.Close()
+		a.close()
+		cancelHandlers(nil)
+		cancel()
+		a.background.Wait()
+		a.restore()
+		close(a.done)
+	}()
+
+	select {
+	case <-ready.Up():
+		return nil
+	case <-a.done:
+		// The cleanup above already ran.
+		return a.err
+	}
+}
+
+// Stop stops the servers, letting the calls in flight finish for up to the
+// shutdown timeout. Should ctx end first, the calls still running are
+// cancelled with errServerShutdown and Stop returns the context error
+// without waiting for them.
+func (a *App) Stop(ctx context.Context) error {
+	if a.servers == nil {
+		return errors.New("app not started")
+	}
+	a.servers.Stop()
+
+	select {
+	case <-a.done:
+		return a.err
+	case <-ctx.Done():
+		a.cancelHandlers(errServerShutdown)
+		return ctx.Err()
+	}
+}
+
+// Wait returns once the servers stopped, after Stop or when one of them
+// failed, with their errors and, in strict fail mode, the handlers that
+// ignored their cancellation.
+func (a *App) Wait() error {
+	<-a.done
+	return a.err
+}
+
+// Done is closed once the servers stopped.
+func (a *App) Done() <-chan struct{} {
+	return a.done
+}
+
+// GRPCAddr is the address of the first gRPC listener, useful when the
+// configuration let the system pick the port, unix:path for a unix socket.
+// It is empty before Start.
+func (a *App) GRPCAddr() string {
+	return a.firstAddr("grpc")
+}
+
+// RESTAddr is the address of the first REST listener.
+func (a *App) RESTAddr() string {
+	return a.firstAddr("rest")
+}
+
+func (a *App) firstAddr(name string) string {
+	if a.ready == nil {
+		return ""
+	}
+	addrs := a.ready.Addrs(name)
+	if len(addrs) == 0 {
+		return ""
+	}
+	// grpc.Dial takes the unix: prefix.
+	if addrs[0].Network() == "unix" {
+		return unixPrefix + addrs[0].String()
+	}
+	return addrs[0].String()
+}
+
+// goBackground runs f beside the servers, f returns once the context of
+// Start ends.
+func (a *App) goBackground(f func()) {
+	a.background.Add(1)
+	go func() {
+		defer a.background.Done()
+		f()
+	}()
+}
+
+// saveProcessSettings saves the process wide settings Start and the admin
+// API change, restore puts them back once the App stopped, so the next App
+// of the process starts from the same.
+func saveProcessSettings() (restore func()) {
+	alert, fraction := propagationAlert, softDeadlineFraction
+	count, interval := defaultSimulator.count, defaultSimulator.delays[stepStream]
+	names := defaultNames
+	static := staticCities.Load()
+	latency := extraLatency.Load()
+	logs := currentLogSettings()
+	percent := debug.SetGCPercent(-1)
+	debug.SetGCPercent(percent)
+	limit := debug.SetMemoryLimit(-1)
+	heap := ballast
+
+	return func() {
+		propagationAlert, softDeadlineFraction = alert, fraction
+		defaultSimulator.count, defaultSimulator.delays[stepStream] = count, interval
+		defaultNames = names
+		staticCities.Store(static)
+		extraLatency.Store(latency)
+		if err := setLogSettings(logs); err != nil {
+			log.Printf("error: restoring log settings: %s", err)
+		}
+		debug.SetGCPercent(percent)
+		debug.SetMemoryLimit(limit)
+		ballast = heap
+	}
+}
+
+func (a *App) close() {
+	for _, c := range a.closers {
+		if err := c(); err != nil {
+			log.Printf("error: closing: %s", err)
+		}
+	}
+	a.closers = nil
+}
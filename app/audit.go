@@ -0,0 +1,274 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	auditRecorded = expvar.NewInt("audit_recorded")
+	auditDropped  = expvar.NewInt("audit_dropped")
+)
+
+// auditedUnary are the unary methods changing something. Every streaming
+// call is audited, they are the long-running ones.
+var auditedUnary = map[string]bool{
+	"/cities.CitiesService/Create":        true,
+	"/cities.AdminService/SetMaintenance": true,
+	"/cities.AdminService/CancelCall":     true,
+	"/cities.AdminService/SetLatency":     true,
+	"/cities.AdminService/ReloadConfig":   true,
+}
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time      time.Time  `json:"time"`
+	RequestID string     `json:"request_id,omitempty"`
+	Peer      string     `json:"peer,omitempty"`
+	Session   string     `json:"session_id,omitempty"`
+	Method    string     `json:"method"`
+	Deadline  *time.Time `json:"deadline,omitempty"`
+	Duration  string     `json:"duration"`
+	Outcome   string     `json:"outcome"`
+	Code      string     `json:"code"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// auditOutcome sorts the end of a call: ok, cancelled, timed_out or error.
+func auditOutcome(err error) string {
+	switch status.Code(err) {
+	case codes.OK:
+		return "ok"
+	case codes.Canceled:
+		return "cancelled"
+	case codes.DeadlineExceeded:
+		return "timed_out"
+	}
+	return "error"
+}
+
+// auditLog appends the entries to a file. Calls only queue their entry, a
+// background writer does the writing and flushes every second, on queries
+// and on Close. An entry that finds the queue full is dropped rather than
+// slowing the call down.
+type auditLog struct {
+	path    string
+	entries chan auditEntry
+	flushes chan chan error
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	a := &auditLog{
+		path:    path,
+		entries: make(chan auditEntry, 1024),
+		flushes: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	go a.write(f)
+	return a, nil
+}
+
+func (a *auditLog) write(f *os.File) {
+	defer close(a.done)
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-a.entries:
+			if !ok {
+				if err := w.Flush(); err != nil {
+					log.Println("error flushing audit log", err)
+				}
+				if err := f.Close(); err != nil {
+					log.Println("error closing audit log", err)
+				}
+				return
+			}
+			if err := enc.Encode(e); err != nil {
+				log.Println("error writing audit log", err)
+			}
+		case <-ticker.C:
+			if err := w.Flush(); err != nil {
+				log.Println("error flushing audit log", err)
+			}
+		case res := <-a.flushes:
+			// Whatever was queued before the flush goes with it.
+			for n := len(a.entries); n > 0; n-- {
+				if err := enc.Encode(<-a.entries); err != nil {
+					log.Println("error writing audit log", err)
+				}
+			}
+			res <- w.Flush()
+		}
+	}
+}
+
+func (a *auditLog) record(e auditEntry) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		auditDropped.Add(1)
+		return
+	}
+	select {
+	case a.entries <- e:
+		auditRecorded.Add(1)
+	default:
+		auditDropped.Add(1)
+	}
+}
+
+func (a *auditLog) flush() error {
+	res := make(chan error, 1)
+	select {
+	case a.flushes <- res:
+		return <-res
+	case <-a.done:
+		return errors.New("audit log closed")
+	}
+}
+
+// Close writes what is queued and closes the file. Entries recorded after it
+// are dropped.
+func (a *auditLog) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.entries)
+	a.mu.Unlock()
+	<-a.done
+	return nil
+}
+
+func (a *auditLog) entry(ctx context.Context, method string, start time.Time, err error) auditEntry {
+	e := auditEntry{
+		Time:      start,
+		RequestID: requestID(ctx),
+		Method:    method,
+		Duration:  time.Since(start).Round(time.Millisecond).String(),
+		Outcome:   auditOutcome(err),
+		Code:      status.Code(err).String(),
+	}
+	if err != nil {
+		e.Error = status.Convert(err).Message()
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		e.Peer = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("session-id"); len(v) > 0 {
+		e.Session = v[0]
+	}
+	if d, ok := ctx.Deadline(); ok {
+		e.Deadline = &d
+	}
+	return e
+}
+
+func (a *auditLog) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !auditedUnary[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	a.record(a.entry(ctx, info.FullMethod, start, err))
+	return resp, err
+}
+
+func (a *auditLog) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	a.record(a.entry(ss.Context(), info.FullMethod, start, err))
+	return err
+}
+
+// query returns the entries matching the non-empty filters, the most recent
+// limit of them, oldest first.
+func (a *auditLog) query(method, outcome, requestID string, limit int) ([]auditEntry, error) {
+	if err := a.flush(); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var found []auditEntry
+	dec := json.NewDecoder(f)
+	for {
+		var e auditEntry
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if (method == "" || e.Method == method) && (outcome == "" || e.Outcome == outcome) && (requestID == "" || e.RequestID == requestID) {
+			found = append(found, e)
+		}
+	}
+	if len(found) > limit {
+		found = found[len(found)-limit:]
+	}
+	return found, nil
+}
+
+// adminAudit lists audit entries on GET, filtered by ?method=, ?outcome= and
+// ?request_id=, the last ?limit= of them (100 by default).
+func (a *auditLog) adminAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	entries, err := a.query(q.Get("method"), q.Get("outcome"), q.Get("request_id"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []auditEntry{}
+	}
+	writeJSON(w, map[string][]auditEntry{"entries": entries})
+}
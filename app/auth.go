@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-cancel/ctxmeta"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// identity is the caller of a call, read from the claims of its token.
+type identity struct {
+	Subject string   `json:"sub"`
+	Roles   []string `json:"roles"`
+	Expires int64    `json:"exp"`
+}
+
+type identityKey struct{}
+
+// identityFrom returns the caller the authenticator let in, ok is false for
+// the public methods and when authentication is off.
+func identityFrom(ctx context.Context) (id identity, ok bool) {
+	id, ok = ctx.Value(identityKey{}).(identity)
+	return id, ok
+}
+
+// authenticator requires an HS256 signed JWT, sent as a bearer token, from
+// every call but those of the public methods, and puts the identity it
+// carries in the context of the call. The AdminService keeps its own token,
+// see adminAuth.
+type authenticator struct {
+	secret []byte
+	public map[string]bool
+}
+
+func newAuthenticator(secret string, public []string) *authenticator {
+	a := &authenticator{secret: []byte(secret), public: make(map[string]bool, len(public))}
+	for _, m := range public {
+		a.public[m] = true
+	}
+	return a
+}
+
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if a.public[method] || strings.HasPrefix(method, "/cities.AdminService/") {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := md.Get("authorization")
+	if len(v) == 0 || !strings.HasPrefix(v[0], "Bearer ") {
+		return nil, status.Errorf(codes.Unauthenticated, "bearer token required for %s", method)
+	}
+	id, err := a.verify(strings.TrimPrefix(v[0], "Bearer "))
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	return context.WithValue(ctxmeta.WithUserID(ctx, id.Subject), identityKey{}, id), nil
+}
+
+// verify checks the signature and the expiry of token.
+func (a *authenticator) verify(token string) (identity, error) {
+	var id identity
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return id, errors.New("not a JWT")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return id, fmt.Errorf("header: %w", err)
+	}
+	if header.Alg != "HS256" {
+		return id, fmt.Errorf("algorithm %q, want HS256", header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return id, fmt.Errorf("signature: %w", err)
+	}
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return id, errors.New("bad signature")
+	}
+
+	if err := decodeSegment(parts[1], &id); err != nil {
+		return id, fmt.Errorf("claims: %w", err)
+	}
+	if id.Expires != 0 && time.Now().Unix() >= id.Expires {
+		return id, errors.New("expired")
+	}
+	return id, nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (a *authenticator) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/status"
+)
+
+const (
+	callPending int32 = iota
+	callCommitting
+	callAbandoned
+)
+
+type createCall struct {
+	name  string
+	state int32
+	done  chan struct{}
+	city  *cities.City
+	err   error
+}
+
+// createBatcher groups concurrent Create calls into one repository batch.
+// A batch is committed when it holds maxItems calls or when interval has
+// passed since its first call, whichever comes first.
+type createBatcher struct {
+	repo     CityRepository
+	maxItems int
+	interval time.Duration
+	timeout  time.Duration
+	calls    chan *createCall
+}
+
+func newCreateBatcher(repo CityRepository, maxItems int, interval time.Duration) *createBatcher {
+	return &createBatcher{
+		repo:     repo,
+		maxItems: maxItems,
+		interval: interval,
+		timeout:  5 * time.Second,
+		calls:    make(chan *createCall),
+	}
+}
+
+// run commits the batches until ctx ends, then the one still pending.
+func (b *createBatcher) run(ctx context.Context) {
+	var (
+		pending []*createCall
+		flush   <-chan time.Time
+	)
+
+	for {
+		select {
+		case c := <-b.calls:
+			if len(pending) == 0 {
+				flush = time.After(b.interval)
+			}
+			pending = append(pending, c)
+			if len(pending) < b.maxItems {
+				continue
+			}
+		case <-flush:
+		case <-ctx.Done():
+			if len(pending) > 0 {
+				b.commit(pending)
+			}
+			return
+		}
+
+		b.commit(pending)
+		pending, flush = nil, nil
+	}
+}
+
+// commit writes the calls whose callers are still waiting. The batch is
+// shared by many requests, so it runs under its own timeout instead of any
+// caller's context.
+func (b *createBatcher) commit(calls []*createCall) {
+	var (
+		batch []*createCall
+		names []string
+	)
+	for _, c := range calls {
+		if atomic.CompareAndSwapInt32(&c.state, callPending, callCommitting) {
+			batch = append(batch, c)
+			names = append(names, c.name)
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	list, err := b.repo.BatchInsert(ctx, names)
+	for i, c := range batch {
+		if err != nil {
+			c.err = err
+		} else {
+			c.city = list[i]
+		}
+		close(c.done)
+	}
+	debugf("committed batch of %d cities, %d withdrawn", len(batch), len(calls)-len(batch))
+}
+
+// Create queues name for the next batch and waits for it to be committed.
+// A caller that gives up before its batch starts committing is removed from
+// the batch; one that gives up later gets the committed city together with
+// an error saying so.
+func (b *createBatcher) Create(ctx context.Context, name string) (*cities.City, error) {
+	c := &createCall{name: name, done: make(chan struct{})}
+
+	select {
+	case b.calls <- c:
+	case <-ctx.Done():
+		return nil, grpcerr.FromContext(ctx)
+	}
+
+	select {
+	case <-c.done:
+		return c.city, c.err
+	case <-ctx.Done():
+	}
+
+	if atomic.CompareAndSwapInt32(&c.state, callPending, callAbandoned) {
+		return nil, grpcerr.FromContext(ctx)
+	}
+
+	<-c.done
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	st, _ := status.FromError(grpcerr.FromContext(ctx))
+	return c.city, status.Errorf(st.Code(), "%s, but city %s was committed", st.Message(), c.city.Id)
+}
@@ -0,0 +1,308 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errSlowSubscriber is the cancel cause of a CANCEL_SUBSCRIBER watch whose
+// buffer overflowed.
+var errSlowSubscriber = errors.New("subscriber is too slow")
+
+// watchHistory is the number of deltas the broker keeps for watches
+// resuming after a disconnect.
+const watchHistory = 256
+
+// snapshotTimeout bounds building a snapshot. The watch ends when its first
+// snapshot misses it, a periodic one is skipped.
+const snapshotTimeout = 2 * time.Second
+
+// delta is a created city with its place in the order of creation.
+type delta struct {
+	seq  uint64
+	city *cities.City
+}
+
+// subscriber buffers events for one WatchCities call. Publishing never
+// blocks on it, when the buffer is full the overflow policy decides.
+type subscriber struct {
+	size   int
+	policy cities.OverflowPolicy
+	cancel context.CancelCauseFunc
+	notify chan struct{}
+
+	mu      sync.Mutex
+	buf     []delta
+	dropped uint64
+}
+
+func (s *subscriber) push(d delta) {
+	s.mu.Lock()
+	if len(s.buf) < s.size {
+		s.buf = append(s.buf, d)
+	} else {
+		switch s.policy {
+		case cities.OverflowPolicy_DROP_OLDEST:
+			s.buf = append(s.buf[1:], d)
+			s.dropped++
+		case cities.OverflowPolicy_DROP_NEWEST:
+			s.dropped++
+		case cities.OverflowPolicy_CANCEL_SUBSCRIBER:
+			s.buf = nil
+			s.cancel(errSlowSubscriber)
+		}
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next waits for the next buffered event. dropped is the number of events
+// discarded since the previous one. It returns ok false without an event
+// when tick fires first, a nil tick never does.
+func (s *subscriber) next(ctx context.Context, tick <-chan time.Time) (d delta, dropped uint64, ok bool, err error) {
+	for {
+		s.mu.Lock()
+		if len(s.buf) > 0 {
+			d, s.buf = s.buf[0], s.buf[1:]
+			dropped, s.dropped = s.dropped, 0
+			s.mu.Unlock()
+			return d, dropped, true, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-tick:
+			return delta{}, 0, false, nil
+		case <-ctx.Done():
+			return delta{}, 0, false, ctx.Err()
+		}
+	}
+}
+
+// broker fans created cities out to the WatchCities subscribers. It numbers
+// them and keeps the last watchHistory of them.
+type broker struct {
+	mu      sync.RWMutex
+	subs    map[*subscriber]struct{}
+	seq     uint64
+	history []delta
+}
+
+func newBroker() *broker {
+	return &broker{subs: make(map[*subscriber]struct{})}
+}
+
+func (b *broker) Subscribe(size int, policy cities.OverflowPolicy, cancel context.CancelCauseFunc) *subscriber {
+	s := &subscriber{
+		size:   size,
+		policy: policy,
+		cancel: cancel,
+		notify: make(chan struct{}, 1),
+	}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Unsubscribe removes s and releases its buffer right away.
+func (b *broker) Unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	delete(b.subs, s)
+	b.mu.Unlock()
+
+	s.mu.Lock()
+	s.buf = nil
+	s.mu.Unlock()
+}
+
+func (b *broker) Publish(city *cities.City) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	d := delta{seq: b.seq, city: &cities.City{Id: city.GetId(), Name: city.GetName()}}
+	if len(b.history) == watchHistory {
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, d)
+
+	for s := range b.subs {
+		s.push(d)
+	}
+}
+
+// Sequence is the sequence of the last published city.
+func (b *broker) Sequence() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.seq
+}
+
+// Since returns the deltas published after seq, false when the history no
+// longer reaches back that far.
+func (b *broker) Since(seq uint64) ([]delta, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if seq > b.seq {
+		return nil, false
+	}
+	if seq == b.seq {
+		return nil, true
+	}
+	if len(b.history) == 0 || b.history[0].seq > seq+1 {
+		return nil, false
+	}
+	start := int(seq + 1 - b.history[0].seq)
+	return append([]delta(nil), b.history[start:]...), true
+}
+
+// publishingRepository publishes every inserted city on the broker.
+type publishingRepository struct {
+	CityRepository
+	broker *broker
+}
+
+func (r *publishingRepository) BatchInsert(ctx context.Context, names []string) ([]*cities.City, error) {
+	list, err := r.CityRepository.BatchInsert(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, city := range list {
+		r.broker.Publish(city)
+	}
+	return list, nil
+}
+
+func (u *citiesServer) WatchCities(in *cities.WatchRequest, stream cities.CitiesService_WatchCitiesServer) error {
+	size := int(in.GetBuffer())
+	if size == 0 {
+		size = 16
+	}
+	if size > 1024 {
+		return status.Error(codes.InvalidArgument, "buffer must not be above 1024")
+	}
+	interval := time.Duration(in.GetSnapshotIntervalMs()) * time.Millisecond
+	if interval > 0 && interval < 100*time.Millisecond {
+		return status.Error(codes.InvalidArgument, "snapshot_interval_ms must be at least 100")
+	}
+
+	ctx, cancel := context.WithCancelCause(stream.Context())
+	defer cancel(nil)
+
+	// Subscribed before the history or the snapshot are read, nothing
+	// published in between is missed. last skips what was already sent.
+	sub := u.broker.Subscribe(size, in.GetOverflow(), cancel)
+	defer u.broker.Unsubscribe(sub)
+	var last uint64
+
+	snapshot := func() error {
+		seq, list, err := u.snapshot(ctx)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&cities.CityEvent{Sequence: seq, Snapshot: &cities.CitySnapshot{City: list}}); err != nil {
+			return status.Errorf(codes.Unknown, "cannot send stream response: %v", err)
+		}
+		if seq > last {
+			last = seq
+		}
+		return nil
+	}
+
+	switch in.GetStart() {
+	case cities.WatchStart_RESUME:
+		deltas, ok := u.broker.Since(in.GetResumeAfter())
+		if !ok {
+			if err := snapshot(); err != nil {
+				return err
+			}
+			break
+		}
+		last = in.GetResumeAfter()
+		for _, d := range deltas {
+			if err := stream.Send(&cities.CityEvent{City: d.city, Sequence: d.seq}); err != nil {
+				return status.Errorf(codes.Unknown, "cannot send stream response: %v", err)
+			}
+			last = d.seq
+		}
+	case cities.WatchStart_RESYNC:
+		if err := snapshot(); err != nil {
+			return err
+		}
+	}
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		d, dropped, ok, err := sub.next(ctx, tick)
+		if err != nil {
+			if context.Cause(ctx) == errSlowSubscriber {
+				return status.Error(codes.ResourceExhausted, "subscriber is too slow, buffer overflowed")
+			}
+			return grpcerr.FromContext(ctx)
+		}
+		if !ok {
+			// A periodic snapshot that failed is skipped, the deltas go on.
+			if err := snapshot(); err != nil {
+				if ctx.Err() != nil {
+					return grpcerr.FromContext(ctx)
+				}
+				debugf("watch snapshot skipped: %s", status.Convert(err).Message())
+			}
+			continue
+		}
+		if d.seq <= last {
+			continue
+		}
+
+		if err := stream.Send(&cities.CityEvent{City: d.city, Dropped: dropped, Sequence: d.seq}); err != nil {
+			return status.Errorf(codes.Unknown, "cannot send stream response: %v", err)
+		}
+		last = d.seq
+	}
+}
+
+// snapshot returns every city and the sequence they are at, within
+// snapshotTimeout.
+func (u *citiesServer) snapshot(ctx context.Context) (uint64, []*cities.City, error) {
+	ctx, cancel := context.WithTimeout(ctx, snapshotTimeout)
+	defer cancel()
+
+	// Read first, every city published up to seq is in the list.
+	seq := u.broker.Sequence()
+	if err := simulatorFrom(ctx).Step(ctx, stepSnapshot); err != nil {
+		return 0, nil, err
+	}
+	list, err := u.repo.All(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	compact := make([]*cities.City, len(list))
+	for i, city := range list {
+		compact[i] = &cities.City{Id: city.GetId(), Name: city.GetName()}
+	}
+	return seq, compact, nil
+}
@@ -0,0 +1,24 @@
+//go:build !minimal
+
+package app
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// buildFlavor names the feature set compiled in. Build with -tags minimal
+// for the small tutorial server: no metrics, no dashboard, no TLS and no
+// WebSocket tunnel.
+const buildFlavor = "full"
+
+// flavorOptions are the server options only the full build has.
+func flavorOptions() []Option {
+	return []Option{WithInterceptors(metricsUnary, metricsStream)}
+}
+
+// flavorRoutes registers the REST routes only the full build has.
+func flavorRoutes(mux *http.ServeMux) {
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/dashboard", dashboard)
+}
@@ -0,0 +1,50 @@
+//go:build minimal
+
+package app
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"go-cancel/config"
+)
+
+const buildFlavor = "minimal"
+
+func flavorOptions() []Option {
+	return nil
+}
+
+func flavorRoutes(mux *http.ServeMux) {}
+
+// serverTLS refuses the TLS settings, the minimal build serves plaintext
+// only.
+func serverTLS(cfg config.Server) (*tls.Config, error) {
+	if cfg.TLSCert != "" || cfg.TLSSelfSigned {
+		return nil, errors.New("TLS needs the full build")
+	}
+	return nil, nil
+}
+
+func tlsOptions(tc *tls.Config) []Option {
+	return nil
+}
+
+// wsListener only exists in the full build, run refuses GRPC_WEBSOCKET
+// without it.
+type wsListener struct {
+	net.Listener
+	http.Handler
+}
+
+func newWsListener(path string) *wsListener {
+	return nil
+}
+
+// wsCities is the WebSocket bridge of ListStream, the minimal build has none.
+func wsCities(gateway *restGateway, writeTimeout time.Duration) http.Handler {
+	return nil
+}
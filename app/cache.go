@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-cancel/pb/cities"
+)
+
+// listCache holds a List result generated ahead of time. Until it is primed,
+// or when there is none, List generates the cities on every call.
+type listCache struct {
+	mu   sync.RWMutex
+	list []*cities.City
+}
+
+// Get returns a copy of the cached cities, false before priming finished.
+func (c *listCache) Get() ([]*cities.City, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.list == nil {
+		return nil, false
+	}
+	return cloneCities(c.list), true
+}
+
+// Prime fills the cache within timeout. It gives up as soon as ctx ends, so
+// a shutdown during startup is not held up by it.
+func (c *listCache) Prime(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	list, err := generateCities(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.list = list
+	c.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"go-cancel/citycrypt"
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// coalescer runs concurrent identical unary calls once and hands the result
+// to every caller still waiting for it. Calls are identical when they go to
+// the same method, in the same scope, with requests that encode to the same
+// bytes once deterministically marshalled.
+//
+// The shared call runs on a context detached from the caller that started
+// it, so that caller going away does not fail the others. It is cancelled
+// once the last waiter has gone.
+type coalescer struct {
+	methods map[string]bool
+	// scope separates calls whose results differ for the same request, it
+	// returns false for calls that must not be shared at all.
+	scope func(ctx context.Context) (string, bool)
+	// strict observes the shared calls, nil without strict mode.
+	strict *strictCancellation
+
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	waiters int
+	cancel  context.CancelFunc
+	done    chan struct{}
+	resp    interface{}
+	err     error
+}
+
+func newCoalescer(scope func(context.Context) (string, bool), methods ...string) *coalescer {
+	c := &coalescer{
+		methods: make(map[string]bool, len(methods)),
+		scope:   scope,
+		calls:   make(map[string]*coalescedCall),
+	}
+	for _, m := range methods {
+		c.methods[m] = true
+	}
+	return c
+}
+
+// key hashes the method, the scope and the normalized request, false when
+// the call is not coalesced.
+func (c *coalescer) key(ctx context.Context, method string, req interface{}) (string, bool) {
+	if !c.methods[method] {
+		return "", false
+	}
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return "", false
+	}
+	scope, ok := c.scope(ctx)
+	if !ok {
+		return "", false
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(scope))
+	h.Write([]byte{0})
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// Unary is the interceptor coalescing the calls to the methods of c.
+func (c *coalescer) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	key, ok := c.key(ctx, info.FullMethod, req)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	c.mu.Lock()
+	call, shared := c.calls[key]
+	if shared {
+		coalescedCalls.Add(1)
+	} else {
+		callCtx, cancel := context.WithCancel(detach(ctx))
+		call = &coalescedCall{cancel: cancel, done: make(chan struct{})}
+		c.calls[key] = call
+		go c.run(callCtx, key, info.FullMethod, call, handler, req)
+	}
+	call.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		c.leave(key, call)
+		return nil, grpcerr.FromContext(ctx)
+	}
+
+	if call.err != nil {
+		return nil, call.err
+	}
+	// Every waiter gets its own copy, the interceptors outside may still
+	// change it.
+	if msg, ok := call.resp.(proto.Message); ok {
+		return proto.Clone(msg), nil
+	}
+	return call.resp, nil
+}
+
+func (c *coalescer) run(ctx context.Context, key, method string, call *coalescedCall, handler grpc.UnaryHandler, req interface{}) {
+	call.resp, call.err = c.call(ctx, method, handler, req)
+	call.cancel()
+
+	c.mu.Lock()
+	c.forget(key, call)
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// call runs the shared call on its own goroutine, out of reach of
+// recoveryUnary: a panic fails every waiter with Internal instead of the
+// process.
+func (c *coalescer) call(ctx context.Context, method string, handler grpc.UnaryHandler, req interface{}) (resp interface{}, err error) {
+	defer recovered(ctx, method, &err)
+	return c.strict.call(ctx, method, handler, req)
+}
+
+// leave drops a waiter that went away, the last one cancels the call.
+func (c *coalescer) leave(key string, call *coalescedCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	call.waiters--
+	if call.waiters == 0 {
+		// New callers start over instead of joining a cancelled call.
+		c.forget(key, call)
+		call.cancel()
+	}
+}
+
+func (c *coalescer) forget(key string, call *coalescedCall) {
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+}
+
+// coalesceScope shares calls running on the same simulator preset and city
+// names. Calls negotiating encryption are never shared, their responses are
+// sealed for one client.
+func coalesceScope(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get(citycrypt.MetadataKey)) > 0 {
+		return "", false
+	}
+	return simulatorFrom(ctx).Name() + "/" + namesFrom(ctx).Country(), true
+}
+
+// detached keeps the values of a context but neither its deadline nor its
+// cancellation.
+type detached struct {
+	context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detached{ctx}
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"go-cancel/deadline"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpc-go compresses every message of a call with the compressor the client
+// asked for, it has no hook to skip one message. The pieces below make that
+// decision per message anyway: the stream wrapper encodes a message itself
+// and marks the bytes, the codec passes them through untouched, and the gzip
+// compressor writes marked bytes as stored blocks. The result is still gzip
+// on the wire, so clients need nothing special.
+//
+// grpc-go looks compressors up by name, so deadlineGzip can only work
+// registered as "gzip", for the whole process. It is registered by the first
+// WithDeadlineCompression with a threshold, a program importing app without
+// it keeps the gzip of grpc/encoding/gzip.
+
+// uncompressed holds the first byte of every encoded message that must be
+// sent as stored blocks.
+var uncompressed sync.Map
+
+var registerDeadlineGzip sync.Once
+
+// encodedMessage is a message already encoded by deadlineCompression.
+type encodedMessage []byte
+
+// passthroughCodec is the proto codec, except that it sends an
+// encodedMessage as is.
+type passthroughCodec struct {
+	encoding.Codec
+}
+
+func newPassthroughCodec() passthroughCodec {
+	return passthroughCodec{Codec: encoding.GetCodec("proto")}
+}
+
+func (c passthroughCodec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(encodedMessage); ok {
+		return m, nil
+	}
+	return c.Codec.Marshal(v)
+}
+
+// deadlineGzip replaces the gzip compressor of grpc/encoding/gzip.
+type deadlineGzip struct{}
+
+func (deadlineGzip) Name() string {
+	return "gzip"
+}
+
+func (deadlineGzip) Compress(w io.Writer) (io.WriteCloser, error) {
+	return &deadlineGzipWriter{w: w}, nil
+}
+
+func (deadlineGzip) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// deadlineGzipWriter picks the compression level on the first write, which
+// grpc-go makes with the whole encoded message.
+type deadlineGzipWriter struct {
+	w io.Writer
+	z *gzip.Writer
+}
+
+func (d *deadlineGzipWriter) Write(p []byte) (int, error) {
+	if d.z == nil {
+		level := gzip.DefaultCompression
+		if len(p) > 0 {
+			if _, ok := uncompressed.Load(&p[0]); ok {
+				level = gzip.NoCompression
+			}
+		}
+
+		z, err := gzip.NewWriterLevel(d.w, level)
+		if err != nil {
+			return 0, err
+		}
+		d.z = z
+	}
+	return d.z.Write(p)
+}
+
+func (d *deadlineGzipWriter) Close() error {
+	if d.z == nil {
+		d.z = gzip.NewWriter(d.w)
+	}
+	return d.z.Close()
+}
+
+// deadlineCompression sends stream messages uncompressed once less than
+// threshold is left before the deadline, compressing them could make the
+// call miss it.
+type deadlineCompression time.Duration
+
+func (threshold deadlineCompression) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &compressionStream{ServerStream: ss, threshold: time.Duration(threshold)})
+}
+
+type compressionStream struct {
+	grpc.ServerStream
+	threshold time.Duration
+}
+
+func (s *compressionStream) SendMsg(m interface{}) error {
+	msg, ok := m.(proto.Message)
+	if !ok || !s.nearDeadline() {
+		return s.ServerStream.SendMsg(m)
+	}
+
+	data, err := proto.Marshal(msg)
+	if err != nil || len(data) == 0 {
+		return s.ServerStream.SendMsg(m)
+	}
+
+	compressionSkipped.Add(1)
+	uncompressed.Store(&data[0], struct{}{})
+	defer uncompressed.Delete(&data[0])
+
+	return s.ServerStream.SendMsg(encodedMessage(data))
+}
+
+// nearDeadline reports whether the response is gzipped and the deadline is
+// closer than the threshold.
+func (s *compressionStream) nearDeadline() bool {
+	ctx := s.Context()
+	if recvCompress(ctx) != "gzip" {
+		return false
+	}
+
+	remaining, ok := deadline.RemainingBudget(ctx)
+	return ok && remaining < s.threshold
+}
+
+// recvCompress is the compressor the client used, the server answers with
+// the same one.
+func recvCompress(ctx context.Context) string {
+	st, ok := grpc.ServerTransportStreamFromContext(ctx).(interface{ RecvCompress() string })
+	if !ok {
+		return ""
+	}
+	return st.RecvCompress()
+}
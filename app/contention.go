@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"expvar"
+	"sync/atomic"
+	"time"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var repositoryLocks = expvar.NewMap("repository_locks")
+
+// contentionRepository simulates the locks of a busy database: every
+// transaction waits in line for one of the slots, then holds it for
+// writeHold or readHold. The deadline of a request is spent waiting, not
+// working, and a request cancelled in line leaves it at once, without ever
+// reaching the repository. A line longer than queue is refused.
+type contentionRepository struct {
+	CityRepository
+	slots     chan struct{}
+	queue     int64
+	waiting   int64
+	writeHold time.Duration
+	readHold  time.Duration
+}
+
+func newContentionRepository(repo CityRepository, slots, queue int, writeHold, readHold time.Duration) *contentionRepository {
+	return &contentionRepository{
+		CityRepository: repo,
+		slots:          make(chan struct{}, slots),
+		queue:          int64(queue),
+		writeHold:      writeHold,
+		readHold:       readHold,
+	}
+}
+
+func (r *contentionRepository) BatchInsert(ctx context.Context, names []string) ([]*cities.City, error) {
+	release, err := r.lock(ctx, r.writeHold)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return r.CityRepository.BatchInsert(ctx, names)
+}
+
+func (r *contentionRepository) All(ctx context.Context) ([]*cities.City, error) {
+	release, err := r.lock(ctx, r.readHold)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return r.CityRepository.All(ctx)
+}
+
+// lock waits for a slot, then holds it for hold before the transaction
+// runs. The slot is released by release, or right away when ctx ends while
+// holding it.
+func (r *contentionRepository) lock(ctx context.Context, hold time.Duration) (release func(), err error) {
+	if n := atomic.AddInt64(&r.waiting, 1); r.queue > 0 && n > r.queue {
+		atomic.AddInt64(&r.waiting, -1)
+		repositoryLocks.Add("refused", 1)
+		return nil, status.Errorf(codes.ResourceExhausted, "%d transactions already waiting for a lock", n-1)
+	}
+
+	start := time.Now()
+	select {
+	case r.slots <- struct{}{}:
+		atomic.AddInt64(&r.waiting, -1)
+	case <-ctx.Done():
+		atomic.AddInt64(&r.waiting, -1)
+		repositoryLocks.Add("abandoned", 1)
+		debugf("lock wait abandoned after %s", time.Since(start))
+		return nil, grpcerr.FromContext(ctx)
+	}
+	repositoryLocks.Add("acquired", 1)
+	repositoryLocks.Add("waited_ms", time.Since(start).Milliseconds())
+
+	release = func() { <-r.slots }
+	if err := sleep(ctx, hold); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
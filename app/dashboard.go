@@ -0,0 +1,21 @@
+//go:build !minimal
+
+package app
+
+import (
+	_ "embed"
+	"log"
+	"net/http"
+)
+
+//go:embed asset/dashboard/index.html
+var dashboardHTML []byte
+
+// dashboard serves the page charting the metrics published on /debug/vars.
+func dashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(dashboardHTML); err != nil {
+		log.Println("error writing dashboard", err)
+	}
+}
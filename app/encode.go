@@ -0,0 +1,57 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"io"
+
+	"go-cancel/grpcerr"
+)
+
+var restEncodingAborted = expvar.NewInt("rest_encoding_aborted")
+
+// encodeBatchSize is how many encoded bytes are held before being written,
+// a write is also where a dead connection shows.
+const encodeBatchSize = 32 << 10
+
+// encodeJSONArray writes items as a JSON array, the same bytes json.Marshal
+// gives. It checks ctx between items and writes in batches, so a client
+// that goes away stops the encoding within a batch instead of the whole
+// payload being encoded for nobody. It returns the status error of ctx when
+// it stopped for it.
+func encodeJSONArray[T any](ctx context.Context, w io.Writer, items []T) error {
+	if items == nil {
+		_, err := io.WriteString(w, "null")
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			restEncodingAborted.Add(1)
+			return grpcerr.FromContext(ctx)
+		}
+
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(data)
+
+		if buf.Len() >= encodeBatchSize {
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+	}
+	buf.WriteByte(']')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"go-cancel/citycrypt"
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// keyAgreementTimeout bounds the key agreement of an encrypted call. It is
+// carved out of the call deadline, a call that would spend its time on the
+// handshake fails early instead.
+const keyAgreementTimeout = 500 * time.Millisecond
+
+// keyAgreements counts the key agreements by outcome.
+var keyAgreements = expvar.NewMap("key_agreements")
+
+// agreeKey runs the key agreement when the client sent the city-key
+// metadata. It returns a nil Sealer for a call that is not encrypted, and
+// the header carrying the public key of the server otherwise.
+func agreeKey(ctx context.Context) (*citycrypt.Sealer, metadata.MD, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := md.Get(citycrypt.MetadataKey)
+	if len(v) == 0 {
+		return nil, nil, nil
+	}
+
+	peer, err := citycrypt.ParseKey(v[0])
+	if err != nil {
+		keyAgreements.Add("invalid", 1)
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, keyAgreementTimeout)
+	defer cancel()
+
+	// The simulator stands for the key service a real server would ask.
+	if err := simulatorFrom(ctx).Step(hctx, stepHandshake); err != nil {
+		if ctxErr := grpcerr.FromContext(ctx); ctxErr != nil {
+			keyAgreements.Add("cancelled", 1)
+			return nil, nil, ctxErr
+		}
+		if hctx.Err() != nil {
+			keyAgreements.Add("timeout", 1)
+			return nil, nil, status.Errorf(codes.DeadlineExceeded, "key agreement took longer than %s", keyAgreementTimeout)
+		}
+		keyAgreements.Add("failed", 1)
+		return nil, nil, err
+	}
+
+	priv, err := citycrypt.GenerateKey()
+	if err != nil {
+		keyAgreements.Add("failed", 1)
+		return nil, nil, status.Errorf(codes.Internal, "generate key: %v", err)
+	}
+	sealer, err := citycrypt.Agree(priv, peer)
+	if err != nil {
+		keyAgreements.Add("invalid", 1)
+		return nil, nil, status.Errorf(codes.InvalidArgument, "key agreement: %v", err)
+	}
+
+	keyAgreements.Add("ok", 1)
+	return sealer, metadata.Pairs(citycrypt.MetadataKey, citycrypt.EncodeKey(priv.PublicKey())), nil
+}
+
+func sealCity(s *citycrypt.Sealer, city *cities.City) (*cities.City, error) {
+	data, err := proto.Marshal(city)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.Seal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &cities.City{Sealed: sealed}, nil
+}
+
+// sealResponse returns a copy of m with every City sealed. The handlers may
+// share the cities of a response, with the broker subscribers for instance,
+// so m itself is left alone.
+func sealResponse(s *citycrypt.Sealer, m interface{}) (interface{}, error) {
+	if city, ok := m.(*cities.City); ok {
+		return sealCity(s, city)
+	}
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return m, nil
+	}
+
+	out := proto.Clone(msg)
+	var err error
+	seal := func(city **cities.City) {
+		if *city != nil && err == nil {
+			*city, err = sealCity(s, *city)
+		}
+	}
+
+	switch r := out.(type) {
+	case *cities.Cities:
+		for i := range r.City {
+			seal(&r.City[i])
+		}
+	case *cities.CityStream:
+		seal(&r.City)
+		for i := range r.Cities {
+			seal(&r.Cities[i])
+		}
+	case *cities.CityEvent:
+		seal(&r.City)
+		if r.Snapshot != nil {
+			for i := range r.Snapshot.City {
+				seal(&r.Snapshot.City[i])
+			}
+		}
+	case *cities.TransformResult:
+		seal(&r.City)
+	default:
+		return m, nil
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "seal city: %v", err)
+	}
+	return out, nil
+}
+
+// The export sends cities as NDJSON bytes, not City messages, so it cannot
+// be encrypted and is refused rather than sent in the clear.
+const exportMethod = "/cities.CitiesService/ExportCities"
+
+func encryptionUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	sealer, header, err := agreeKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sealer == nil {
+		return handler(ctx, req)
+	}
+
+	if err := grpc.SetHeader(ctx, header); err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot send key: %v", err)
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return sealResponse(sealer, resp)
+}
+
+func encryptionStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, _ := metadata.FromIncomingContext(ss.Context())
+	if info.FullMethod == exportMethod && len(md.Get(citycrypt.MetadataKey)) > 0 {
+		return status.Error(codes.Unimplemented, "ExportCities cannot be encrypted")
+	}
+
+	sealer, header, err := agreeKey(ss.Context())
+	if err != nil {
+		return err
+	}
+	if sealer == nil {
+		return handler(srv, ss)
+	}
+
+	if err := ss.SetHeader(header); err != nil {
+		return status.Errorf(codes.Internal, "cannot send key: %v", err)
+	}
+	return handler(srv, &sealingStream{ServerStream: ss, sealer: sealer})
+}
+
+type sealingStream struct {
+	grpc.ServerStream
+	sealer *citycrypt.Sealer
+}
+
+func (s *sealingStream) SendMsg(m interface{}) error {
+	out, err := sealResponse(s.sealer, m)
+	if err != nil {
+		return err
+	}
+	return s.ServerStream.SendMsg(out)
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"bytes"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExportCities sends the stored cities in the format of /cities/ndjson, one
+// chunk per city. The end marker is only sent when every city went out.
+func (u *citiesServer) ExportCities(in *cities.ExportRequest, stream cities.CitiesService_ExportCitiesServer) error {
+	ctx := stream.Context()
+
+	list, err := u.repo.All(ctx)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	out := newNDJSONWriter(&buf, in.GetGzip())
+
+	send := func() error {
+		if err := grpcerr.FromContext(ctx); err != nil {
+			return err
+		}
+		chunk := &cities.ExportChunk{Data: append([]byte(nil), buf.Bytes()...)}
+		buf.Reset()
+		if err := stream.Send(chunk); err != nil {
+			return status.Errorf(codes.Unknown, "cannot send stream response: %v", err)
+		}
+		return nil
+	}
+
+	for _, city := range list {
+		if err := out.Write(city); err != nil {
+			return status.Errorf(codes.Internal, "cannot encode city: %v", err)
+		}
+		if err := send(); err != nil {
+			return err
+		}
+	}
+
+	if err := out.Finish(); err != nil {
+		return status.Errorf(codes.Internal, "cannot finish export: %v", err)
+	}
+	return send()
+}
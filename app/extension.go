@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"go-cancel/deadline"
+	"go-cancel/pb/cities"
+)
+
+// extensionRequests counts the ExtensionRequests sent to clients.
+var extensionRequests = expvar.NewInt("extension_requests")
+
+// extensionPlanner decides when a ListStream asks the client for more time.
+// It asks once per call, when the deadline would end the stream within the
+// next two cities although there are more to send.
+type extensionPlanner struct {
+	start time.Time
+	total int
+	asked bool
+}
+
+func newExtensionPlanner(total int) *extensionPlanner {
+	return &extensionPlanner{start: time.Now(), total: total}
+}
+
+// check is called before a city is produced, the caller sets the resume
+// token of the request it returns. handled is the number of
+// cities this call produced so far, lastSent the index of the last city the
+// client got, resumed calls included.
+func (p *extensionPlanner) check(ctx context.Context, handled, lastSent int) *cities.ExtensionRequest {
+	if p == nil || p.asked || handled == 0 {
+		return nil
+	}
+	remaining, ok := deadline.RemainingBudget(ctx)
+	if !ok {
+		return nil
+	}
+
+	perCity := time.Since(p.start) / time.Duration(handled)
+	left := time.Duration(p.total-lastSent) * perCity
+	if remaining >= left || remaining >= 2*perCity {
+		return nil
+	}
+
+	p.asked = true
+	extensionRequests.Add(1)
+	return &cities.ExtensionRequest{
+		Progress:    float64(lastSent) / float64(p.total),
+		RequestedMs: left.Milliseconds(),
+	}
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExtensionPlanner(t *testing.T) {
+	// Five cities took a second so far, five are left: another second.
+	newPlanner := func() *extensionPlanner {
+		return &extensionPlanner{start: time.Now().Add(-time.Second), total: 10}
+	}
+
+	tests := []struct {
+		name    string
+		planner *extensionPlanner
+		// timeout is the deadline of the call, zero for none.
+		timeout time.Duration
+		handled int
+		wantAsk bool
+	}{
+		{name: "deadline before the next two cities", planner: newPlanner(), timeout: 300 * time.Millisecond, handled: 5, wantAsk: true},
+		{name: "time for the rest", planner: newPlanner(), timeout: time.Hour, handled: 5},
+		{name: "time for two more cities", planner: newPlanner(), timeout: 500 * time.Millisecond, handled: 5},
+		{name: "no deadline", planner: newPlanner(), handled: 5},
+		{name: "nothing produced yet", planner: newPlanner(), timeout: 300 * time.Millisecond},
+		{name: "extensions not allowed", timeout: 300 * time.Millisecond, handled: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			req := tt.planner.check(ctx, tt.handled, tt.handled)
+			if !tt.wantAsk {
+				if req != nil {
+					t.Fatalf("asked for an extension: %v", req)
+				}
+				return
+			}
+			if req == nil {
+				t.Fatal("did not ask for an extension")
+			}
+			if req.GetProgress() != 0.5 || req.GetRequestedMs() < 1000 || req.GetRequestedMs() > 1100 {
+				t.Fatalf("asked for %dms at %v, want about 1000ms at 0.5", req.GetRequestedMs(), req.GetProgress())
+			}
+			if again := tt.planner.check(ctx, tt.handled+1, tt.handled+1); again != nil {
+				t.Fatalf("asked twice in a call: %v", again)
+			}
+		})
+	}
+}
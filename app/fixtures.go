@@ -0,0 +1,114 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// repositorySnapshot is the whole content of a memoryRepository.
+type repositorySnapshot struct {
+	Cities []*cities.City `json:"cities"`
+}
+
+func cloneCities(list []*cities.City) []*cities.City {
+	out := make([]*cities.City, len(list))
+	for i, city := range list {
+		out[i] = proto.Clone(city).(*cities.City)
+	}
+	return out
+}
+
+// Snapshot copies the repository content. Later writes do not change it.
+func (r *memoryRepository) Snapshot() repositorySnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return repositorySnapshot{Cities: cloneCities(r.cities)}
+}
+
+// Restore replaces the repository content with snap.
+func (r *memoryRepository) Restore(snap repositorySnapshot) {
+	list := cloneCities(snap.Cities)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cities = list
+	r.bump()
+}
+
+// loadFixtures reads a JSON array of cities. Cities without an id get one
+// from ids.
+func loadFixtures(rd io.Reader, ids IDGenerator) (repositorySnapshot, error) {
+	var list []*cities.City
+	if err := json.NewDecoder(rd).Decode(&list); err != nil {
+		return repositorySnapshot{}, fmt.Errorf("parse fixtures: %w", err)
+	}
+
+	for _, city := range list {
+		if city.Id == "" {
+			city.Id = ids.NewID()
+		}
+	}
+
+	return repositorySnapshot{Cities: list}, nil
+}
+
+func loadFixtureFile(path string, ids IDGenerator) (repositorySnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return repositorySnapshot{}, err
+	}
+	defer f.Close()
+
+	return loadFixtures(f, ids)
+}
+
+// adminRepository returns a snapshot on GET. PUT restores a snapshot taken
+// earlier, POST loads fixtures, so tests can reset the data between
+// scenarios without restarting the server.
+func adminRepository(repo *memoryRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var snap repositorySnapshot
+			if err := json.NewDecoder(r.Body).Decode(&snap); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			repo.Restore(snap)
+		case http.MethodPost:
+			snap, err := loadFixtures(r.Body, repo.ids)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			repo.Restore(snap)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := json.Marshal(repo.Snapshot())
+		if err != nil {
+			log.Println("error marshalling snapshot", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(data); err != nil {
+			log.Println("error writing result", err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"expvar"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	gatewayShed      = expvar.NewMap("gateway_shed")
+	gatewayInflight  = expvar.NewInt("gateway_inflight")
+	gatewayLatencyMs = expvar.NewInt("gateway_latency_ms")
+)
+
+// gatewayShedder rejects REST requests up front with 503 and Retry-After
+// while the backend is saturated. Those requests would only queue behind the
+// others and time out, after using backend time that is already short.
+//
+// The backend counts as saturated when too many REST requests are in flight,
+// when the gRPC admission queue is nearly full, or when the REST requests
+// finishing lately took longer than maxLatency. The latency only counts while
+// requests are in flight, they are what brings it down again.
+type gatewayShedder struct {
+	maxInflight int64
+	maxLatency  time.Duration
+	queue       *admissionQueue
+
+	inflight atomic.Int64
+
+	mu      sync.Mutex
+	latency time.Duration
+}
+
+func newGatewayShedder(maxInflight int, maxLatency time.Duration, queue *admissionQueue) *gatewayShedder {
+	return &gatewayShedder{maxInflight: int64(maxInflight), maxLatency: maxLatency, queue: queue}
+}
+
+// saturated returns why new requests are rejected, empty when they are not.
+func (g *gatewayShedder) saturated() string {
+	inflight := g.inflight.Load()
+	switch {
+	case inflight >= g.maxInflight:
+		return "inflight"
+	case g.queue.Depth() >= g.queue.Cap()*3/4:
+		return "queue"
+	case inflight > 0 && g.smoothedLatency() > g.maxLatency:
+		return "latency"
+	}
+	return ""
+}
+
+func (g *gatewayShedder) smoothedLatency() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.latency
+}
+
+func (g *gatewayShedder) observe(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.latency == 0 {
+		g.latency = d
+	} else {
+		g.latency = (4*g.latency + d) / 5
+	}
+	gatewayLatencyMs.Set(g.latency.Milliseconds())
+}
+
+// retryAfter is how long a rejected client should wait, about the time a
+// request takes now in whole seconds, at least a second.
+func (g *gatewayShedder) retryAfter() time.Duration {
+	return time.Duration(math.Max(1, math.Ceil(g.smoothedLatency().Seconds()))) * time.Second
+}
+
+// Handler sheds the requests of next. sample is false for the streaming
+// routes, their duration says nothing about the backend.
+func (g *gatewayShedder) Handler(next http.Handler, sample bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reason := g.saturated(); reason != "" {
+			gatewayShed.Add(reason, 1)
+			// The RetryInfo is the Retry-After header.
+			err := grpcerr.RetryError(codes.Unavailable, "backend saturated ("+reason+"), retry later", g.retryAfter())
+			grpcerr.WriteJSON(w, err, ctxmeta.RequestID(r.Context()))
+			return
+		}
+
+		gatewayInflight.Set(g.inflight.Add(1))
+		defer func() {
+			gatewayInflight.Set(g.inflight.Add(-1))
+		}()
+
+		start := time.Now()
+		next.ServeHTTP(w, r)
+
+		// A request the client gave up on did not run to the end.
+		if sample && r.Context().Err() == nil {
+			g.observe(time.Since(start))
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+)
+
+// TestRESTRejections rejects a request up front, for maintenance and for a
+// saturated backend: the client gets the grpcerr.HTTPError body the other
+// errors of the REST API have.
+func TestRESTRejections(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request not rejected")
+	})
+	m := &maintenance{}
+	m.Set(true, "upgrade")
+	shed := newGatewayShedder(0, time.Second, newAdmissionQueue(1, 4, 0))
+
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		wantMessage    string
+		wantRetryAfter string
+	}{
+		{name: "maintenance", handler: m.HTTP(next), wantMessage: "server in maintenance: upgrade"},
+		{name: "saturated", handler: shed.Handler(next, true), wantMessage: "backend saturated (inflight), retry later", wantRetryAfter: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/cities", nil)
+			req = req.WithContext(ctxmeta.WithRequestID(req.Context(), "req-1"))
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+
+			var body grpcerr.HTTPError
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q: %v", rec.Body, err)
+			}
+			want := grpcerr.HTTPError{Code: "Unavailable", Message: tt.wantMessage, RequestID: "req-1"}
+			if rec.Code != http.StatusServiceUnavailable || body != want {
+				t.Fatalf("got %d %+v, want 503 %+v", rec.Code, body, want)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Fatalf("Retry-After: got %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"expvar"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+)
+
+// ballast is allocated once and never touched. It makes the heap look bigger
+// to the GC, which then runs less often while the real heap is small, for
+// instance while thousands of streams are cancelled at once. Its pages are
+// never written, so it costs address space rather than memory.
+var ballast []byte
+
+// applyGCSettings sets the GC target and the soft memory limit and allocates
+// the ballast, zero leaves each one alone. GOGC and GOMEMLIMIT still work,
+// these override them.
+func applyGCSettings(percent int, memoryLimit, ballastSize int64) {
+	if percent != 0 {
+		debug.SetGCPercent(percent)
+	}
+	if memoryLimit > 0 {
+		debug.SetMemoryLimit(memoryLimit)
+	}
+	if ballastSize > 0 {
+		ballast = make([]byte, ballastSize)
+	}
+}
+
+// gcConfig describes the GC settings in effect, for the server info.
+func gcConfig() map[string]string {
+	percent := debug.SetGCPercent(-1)
+	debug.SetGCPercent(percent)
+	return map[string]string{
+		"gc_percent":      strconv.Itoa(percent),
+		"gc_memory_limit": strconv.FormatInt(debug.SetMemoryLimit(-1), 10),
+		"gc_ballast":      strconv.Itoa(len(ballast)),
+	}
+}
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IDGenerator creates city ids. The ids must stay unique when several
+// servers create cities at the same time.
+type IDGenerator interface {
+	NewID() string
+}
+
+// newIDGenerator returns the generator called name. node only matters for
+// snowflake ids and must differ between the servers.
+func newIDGenerator(name string, node int64) (IDGenerator, error) {
+	switch name {
+	case "", "ulid":
+		return &ulidGenerator{}, nil
+	case "uuid":
+		return uuidGenerator{}, nil
+	case "snowflake":
+		if node < 0 || node > snowflakeMaxNode {
+			return nil, fmt.Errorf("snowflake node must be between 0 and %d", snowflakeMaxNode)
+		}
+		return &snowflakeGenerator{node: node}, nil
+	}
+	return nil, fmt.Errorf("unknown id generator %q", name)
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator creates ULIDs: 48 bits of milliseconds and 80 random bits,
+// in Crockford's base32. Ids created in the same millisecond increment the
+// random part, so they still sort in creation order.
+type ulidGenerator struct {
+	mu      sync.Mutex
+	lastMs  uint64
+	entropy [10]byte
+}
+
+func (g *ulidGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	if ms > g.lastMs {
+		g.lastMs = ms
+		if _, err := rand.Read(g.entropy[:]); err != nil {
+			panic(err)
+		}
+	} else {
+		for i := len(g.entropy) - 1; i >= 0; i-- {
+			g.entropy[i]++
+			if g.entropy[i] != 0 {
+				break
+			}
+		}
+	}
+
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:], uint16(g.lastMs>>32))
+	binary.BigEndian.PutUint32(id[2:], uint32(g.lastMs))
+	copy(id[6:], g.entropy[:])
+
+	return encodeCrockford(id)
+}
+
+// encodeCrockford encodes the 128 bits of id as 26 characters, the first one
+// holding only the top 3 bits.
+func encodeCrockford(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[0:])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// uuidGenerator creates random version 4 UUIDs.
+type uuidGenerator struct{}
+
+func (uuidGenerator) NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	snowflakeMaxNode  = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSeq   = 1<<snowflakeSeqBits - 1
+)
+
+// snowflakeEpoch is the start of the 41 bit millisecond clock.
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// snowflakeGenerator creates Twitter style ids: milliseconds since
+// snowflakeEpoch, the node number and a sequence within the millisecond.
+type snowflakeGenerator struct {
+	node int64
+
+	mu     sync.Mutex
+	lastMs int64
+	seq    int64
+}
+
+func (g *snowflakeGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Since(snowflakeEpoch).Milliseconds()
+	if ms < g.lastMs {
+		// The clock went back, keep counting from the last id.
+		ms = g.lastMs
+	}
+
+	if ms == g.lastMs {
+		g.seq = (g.seq + 1) & snowflakeMaxSeq
+		if g.seq == 0 {
+			// The sequence is exhausted, wait for the next millisecond.
+			for ms <= g.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				ms = time.Since(snowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		g.seq = 0
+	}
+	g.lastMs = ms
+
+	id := ms<<(snowflakeNodeBits+snowflakeSeqBits) | g.node<<snowflakeSeqBits | g.seq
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"go-cancel/deadline"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// processingTimeUnary reports how long the handler ran in the processing-time
+// trailer and logs the call: the client's request-id and address, the
+// deadline it gave, the status code and how long it took.
+func processingTimeUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start, budget := time.Now(), deadlineLeft(ctx)
+	resp, err := handler(ctx, req)
+	elapsed := time.Since(start)
+
+	if err := grpc.SetTrailer(ctx, metadata.Pairs("processing-time", elapsed.String())); err != nil {
+		debugf("cannot set trailer: %v", err)
+	}
+	logRequest(ctx, info.FullMethod, budget, err, elapsed)
+
+	return resp, err
+}
+
+func processingTimeStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start, budget := time.Now(), deadlineLeft(ss.Context())
+	err := handler(srv, ss)
+	elapsed := time.Since(start)
+
+	ss.SetTrailer(metadata.Pairs("processing-time", elapsed.String()))
+	logRequest(ss.Context(), info.FullMethod, budget, err, elapsed)
+
+	return err
+}
+
+// deadlineLeft is the time left before the deadline of ctx, as logged.
+func deadlineLeft(ctx context.Context) string {
+	remaining, ok := deadline.RemainingBudget(ctx)
+	if !ok {
+		return "none"
+	}
+	return remaining.Round(time.Millisecond).String()
+}
+
+// logRequest logs a call once it returned. A call whose context ended is
+// logged with why, whatever code its handler returned.
+func logRequest(ctx context.Context, method, budget string, err error, elapsed time.Duration) {
+	addr := "-"
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	ended := ""
+	if ctx.Err() != nil {
+		ended = fmt.Sprintf(", context ended: %v", context.Cause(ctx))
+	}
+	infof("request-id %s %s from %s deadline %s: %s took %s%s", requestID(ctx), method, addr, budget, status.Code(err), elapsed, ended)
+}
+
+// recovered turns a panic of the call into an Internal error, logging its
+// stack. Only the panics of the goroutine running the handler are caught, a
+// goroutine the handler started still takes the process down.
+func recovered(ctx context.Context, method string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	panics.Add(1)
+	log.Printf("error: request-id %s %s panicked: %v\n%s", requestID(ctx), method, r, debug.Stack())
+	*err = status.Errorf(codes.Internal, "%s panicked", method)
+}
+
+func recoveryUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer recovered(ctx, info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func recoveryStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recovered(ss.Context(), info.FullMethod, &err)
+	return handler(srv, ss)
+}
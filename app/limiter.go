@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gradientLimiter bounds the number of unary calls in flight. The limit
+// follows the gradient between the best latency seen and the latency of the
+// last call: it shrinks when calls queue up somewhere and grows back when
+// they get fast again. Calls above the limit are shed with Unavailable and a
+// RetryInfo telling the client when to come back.
+type gradientLimiter struct {
+	min, max   float64
+	smoothing  float64
+	retryAfter time.Duration
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	noLoad   time.Duration
+	samples  int
+}
+
+func newGradientLimiter(initial, min, max int, retryAfter time.Duration) *gradientLimiter {
+	l := &gradientLimiter{
+		min:        float64(min),
+		max:        float64(max),
+		smoothing:  0.2,
+		retryAfter: retryAfter,
+		limit:      float64(initial),
+	}
+	limiterLimit.Set(int64(initial))
+	return l
+}
+
+func (l *gradientLimiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inflight >= int(l.limit) {
+		return false
+	}
+	l.inflight++
+	limiterInflight.Set(int64(l.inflight))
+	return true
+}
+
+// release records the latency of a finished call and moves the limit.
+// Cancelled calls say nothing about the server and only free their slot.
+func (l *gradientLimiter) release(rtt time.Duration, sample bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inflight := l.inflight
+	l.inflight--
+	limiterInflight.Set(int64(l.inflight))
+
+	if !sample || rtt <= 0 {
+		return
+	}
+
+	// Forget the best latency now and then, it may be stale after the
+	// workload changed.
+	l.samples++
+	if l.noLoad == 0 || rtt < l.noLoad || l.samples%1000 == 0 {
+		l.noLoad = rtt
+	}
+
+	gradient := math.Max(0.5, math.Min(1, float64(l.noLoad)/float64(rtt)))
+	next := l.limit*gradient + math.Sqrt(l.limit)
+
+	// Do not grow a limit that is not being used.
+	if next > l.limit && float64(inflight) < l.limit/2 {
+		return
+	}
+
+	next = l.limit*(1-l.smoothing) + next*l.smoothing
+	l.limit = math.Max(l.min, math.Min(l.max, next))
+	limiterLimit.Set(int64(l.limit))
+}
+
+func (l *gradientLimiter) shed() error {
+	return grpcerr.RetryError(codes.Unavailable, "server is overloaded", l.retryAfter)
+}
+
+// Unary is the interceptor applying the limit to every unary call.
+func (l *gradientLimiter) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !l.acquire() {
+		limiterShed.Add(1)
+		return nil, l.shed()
+	}
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	l.release(time.Since(start), ctx.Err() == nil)
+	return resp, err
+}
+
+// streamLimiter bounds the number of streams in flight on the whole server,
+// each one holds a goroutine and its buffers for as long as it runs. The
+// streams above the limit fail at once with ResourceExhausted rather than
+// waiting for a slot.
+type streamLimiter chan struct{}
+
+func newStreamLimiter(n int) streamLimiter {
+	return make(streamLimiter, n)
+}
+
+// Stream is the interceptor applying the limit to every stream.
+func (l streamLimiter) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	select {
+	case l <- struct{}{}:
+	default:
+		streamsRejected.Add(1)
+		return status.Errorf(codes.ResourceExhausted, "%d streams already in flight", cap(l))
+	}
+	streamsInflight.Add(1)
+	defer func() {
+		streamsInflight.Add(-1)
+		<-l
+	}()
+	return handler(srv, ss)
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// listenAddrs splits a comma separated address list, def when it is empty.
+func listenAddrs(list, def string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(list, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return []string{def}
+	}
+	return addrs
+}
+
+// unixPrefix marks a listen address as the path of a unix socket, e.g.
+// unix:/tmp/cities.sock, for sidecars talking to the server without a TCP
+// port.
+const unixPrefix = "unix:"
+
+// listenNetwork picks tcp4 or tcp6 for a literal address, so "0.0.0.0:9099"
+// and "[::]:9099" can both be bound: a tcp6 wildcard listener only accepts
+// IPv6 then.
+func listenNetwork(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "tcp"
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return "tcp"
+	case ip.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
+}
+
+// listenAll binds every address it can. An address that fails is logged and
+// skipped, for example IPv6 in a container without it, it is only an error
+// when none is left.
+func listenAll(name string, addrs []string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	for _, addr := range addrs {
+		var l net.Listener
+		var err error
+		if path, ok := strings.CutPrefix(addr, unixPrefix); ok {
+			l, err = listenUnix(path)
+		} else {
+			l, err = net.Listen(listenNetwork(addr), addr)
+		}
+		if err != nil {
+			log.Printf("%s: cannot listen on %s: %s", name, addr, err)
+			continue
+		}
+		listeners = append(listeners, l)
+	}
+
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("%s: no address to listen on in %v", name, addrs)
+	}
+	return listeners, nil
+}
+
+// listenUnix listens on the unix socket path. The socket of a server that
+// did not stop cleanly is removed first, one still answering is left alone.
+// Closing the listener removes the socket.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// serveAll runs serve on every listener. A listener that fails stops alone,
+// serveAll returns once all of them stopped, with the last error.
+func serveAll(name string, listeners []net.Listener, serve func(net.Listener) error) error {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		lastErr error
+	)
+
+	for _, l := range listeners {
+		wg.Add(1)
+		go func(l net.Listener) {
+			defer wg.Done()
+
+			err := serve(l)
+			if err != nil {
+				log.Printf("%s: listener %s stopped: %s", name, l.Addr(), err)
+			}
+
+			mu.Lock()
+			lastErr = err
+			mu.Unlock()
+		}(l)
+	}
+
+	wg.Wait()
+	return lastErr
+}
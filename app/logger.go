@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// LogSettings is the part of the logging behaviour that can be changed while
+// the server is running, either by sending SIGHUP (the file named by
+// LOG_CONFIG is re-read) or through the /admin/log endpoint.
+type LogSettings struct {
+	Level           string  `json:"level"`
+	AccessLogSample float64 `json:"access_log_sample"`
+	DebugStream     bool    `json:"debug_stream"`
+}
+
+var logLevels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+var (
+	logMu       sync.RWMutex
+	logSettings = LogSettings{Level: "info", AccessLogSample: 1, DebugStream: true}
+)
+
+func currentLogSettings() LogSettings {
+	logMu.RLock()
+	defer logMu.RUnlock()
+	return logSettings
+}
+
+func setLogSettings(s LogSettings) error {
+	s.Level = strings.ToLower(s.Level)
+	if _, ok := logLevels[s.Level]; !ok {
+		return fmt.Errorf("unknown log level %q", s.Level)
+	}
+	if s.AccessLogSample < 0 || s.AccessLogSample > 1 {
+		return fmt.Errorf("access log sample must be between 0 and 1, got %v", s.AccessLogSample)
+	}
+
+	logMu.Lock()
+	logSettings = s
+	logMu.Unlock()
+	return nil
+}
+
+func logEnabled(level string) bool {
+	return logLevels[level] >= logLevels[currentLogSettings().Level]
+}
+
+func debugf(format string, v ...interface{}) {
+	if logEnabled("debug") {
+		log.Printf("debug: "+format, v...)
+	}
+}
+
+func infof(format string, v ...interface{}) {
+	if logEnabled("info") {
+		log.Printf(format, v...)
+	}
+}
+
+// streamDebug replaces the bare println calls used to follow the progress of
+// List and ListStream, so they can be silenced without a restart.
+func streamDebug(ctx context.Context, v ...interface{}) {
+	if currentLogSettings().DebugStream {
+		log.Println(append([]interface{}{"request-id", requestID(ctx)}, v...)...)
+	}
+}
+
+// loadLogSettings reads the settings file, keeping the current values for
+// fields the file does not mention.
+func loadLogSettings(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	s := currentLogSettings()
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return setLogSettings(s)
+}
+
+// errNoLogConfig is returned by reloadLogSettings when there is nothing to
+// reload.
+var errNoLogConfig = errors.New("LOG_CONFIG is not set, keeping log settings")
+
+// reloadLogSettings re-reads LOG_CONFIG.
+func reloadLogSettings() error {
+	path := os.Getenv("LOG_CONFIG")
+	if path == "" {
+		return errNoLogConfig
+	}
+	if err := loadLogSettings(path); err != nil {
+		return err
+	}
+	log.Printf("log settings reloaded: %+v", currentLogSettings())
+	return nil
+}
+
+// watchLogReload re-reads LOG_CONFIG every time the process receives SIGHUP,
+// until ctx ends.
+func watchLogReload(ctx context.Context) {
+	if path := os.Getenv("LOG_CONFIG"); path != "" {
+		if err := loadLogSettings(path); err != nil {
+			log.Println("error loading log settings", err)
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-hup:
+			if err := reloadLogSettings(); err != nil {
+				log.Println("SIGHUP received:", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// accessLog logs a sample of REST requests, the rate is AccessLogSample.
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rand.Float64() < currentLogSettings().AccessLogSample {
+			infof("request-id %s %s %s %s", requestID(r.Context()), r.RemoteAddr, r.Method, r.URL.Path)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminLog shows the log settings on GET and replaces the given fields on PUT.
+func adminLog(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		s := currentLogSettings()
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := setLogSettings(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("log settings changed: %+v", s)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := json.Marshal(currentLogSettings())
+	if err != nil {
+		log.Println("error marshalling log settings", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.Println("error writing result", err)
+	}
+}
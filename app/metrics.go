@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"go-cancel/deadline"
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics are published with expvar, the REST server exposes them on
+// /debug/vars and the dashboard polls that endpoint.
+var (
+	activeStreams   = expvar.NewInt("active_streams")
+	cancellations   = expvar.NewInt("cancellations")
+	requestsByCode  = expvar.NewMap("requests_by_code")
+	deadlineSeconds = expvar.NewMap("deadline_seconds")
+	latencyMillis   = expvar.NewMap("latency_ms")
+	rejectedStale   = expvar.NewInt("rejected_stale")
+	limiterLimit    = expvar.NewInt("limiter_limit")
+	limiterInflight = expvar.NewInt("limiter_inflight")
+	limiterShed     = expvar.NewInt("limiter_shed")
+	streamsInflight = expvar.NewInt("streams_inflight")
+	streamsRejected = expvar.NewInt("streams_rejected")
+
+	compressionSkipped = expvar.NewInt("compression_skipped")
+	coalescedCalls     = expvar.NewInt("coalesced_calls")
+	panics             = expvar.NewInt("panics")
+)
+
+type bucket struct {
+	name  string
+	upper time.Duration
+}
+
+var deadlineBuckets = []bucket{
+	{"<1", time.Second},
+	{"1-5", 5 * time.Second},
+	{"5-30", 30 * time.Second},
+	{">30", 1<<63 - 1},
+}
+
+var latencyBuckets = []bucket{
+	{"<10", 10 * time.Millisecond},
+	{"10-100", 100 * time.Millisecond},
+	{"100-1000", time.Second},
+	{"1000-10000", 10 * time.Second},
+	{">10000", 1<<63 - 1},
+}
+
+func bucketName(buckets []bucket, d time.Duration) string {
+	for _, b := range buckets {
+		if d < b.upper {
+			return b.name
+		}
+	}
+	return buckets[len(buckets)-1].name
+}
+
+// observeDeadline records how much time the client gave the call.
+func observeDeadline(ctx context.Context) {
+	remaining, ok := deadline.RemainingBudget(ctx)
+	if !ok {
+		deadlineSeconds.Add("none", 1)
+		return
+	}
+	deadlineSeconds.Add(bucketName(deadlineBuckets, remaining), 1)
+}
+
+// observeResult counts the outcome of a call. A handler that failed after its
+// context ended is counted as cancelled, whatever error it returned.
+func observeResult(ctx context.Context, err error, elapsed time.Duration) {
+	if ctxErr := grpcerr.FromContext(ctx); err != nil && ctxErr != nil {
+		err = ctxErr
+	}
+
+	code := status.Code(err)
+	requestsByCode.Add(code.String(), 1)
+	if code == codes.Canceled || code == codes.DeadlineExceeded {
+		cancellations.Add(1)
+	}
+	latencyMillis.Add(bucketName(latencyBuckets, elapsed), 1)
+}
+
+func metricsUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	observeDeadline(ctx)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeResult(ctx, err, time.Since(start))
+	return resp, err
+}
+
+func metricsStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	observeDeadline(ss.Context())
+	activeStreams.Add(1)
+	defer activeStreams.Add(-1)
+
+	start := time.Now()
+	err := handler(srv, ss)
+	observeResult(ss.Context(), err, time.Since(start))
+	return err
+}
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+)
+
+// nameGenerator names the cities of the generated lists.
+type nameGenerator interface {
+	// Country is the key the generator is picked by, empty for the random
+	// letters.
+	Country() string
+	// Name returns the name of the i-th city, counting from 1. It fails
+	// with the status error of ctx once ctx ended, the names are made one
+	// at a time as the list is produced.
+	Name(ctx context.Context, i int) (string, error)
+}
+
+// randomNames are the ten random letters the demo always used.
+type randomNames struct{}
+
+func (randomNames) Country() string {
+	return ""
+}
+
+func (randomNames) Name(ctx context.Context, i int) (string, error) {
+	if err := grpcerr.FromContext(ctx); err != nil {
+		return "", err
+	}
+	return randSeq(10), nil
+}
+
+// corpus names the cities of one country: its largest cities first, then
+// names built from its syllables, so a long list stays plausible. Some
+// countries have longer names, or a script taking three bytes a character,
+// and the same list makes a larger payload, sent in less time before the
+// deadline.
+type corpus struct {
+	country string
+	cities  []string
+
+	// A generated name is minSyllables to maxSyllables of onset, nucleus
+	// and, half of the time, coda, followed by a suffix a third of the time.
+	onsets, nuclei, codas []string
+	suffixes              []string
+	minSyllables          int
+	maxSyllables          int
+}
+
+func (c *corpus) Country() string {
+	return c.country
+}
+
+func (c *corpus) Name(ctx context.Context, i int) (string, error) {
+	if err := grpcerr.FromContext(ctx); err != nil {
+		return "", err
+	}
+	if i >= 1 && i <= len(c.cities) {
+		return c.cities[i-1], nil
+	}
+
+	var b strings.Builder
+	n := c.minSyllables + rand.Intn(c.maxSyllables-c.minSyllables+1)
+	for s := 0; s < n; s++ {
+		b.WriteString(pick(c.onsets))
+		b.WriteString(pick(c.nuclei))
+		if len(c.codas) > 0 && rand.Intn(2) == 0 {
+			b.WriteString(pick(c.codas))
+		}
+	}
+	if len(c.suffixes) > 0 && rand.Intn(3) == 0 {
+		b.WriteString(pick(c.suffixes))
+	}
+
+	name := b.String()
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:], nil
+}
+
+func pick(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return list[rand.Intn(len(list))]
+}
+
+var corpora = map[string]*corpus{
+	"id": {
+		country: "id",
+		cities: []string{
+			"Jakarta", "Surabaya", "Bandung", "Medan", "Semarang", "Makassar", "Palembang",
+			"Tangerang", "Depok", "Bekasi", "Padang", "Denpasar", "Malang", "Samarinda",
+			"Pekanbaru", "Banjarmasin", "Pontianak", "Manado", "Yogyakarta", "Balikpapan",
+		},
+		onsets:       []string{"b", "d", "g", "j", "k", "l", "m", "n", "p", "r", "s", "t", "w", "ng", "ny"},
+		nuclei:       []string{"a", "a", "i", "u", "e", "o"},
+		codas:        []string{"n", "ng", "r", "k", "h"},
+		minSyllables: 2,
+		maxSyllables: 4,
+	},
+	"de": {
+		country: "de",
+		cities: []string{
+			"Berlin", "Hamburg", "München", "Köln", "Frankfurt am Main", "Stuttgart", "Düsseldorf",
+			"Leipzig", "Dortmund", "Essen", "Bremen", "Dresden", "Hannover", "Nürnberg",
+			"Duisburg", "Bochum", "Wuppertal", "Bielefeld", "Bonn", "Münster",
+		},
+		onsets:       []string{"b", "br", "d", "f", "fr", "g", "h", "k", "kl", "l", "m", "n", "r", "sch", "st", "w"},
+		nuclei:       []string{"a", "e", "e", "i", "o", "u", "ä", "ö", "ü", "ei", "au"},
+		codas:        []string{"n", "rg", "ck", "ld", "nn", "tz", "rf", "m", "l"},
+		suffixes:     []string{"burg", "dorf", "hausen", "heim", "stadt", "feld", "bach"},
+		minSyllables: 1,
+		maxSyllables: 3,
+	},
+	"fi": {
+		country: "fi",
+		cities: []string{
+			"Helsinki", "Espoo", "Tampere", "Vantaa", "Oulu", "Turku", "Jyväskylä",
+			"Kuopio", "Lahti", "Pori", "Kouvola", "Joensuu", "Lappeenranta", "Hämeenlinna",
+			"Vaasa", "Seinäjoki", "Rovaniemi", "Mikkeli", "Kotka", "Salo",
+		},
+		onsets:       []string{"h", "j", "k", "l", "m", "n", "p", "r", "s", "t", "v"},
+		nuclei:       []string{"a", "aa", "e", "i", "ii", "o", "u", "uu", "y", "ä", "ö", "ie", "uo"},
+		codas:        []string{"n", "l", "s", "t", "k"},
+		suffixes:     []string{"järvi", "lahti", "joki", "niemi", "koski", "salmi", "linna"},
+		minSyllables: 2,
+		maxSyllables: 4,
+	},
+	"jp": {
+		country: "jp",
+		cities: []string{
+			"東京", "横浜", "大阪", "名古屋", "札幌", "福岡", "神戸",
+			"川崎", "京都", "さいたま", "広島", "仙台", "千葉", "北九州",
+			"堺", "新潟", "浜松", "熊本", "相模原", "岡山",
+		},
+		// The kana are whole syllables, there is no nucleus to add.
+		onsets: []string{
+			"あ", "い", "う", "お", "か", "き", "く", "こ", "さ", "し", "た", "ち", "つ", "な",
+			"に", "の", "は", "ふ", "ま", "み", "や", "よ", "ら", "り", "わ",
+		},
+		codas:        []string{"ん"},
+		suffixes:     []string{"市", "町", "村"},
+		minSyllables: 2,
+		maxSyllables: 4,
+	},
+	"us": {
+		country: "us",
+		cities: []string{
+			"New York", "Los Angeles", "Chicago", "Houston", "Phoenix", "Philadelphia", "San Antonio",
+			"San Diego", "Dallas", "San Jose", "Austin", "Jacksonville", "Fort Worth", "Columbus",
+			"Charlotte", "Indianapolis", "San Francisco", "Seattle", "Denver", "Washington",
+		},
+		onsets:       []string{"b", "br", "ch", "cl", "f", "gr", "h", "l", "m", "r", "sp", "w"},
+		nuclei:       []string{"a", "e", "i", "o", "ee", "oo"},
+		codas:        []string{"n", "l", "r", "ck", "ng", "s"},
+		suffixes:     []string{"ville", "ton", "field", "wood", "port", " Springs", " City"},
+		minSyllables: 1,
+		maxSyllables: 2,
+	},
+}
+
+// languageCountries is the country of a locale naming only its language.
+var languageCountries = map[string]string{"id": "id", "de": "de", "fi": "fi", "ja": "jp", "en": "us"}
+
+// defaultNames names the cities of the calls without a locale, or with one
+// there is no corpus for.
+var defaultNames nameGenerator = randomNames{}
+
+// configureDefaultNames sets the country of defaultNames, empty for the
+// random letters.
+func configureDefaultNames(country string) error {
+	if country == "" {
+		defaultNames = randomNames{}
+		return nil
+	}
+	c, ok := corpora[strings.ToLower(country)]
+	if !ok {
+		countries := make([]string, 0, len(corpora))
+		for k := range corpora {
+			countries = append(countries, k)
+		}
+		sort.Strings(countries)
+		return fmt.Errorf("no city names for %q, use one of %s", country, strings.Join(countries, ", "))
+	}
+	defaultNames = c
+	return nil
+}
+
+// namesFrom picks the names of the call by its locale, the region of en-US
+// or the language of a bare de. Unknown locales get the default, a browser
+// sends whatever its user speaks.
+func namesFrom(ctx context.Context) nameGenerator {
+	locale := strings.ToLower(ctxmeta.Locale(ctx))
+	if locale == "" {
+		return defaultNames
+	}
+	lang, region, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	if c, ok := corpora[region]; ok {
+		return c
+	}
+	if c, ok := corpora[languageCountries[lang]]; ok {
+		return c
+	}
+	return defaultNames
+}
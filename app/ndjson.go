@@ -0,0 +1,193 @@
+package app
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/status"
+)
+
+// cityProducer is a producer started by produceCities. Cities is closed
+// when it stops.
+type cityProducer struct {
+	Cities <-chan *cities.City
+	err    error
+}
+
+// Err is the error that stopped the producer before it sent every city,
+// nil when it sent them all or ctx ended first. It is only set once Cities
+// is closed.
+func (p *cityProducer) Err() error {
+	return p.err
+}
+
+// produceCities generates cities at the pace of ListStream until it has sent
+// them all, ctx ends or the simulator fails a step.
+func produceCities(ctx context.Context) *cityProducer {
+	out := make(chan *cities.City)
+	p := &cityProducer{Cities: out}
+	sim := simulatorFrom(ctx)
+
+	fail := func(err error) {
+		if ctx.Err() == nil {
+			p.err = err
+		}
+	}
+	go func() {
+		defer close(out)
+
+		for i := 1; i <= sim.Count(); i++ {
+			if err := sim.Step(ctx, stepStream); err != nil {
+				fail(err)
+				return
+			}
+
+			city, err := simulatedCity(ctx, i)
+			if err != nil {
+				fail(err)
+				return
+			}
+			select {
+			case out <- city:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// ndjsonEnd is the last line of a complete export. A stream that ends without
+// it was cut short, by a cancellation or a failed write.
+type ndjsonEnd struct {
+	Complete bool `json:"complete"`
+	Count    int  `json:"count"`
+}
+
+// ndjsonWriter writes cities as newline delimited JSON, gzipped when asked
+// to. Every city is flushed through the gzip writer, so the reader can
+// decode it right away.
+type ndjsonWriter struct {
+	z     *gzip.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newNDJSONWriter(w io.Writer, compress bool) *ndjsonWriter {
+	n := &ndjsonWriter{}
+	if compress {
+		n.z = gzip.NewWriter(w)
+		w = n.z
+	}
+	n.enc = json.NewEncoder(w)
+	return n
+}
+
+func (n *ndjsonWriter) Write(city *cities.City) error {
+	if err := n.enc.Encode(city); err != nil {
+		return err
+	}
+	n.count++
+
+	if n.z != nil {
+		return n.z.Flush()
+	}
+	return nil
+}
+
+// Finish writes the end marker and closes the gzip stream. It is only called
+// for complete exports, a truncated one has neither.
+func (n *ndjsonWriter) Finish() error {
+	if err := n.enc.Encode(ndjsonEnd{Complete: true, Count: n.count}); err != nil {
+		return err
+	}
+	if n.z != nil {
+		return n.z.Close()
+	}
+	return nil
+}
+
+// acceptsGzip reports whether the client listed gzip in Accept-Encoding.
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(v, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// ndjson streams cities as newline delimited JSON, flushing every line, with
+// chunked encoding and gzip when the client accepts it. Each write gets
+// writeTimeout to reach the client, a client that stops reading makes the
+// write fail and stops the producer. A stream the producer failed ends
+// without the end marker, as one cut short.
+func ndjson(writeTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		produced := produceCities(ctx)
+
+		// Wait for the producer before returning, so it is measured too.
+		stopped := watchPropagation(r.Context(), "ndjson")
+		defer func() {
+			cancel()
+			for range produced.Cities {
+			}
+			stopped()
+		}()
+
+		rc := http.NewResponseController(w)
+		compress := acceptsGzip(r)
+		out := newNDJSONWriter(w, compress)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if compress {
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		w.WriteHeader(http.StatusOK)
+
+		for city := range produced.Cities {
+			if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				log.Println("request-id", requestID(r.Context()), "error setting write deadline", err)
+			}
+
+			if err := out.Write(city); err != nil {
+				log.Println("request-id", requestID(r.Context()), "error writing city, stopping stream", err)
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				log.Println("request-id", requestID(r.Context()), "error flushing city, stopping stream", err)
+				return
+			}
+		}
+
+		if err := grpcerr.FromContext(ctx); err != nil {
+			log.Println("request-id", requestID(r.Context()), "error streaming cities", status.Convert(err).Message())
+			return
+		}
+		if err := produced.Err(); err != nil {
+			log.Println("request-id", requestID(r.Context()), "error producing cities, stopping stream", status.Convert(err).Message())
+			return
+		}
+
+		if err := out.Finish(); err != nil {
+			log.Println("request-id", requestID(r.Context()), "error finishing stream", err)
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			log.Println("request-id", requestID(r.Context()), "error flushing end of stream", err)
+		}
+	}
+}
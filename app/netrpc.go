@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"net/rpc"
+
+	"go-cancel/citiesrpc"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// netRPCCities serves the unary methods of CitiesService over net/rpc, with
+// the same handlers and unary interceptors as the gRPC server. net/rpc has
+// no streams, ListStream and the other streaming methods are gRPC only.
+type netRPCCities struct {
+	rpc    *RpcServer
+	cities *citiesServer
+}
+
+// newNetRPCServer returns the net/rpc server to mount on citiesrpc.Path.
+func newNetRPCServer(rpcServer *RpcServer, srv *citiesServer) (*rpc.Server, error) {
+	s := rpc.NewServer()
+	if err := s.RegisterName(citiesrpc.ServiceName, &netRPCCities{rpc: rpcServer, cities: srv}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// call runs handler under the context the arguments describe. Nothing ends
+// that context early but its timeout: net/rpc does not tell the server when
+// a client gives up.
+func (n *netRPCCities) call(args citiesrpc.Call, method string, req interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.New(args.Metadata))
+	if args.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, args.Timeout)
+		defer cancel()
+	}
+	return n.rpc.CallUnary(ctx, "/cities.CitiesService/"+method, req, handler)
+}
+
+func (n *netRPCCities) List(args *citiesrpc.ListArgs, reply *cities.Cities) error {
+	resp, err := n.call(args.Call, "List", &cities.EmptyMessage{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return n.cities.List(ctx, req.(*cities.EmptyMessage))
+	})
+	if err != nil {
+		return err
+	}
+	proto.Merge(reply, resp.(*cities.Cities))
+	return nil
+}
+
+func (n *netRPCCities) Create(args *citiesrpc.CreateArgs, reply *cities.City) error {
+	resp, err := n.call(args.Call, "Create", args.Request, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return n.cities.Create(ctx, req.(*cities.CreateCityRequest))
+	})
+	if err != nil {
+		return err
+	}
+	proto.Merge(reply, resp.(*cities.City))
+	return nil
+}
+
+func (n *netRPCCities) Stats(args *citiesrpc.StatsArgs, reply *cities.CityStats) error {
+	resp, err := n.call(args.Call, "Stats", args.Request, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return n.cities.Stats(ctx, req.(*cities.StatsRequest))
+	})
+	if err != nil {
+		return err
+	}
+	proto.Merge(reply, resp.(*cities.CityStats))
+	return nil
+}
+
+func (n *netRPCCities) GetServerInfo(args *citiesrpc.ServerInfoArgs, reply *cities.ServerInfo) error {
+	resp, err := n.call(args.Call, "GetServerInfo", &cities.EmptyMessage{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return n.cities.GetServerInfo(ctx, req.(*cities.EmptyMessage))
+	})
+	if err != nil {
+		return err
+	}
+	proto.Merge(reply, resp.(*cities.ServerInfo))
+	return nil
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"go-cancel/config"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Option configures the server built by NewServer.
+type Option func(*serverOptions)
+
+type serverOptions struct {
+	unary      []grpc.UnaryServerInterceptor
+	stream     []grpc.StreamServerInterceptor
+	grpc       []grpc.ServerOption
+	health     bool
+	reflection bool
+	root       context.Context
+}
+
+// WithInterceptors appends a unary and a stream interceptor to the chain.
+// Interceptors run in the order they were added, either one may be nil.
+func WithInterceptors(unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) Option {
+	return func(o *serverOptions) {
+		if unary != nil {
+			o.unary = append(o.unary, unary)
+		}
+		if stream != nil {
+			o.stream = append(o.stream, stream)
+		}
+	}
+}
+
+// WithKeepalive sets the keepalive parameters and the enforcement policy
+// applied to clients.
+func WithKeepalive(params keepalive.ServerParameters, policy keepalive.EnforcementPolicy) Option {
+	return WithServerOptions(grpc.KeepaliveParams(params), grpc.KeepaliveEnforcementPolicy(policy))
+}
+
+// WithMaxStreams limits the number of concurrent streams on each connection.
+func WithMaxStreams(n uint32) Option {
+	return WithServerOptions(grpc.MaxConcurrentStreams(n))
+}
+
+// WithDeadlineCompression stops compressing stream messages once less than
+// threshold is left before the call's deadline. A zero threshold leaves
+// compression alone. Otherwise the gzip compressor of the process is
+// replaced by one that can skip a message, the option must be applied before
+// any call is served.
+func WithDeadlineCompression(threshold time.Duration) Option {
+	return func(o *serverOptions) {
+		if threshold <= 0 {
+			return
+		}
+		registerDeadlineGzip.Do(func() {
+			encoding.RegisterCompressor(deadlineGzip{})
+		})
+		o.stream = append(o.stream, deadlineCompression(threshold).Stream)
+		o.grpc = append(o.grpc, grpc.ForceServerCodec(newPassthroughCodec()))
+	}
+}
+
+// WithRootContext makes ctx the root of every handler context, cancelling
+// it cancels the calls in flight with its cause.
+func WithRootContext(ctx context.Context) Option {
+	return func(o *serverOptions) {
+		o.root = ctx
+	}
+}
+
+// WithConfig applies the settings of cfg that belong to the gRPC server.
+func WithConfig(cfg config.Server) Option {
+	return func(o *serverOptions) {
+		if cfg.MaxStreams > 0 {
+			WithMaxStreams(cfg.MaxStreams)(o)
+		}
+		WithKeepalive(keepalive.ServerParameters{
+			Time:                  cfg.KeepaliveTime,
+			Timeout:               cfg.KeepaliveTimeout,
+			MaxConnectionIdle:     cfg.MaxConnectionIdle,
+			MaxConnectionAge:      cfg.MaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.MaxConnectionAgeGrace,
+		}, keepalive.EnforcementPolicy{
+			MinTime:             cfg.KeepaliveMinTime,
+			PermitWithoutStream: cfg.KeepalivePermitWithoutStream,
+		})(o)
+		if cfg.GRPCReflection {
+			WithReflection()(o)
+		}
+	}
+}
+
+// WithHealth registers the grpc.health.v1.Health service.
+func WithHealth() Option {
+	return func(o *serverOptions) {
+		o.health = true
+	}
+}
+
+// WithReflection registers the gRPC reflection service, so grpcurl and
+// evans can list and call the services without the proto files.
+func WithReflection() Option {
+	return func(o *serverOptions) {
+		o.reflection = true
+	}
+}
+
+// WithServerOptions passes options straight to grpc.NewServer.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *serverOptions) {
+		o.grpc = append(o.grpc, opts...)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !minimal
+
+package app
+
+import (
+	"crypto/tls"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// WithTLS serves gRPC over TLS using cfg. The in-process connections of the
+// REST gateway stay plaintext, the REST server already did the TLS.
+func WithTLS(cfg *tls.Config) Option {
+	return WithServerOptions(grpc.Creds(inProcessCreds{credentials.NewTLS(cfg)}))
+}
+
+// inProcessCreds skips the handshake of the bufconn connections, they never
+// leave the process.
+type inProcessCreds struct {
+	credentials.TransportCredentials
+}
+
+func (c inProcessCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if conn.LocalAddr().Network() == "bufconn" {
+		return conn, inProcessAuth{credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity}}, nil
+	}
+	return c.TransportCredentials.ServerHandshake(conn)
+}
+
+func (c inProcessCreds) Clone() credentials.TransportCredentials {
+	return inProcessCreds{c.TransportCredentials.Clone()}
+}
+
+type inProcessAuth struct {
+	credentials.CommonAuthInfo
+}
+
+func (inProcessAuth) AuthType() string {
+	return "in-process"
+}
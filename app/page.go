@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Page sizes of ListPage.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageTokenPrefix starts the page tokens, which hold the position of the
+// last city of the previous page. Clients treat them as opaque.
+const pageTokenPrefix = "page:"
+
+func pageToken(last int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(pageTokenPrefix + strconv.Itoa(last)))
+}
+
+// parsePageToken returns the position of the last city of the previous page,
+// zero for an empty token.
+func parsePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	invalid := status.Errorf(codes.InvalidArgument, "invalid page token %q", token)
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(data), pageTokenPrefix) {
+		return 0, invalid
+	}
+	last, err := strconv.Atoi(strings.TrimPrefix(string(data), pageTokenPrefix))
+	if err != nil || last < 0 {
+		return 0, invalid
+	}
+	return last, nil
+}
+
+// ListPage returns the cities after the page token, page_size of them at
+// most. Every city costs a list step, as in List, so the time a page takes
+// grows with its size and a client can size its pages to its deadline.
+func (u *citiesServer) ListPage(ctx context.Context, in *cities.ListPageRequest) (*cities.CityPage, error) {
+	last, err := parsePageToken(in.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	size := int(in.GetPageSize())
+	switch {
+	case size == 0:
+		size = defaultPageSize
+	case size > maxPageSize:
+		size = maxPageSize
+	}
+
+	sim := simulatorFrom(ctx)
+	cityAt := simulatedCity
+	if static, ok := staticSnapshot(); ok {
+		sim = instant{count: len(static)}
+		cityAt = func(_ context.Context, i int) (*cities.City, error) {
+			return static[i-1], nil
+		}
+	}
+	count := sim.Count()
+
+	page := &cities.CityPage{}
+	i := last + 1
+	for ; i <= count && len(page.City) < size; i++ {
+		if err := sim.Step(ctx, stepList); err != nil {
+			return nil, err
+		}
+		city, err := cityAt(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		page.City = append(page.City, city)
+	}
+	if i <= count {
+		page.NextPageToken = pageToken(i - 1)
+	}
+	return page, nil
+}
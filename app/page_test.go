@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListPage(t *testing.T) {
+	ctx := withSimulator(context.Background(), instant{count: 45})
+	u := &citiesServer{}
+
+	tests := []struct {
+		name     string
+		size     uint32
+		wantLens []int
+	}{
+		{name: "default size", wantLens: []int{20, 20, 5}},
+		{name: "small pages", size: 15, wantLens: []int{15, 15, 15}},
+		{name: "capped size", size: 1000, wantLens: []int{45}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lens []int
+			next, token := 1, ""
+			for {
+				page, err := u.ListPage(ctx, &cities.ListPageRequest{PageSize: tt.size, PageToken: token})
+				if err != nil {
+					t.Fatalf("ListPage: %v", err)
+				}
+				for _, city := range page.City {
+					if city.Id != strconv.Itoa(next) {
+						t.Fatalf("city %s where %d was due", city.Id, next)
+					}
+					next++
+				}
+				lens = append(lens, len(page.City))
+				if page.NextPageToken == "" {
+					break
+				}
+				token = page.NextPageToken
+			}
+			if !reflect.DeepEqual(lens, tt.wantLens) {
+				t.Fatalf("pages of %v, want %v", lens, tt.wantLens)
+			}
+		})
+	}
+
+	// Not base64, a resume token of ListStream, a negative position.
+	for _, token := range []string{"5", "Y2l0eToxMA", "cGFnZTotMQ"} {
+		if _, err := u.ListPage(ctx, &cities.ListPageRequest{PageToken: token}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("page token %q: got %v, want InvalidArgument", token, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"expvar"
+	"hash/fnv"
+	"io"
+	"sync"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// prefetchLookups is the number of lookups running, it must go back to zero
+// once the streams using a prefetcher have ended.
+var prefetchLookups = expvar.NewInt("prefetch_lookups")
+
+var regions = []string{"Sumatra", "Java", "Kalimantan", "Sulawesi", "Papua"}
+
+// lookupCityDetail stands for a call to a downstream service, with the
+// default simulator it takes up to 300ms.
+func lookupCityDetail(ctx context.Context, city *cities.City) (*cities.City, error) {
+	if err := simulatorFrom(ctx).Step(ctx, stepLookup); err != nil {
+		return nil, err
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(city.GetName()))
+	sum := h.Sum32()
+
+	out := proto.Clone(city).(*cities.City)
+	out.Detail = &cities.CityDetail{
+		Region:     regions[sum%uint32(len(regions))],
+		Population: sum % 10000000,
+	}
+	return out, nil
+}
+
+type prefetched struct {
+	city *cities.City
+	err  error
+}
+
+// prefetcher runs lookup for the cities from through to ahead of the
+// reader, at most parallel at a time and never more than parallel results
+// ahead. produce makes each city as the lookahead reaches it. Next returns
+// the results in order.
+type prefetcher struct {
+	cancel context.CancelFunc
+	order  chan chan prefetched
+	wg     sync.WaitGroup
+}
+
+func newPrefetcher(ctx context.Context, from, to int, produce func(context.Context, int) (*cities.City, error), parallel int, lookup func(context.Context, *cities.City) (*cities.City, error)) *prefetcher {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &prefetcher{
+		cancel: cancel,
+		order:  make(chan chan prefetched, parallel),
+	}
+
+	p.wg.Add(1)
+	go p.run(ctx, from, to, produce, parallel, lookup)
+	return p
+}
+
+func (p *prefetcher) run(ctx context.Context, from, to int, produce func(context.Context, int) (*cities.City, error), parallel int, lookup func(context.Context, *cities.City) (*cities.City, error)) {
+	defer p.wg.Done()
+	defer close(p.order)
+
+	sem := make(chan struct{}, parallel)
+	for i := from; i <= to; i++ {
+		// A full order channel means the reader is parallel results behind.
+		slot := make(chan prefetched, 1)
+		select {
+		case p.order <- slot:
+		case <-ctx.Done():
+			return
+		}
+
+		city, err := produce(ctx, i)
+		if err != nil {
+			slot <- prefetched{err: err}
+			return
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			slot <- prefetched{err: grpcerr.FromContext(ctx)}
+			return
+		}
+
+		p.wg.Add(1)
+		prefetchLookups.Add(1)
+		go func(city *cities.City) {
+			defer p.wg.Done()
+			defer prefetchLookups.Add(-1)
+			defer func() { <-sem }()
+
+			out, err := lookup(ctx, city)
+			slot <- prefetched{city: out, err: err}
+		}(city)
+	}
+}
+
+// Next returns the next city, io.EOF after the last one.
+func (p *prefetcher) Next(ctx context.Context) (*cities.City, error) {
+	var slot chan prefetched
+	select {
+	case s, ok := <-p.order:
+		if !ok {
+			return nil, io.EOF
+		}
+		slot = s
+	case <-ctx.Done():
+		return nil, grpcerr.FromContext(ctx)
+	}
+
+	select {
+	case r := <-slot:
+		return r.city, r.err
+	case <-ctx.Done():
+		return nil, grpcerr.FromContext(ctx)
+	}
+}
+
+// Close cancels the lookups still running and waits for them. Results
+// nobody read are dropped.
+func (p *prefetcher) Close() {
+	p.cancel()
+	for range p.order {
+	}
+	p.wg.Wait()
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// propagationAlert is how long work may keep running after the REST client
+// went away before it is logged as a warning.
+var propagationAlert = 250 * time.Millisecond
+
+var (
+	propagationMillis = expvar.NewMap("cancel_propagation_ms")
+	propagationLast   = expvar.NewInt("cancel_propagation_last_ms")
+	propagationSlow   = expvar.NewInt("cancel_propagation_slow")
+)
+
+// watchPropagation measures how long the work of a REST request runs on
+// after the client disconnected. Call the returned func once everything the
+// request started, down to the repository, has stopped.
+func watchPropagation(ctx context.Context, name string) (stopped func()) {
+	cancelled := make(chan time.Time, 1)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelled <- time.Now()
+		case <-done:
+			cancelled <- time.Time{}
+		}
+	}()
+
+	return func() {
+		close(done)
+
+		// Only a disconnect is measured, not a deadline running out.
+		at := <-cancelled
+		if at.IsZero() || ctx.Err() != context.Canceled {
+			return
+		}
+
+		elapsed := time.Since(at)
+		propagationMillis.Add(bucketName(latencyBuckets, elapsed), 1)
+		propagationLast.Set(elapsed.Milliseconds())
+		if elapsed > propagationAlert {
+			propagationSlow.Add(1)
+			log.Printf("warning: %s kept running %s after the client disconnected, above %s", name, elapsed, propagationAlert)
+		}
+	}
+}
+
+// restWatchKey carries the id of the watch of a gateway call, the call is
+// measured from the disconnect of the HTTP client to the end of the handler.
+const restWatchKey = "rest-watch-id"
+
+type restWatchIDKey struct{}
+
+var (
+	restWatches sync.Map
+	restWatchID atomic.Int64
+)
+
+// watchGateway watches the REST requests the gateway forwards to a gRPC
+// stream, the handler of the stream tells it when it stopped with
+// gatewayStopped.
+func watchGateway(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strconv.FormatInt(restWatchID.Add(1), 10)
+		restWatches.Store(id, watchPropagation(r.Context(), name))
+		defer restWatches.Delete(id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), restWatchIDKey{}, id)))
+	})
+}
+
+// gatewayStopped returns the func a handler calls once it and the work it
+// started have stopped. It does nothing but for a call of the gateway
+// watched by watchGateway.
+func gatewayStopped(ctx context.Context) (stopped func()) {
+	if p, ok := peer.FromContext(ctx); !ok || p.Addr.Network() != "bufconn" {
+		return func() {}
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := md.Get(restWatchKey)
+	if len(v) == 0 {
+		return func() {}
+	}
+	w, ok := restWatches.LoadAndDelete(v[len(v)-1])
+	if !ok {
+		return func() {}
+	}
+	return w.(func())
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"expvar"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"go-cancel/config"
+)
+
+// propagationCount is how many disconnects watchPropagation measured.
+func propagationCount() int64 {
+	var n int64
+	propagationMillis.Do(func(kv expvar.KeyValue) {
+		v, _ := strconv.ParseInt(kv.Value.String(), 10, 64)
+		n += v
+	})
+	return n
+}
+
+// TestDisconnectPropagation disconnects from the REST streams once their
+// producer runs, ListStream behind the gateway for /v1/cities/stream, and
+// fails when the work behind them did not stop within the propagation alert,
+// or was never told about the disconnect.
+func TestDisconnectPropagation(t *testing.T) {
+	cfg := config.DefaultServer()
+	cfg.GRPCListen, cfg.RESTListen = "127.0.0.1:0", "127.0.0.1:0"
+	cfg.ListSize, cfg.StreamInterval = 1000, 20*time.Millisecond
+	a := New(cfg, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := a.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer a.Stop(context.Background())
+
+	tests := []struct {
+		name string
+		path string
+		// first reads the first city, the producer is running then.
+		first func(r *bufio.Reader) error
+	}{
+		{
+			name: "ndjson",
+			path: "/cities/ndjson",
+			first: func(r *bufio.Reader) error {
+				_, err := r.ReadString('\n')
+				return err
+			},
+		},
+		{
+			name: "gateway stream",
+			path: "/v1/cities/stream?enrich=true",
+			first: func(r *bufio.Reader) error {
+				_, err := r.ReadString('\n')
+				return err
+			},
+		},
+		{
+			name: "server-sent events",
+			path: "/cities/stream",
+			first: func(r *bufio.Reader) error {
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == "\n" {
+						return err
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := propagationCount()
+
+			reqCtx, disconnect := context.WithCancel(ctx)
+			defer disconnect()
+			req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, "http://"+a.RESTAddr()+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s: %v", tt.path, err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET %s: %s", tt.path, resp.Status)
+			}
+			if err := tt.first(bufio.NewReader(resp.Body)); err != nil {
+				t.Fatalf("first city: %v", err)
+			}
+			disconnect()
+			resp.Body.Close()
+
+			deadline := time.Now().Add(5 * time.Second)
+			for propagationCount() == before {
+				if time.Now().After(deadline) {
+					t.Fatal("the work behind the request never saw the disconnect")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if took := time.Duration(propagationLast.Value()) * time.Millisecond; took > cfg.CancelPropagationAlert {
+				t.Fatalf("the work ran on %s after the disconnect, above the alert of %s", took, cfg.CancelPropagationAlert)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"expvar"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+var rateLimited = expvar.NewInt("rate_limited")
+
+// maxRateBuckets is how many clients are tracked before the full buckets,
+// of the clients that went quiet, are dropped.
+const maxRateBuckets = 10000
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// clientRateLimiter gives every client a token bucket refilled with rate
+// calls per second, up to burst. A client is the identity the authenticator
+// let in, or the client address without its port when byPeer is set or the
+// call is anonymous, the HTTP client for a call of the REST gateway. A call
+// finding the bucket empty fails with ResourceExhausted, a retry-after
+// trailer and a RetryInfo telling when the next token arrives, so one
+// client hammering List cannot starve the others.
+type clientRateLimiter struct {
+	rate   float64
+	burst  float64
+	byPeer bool
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newClientRateLimiter(rate float64, burst int, byPeer bool) *clientRateLimiter {
+	return &clientRateLimiter{rate: rate, burst: float64(burst), byPeer: byPeer, buckets: make(map[string]*tokenBucket)}
+}
+
+func (l *clientRateLimiter) key(ctx context.Context) string {
+	if id, ok := identityFrom(ctx); ok && !l.byPeer {
+		return "id:" + id.Subject
+	}
+	addr, ok := clientAddr(ctx)
+	if !ok {
+		return "-"
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// take spends a token of the client, wait is how long until it has one
+// when it has none left.
+func (l *clientRateLimiter) take(key string) (wait time.Duration, ok bool) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, found := l.buckets[key]
+	if !found {
+		if len(l.buckets) >= maxRateBuckets {
+			l.sweep(now)
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep drops the buckets that have refilled since, their clients start
+// over with a full one anyway.
+func (l *clientRateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *clientRateLimiter) reject(wait time.Duration) (metadata.MD, error) {
+	rateLimited.Add(1)
+	// Rounded up, a client waiting that long finds a token.
+	wait = wait.Truncate(time.Millisecond) + time.Millisecond
+	err := grpcerr.RetryError(codes.ResourceExhausted, "rate limit exceeded, retry after "+wait.String(), wait)
+	// The trailer says what the RetryInfo says, for clients not decoding
+	// the details.
+	delay, _ := grpcerr.RetryDelay(err)
+	return metadata.Pairs("retry-after", delay.String()), err
+}
+
+func (l *clientRateLimiter) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if wait, ok := l.take(l.key(ctx)); !ok {
+		trailer, err := l.reject(wait)
+		grpc.SetTrailer(ctx, trailer)
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *clientRateLimiter) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if wait, ok := l.take(l.key(ss.Context())); !ok {
+		trailer, err := l.reject(wait)
+		ss.SetTrailer(trailer)
+		return err
+	}
+	return handler(srv, ss)
+}
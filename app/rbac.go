@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"go-cancel/deadline"
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rbacPolicy is the JSON file named by RBAC_POLICY. Methods maps a full
+// method name to the permission it needs; the methods it does not list need
+// none. A role is granted its permissions and those of the roles it
+// inherits:
+//
+//	{
+//	  "methods": {"/cities.CitiesService/Create": "cities.write"},
+//	  "roles": {
+//	    "reader": {"permissions": ["cities.read"]},
+//	    "editor": {"permissions": ["cities.write"], "inherits": ["reader"]}
+//	  }
+//	}
+type rbacPolicy struct {
+	Methods map[string]string   `json:"methods"`
+	Roles   map[string]rbacRole `json:"roles"`
+}
+
+type rbacRole struct {
+	Permissions []string `json:"permissions"`
+	Inherits    []string `json:"inherits"`
+}
+
+// rbac checks every call against the policy, with the roles of the caller
+// the authenticator let in. The evaluation gets at most timeout, and never
+// more than a tenth of what the client left the call, so a slow check fails
+// the call with time to spare instead of eating its budget.
+type rbac struct {
+	policy  rbacPolicy
+	timeout time.Duration
+}
+
+func newRBAC(path string, timeout time.Duration) (*rbac, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy rbacPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, role := range policy.Roles {
+		for _, parent := range role.Inherits {
+			if _, ok := policy.Roles[parent]; !ok {
+				return nil, fmt.Errorf("%s: role %q inherits unknown role %q", path, name, parent)
+			}
+		}
+	}
+	return &rbac{policy: policy, timeout: timeout}, nil
+}
+
+// check returns nil when the call may run.
+func (r *rbac) check(ctx context.Context, method string) error {
+	permission, ok := r.policy.Methods[method]
+	if !ok {
+		return nil
+	}
+
+	// A public method the policy lists anyway has no caller to check.
+	id, ok := identityFrom(ctx)
+	if !ok {
+		return status.Errorf(codes.Unauthenticated, "%s requires permission %q, the caller is anonymous", method, permission)
+	}
+
+	timeout := r.timeout
+	if remaining, ok := deadline.RemainingBudget(ctx); ok && remaining/10 < timeout {
+		timeout = remaining / 10
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Only the timeout of the check is reported as such, a call cancelled
+	// or out of time by itself ends as the call did.
+	granted, err := r.grants(checkCtx, id.Roles, permission)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return grpcerr.FromContext(ctx)
+	case err != nil:
+		return status.Errorf(codes.DeadlineExceeded, "permission check for %s ran out of time after %s", method, timeout)
+	case !granted:
+		return status.Errorf(codes.PermissionDenied, "%s requires permission %q, not granted to %s", method, permission, id.Subject)
+	}
+	return nil
+}
+
+// grants walks the roles and those they inherit, until one grants
+// permission or ctx ends.
+func (r *rbac) grants(ctx context.Context, roles []string, permission string) (bool, error) {
+	seen := make(map[string]bool)
+	for len(roles) > 0 {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		name := roles[0]
+		roles = roles[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		role := r.policy.Roles[name]
+		for _, p := range role.Permissions {
+			if p == permission {
+				return true, nil
+			}
+		}
+		roles = append(roles, role.Inherits...)
+	}
+	return false, nil
+}
+
+func (r *rbac) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := r.check(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (r *rbac) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := r.check(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRBACCheck(t *testing.T) {
+	policy := rbacPolicy{
+		Methods: map[string]string{"/cities.CitiesService/Create": "cities.write"},
+		Roles: map[string]rbacRole{
+			"reader": {Permissions: []string{"cities.read"}},
+			"editor": {Permissions: []string{"cities.write"}, Inherits: []string{"reader"}},
+			"admin":  {Inherits: []string{"editor"}},
+		},
+	}
+	caller := func(roles ...string) context.Context {
+		return context.WithValue(context.Background(), identityKey{}, identity{Subject: "ana", Roles: roles})
+	}
+
+	tests := []struct {
+		name    string
+		ctx     func(t *testing.T) context.Context
+		method  string
+		timeout time.Duration
+		want    codes.Code
+		wantMsg string
+	}{
+		{name: "unlisted method", ctx: func(*testing.T) context.Context { return context.Background() }, method: "/cities.CitiesService/List", want: codes.OK},
+		{name: "anonymous", ctx: func(*testing.T) context.Context { return context.Background() }, want: codes.Unauthenticated},
+		{name: "granted", ctx: func(*testing.T) context.Context { return caller("editor") }, want: codes.OK},
+		{name: "inherited", ctx: func(*testing.T) context.Context { return caller("admin") }, want: codes.OK},
+		{name: "denied", ctx: func(*testing.T) context.Context { return caller("reader") }, want: codes.PermissionDenied},
+		{
+			name: "call cancelled",
+			ctx: func(*testing.T) context.Context {
+				ctx, cancel := context.WithCancel(caller("editor"))
+				cancel()
+				return ctx
+			},
+			want: codes.Canceled,
+		},
+		{
+			name: "call out of time",
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithDeadline(caller("editor"), time.Now().Add(-time.Second))
+				t.Cleanup(cancel)
+				return ctx
+			},
+			want:    codes.DeadlineExceeded,
+			wantMsg: "deadline is exceeded",
+		},
+		{
+			name:    "check out of time",
+			ctx:     func(*testing.T) context.Context { return caller("editor") },
+			timeout: -1,
+			want:    codes.DeadlineExceeded,
+			wantMsg: "permission check for /cities.CitiesService/Create ran out of time",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &rbac{policy: policy, timeout: time.Second}
+			if tt.timeout != 0 {
+				r.timeout = tt.timeout
+			}
+			method := tt.method
+			if method == "" {
+				method = "/cities.CitiesService/Create"
+			}
+			err := r.check(tt.ctx(t), method)
+			if st := status.Convert(err); st.Code() != tt.want || !strings.HasPrefix(st.Message(), tt.wantMsg) {
+				t.Fatalf("got %v, want %s %s", err, tt.want, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"sync"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// readiness turns the health of the gRPC server to SERVING once every
+// listener it waits for is up. The health server ignores it after Shutdown,
+// a server draining stays NOT_SERVING.
+type readiness struct {
+	server *RpcServer
+	up     chan struct{}
+
+	mu      sync.Mutex
+	waiting map[string]bool
+	addrs   map[string][]net.Addr
+}
+
+func newReadiness(server *RpcServer, names ...string) *readiness {
+	r := &readiness{
+		server:  server,
+		up:      make(chan struct{}),
+		waiting: make(map[string]bool, len(names)),
+		addrs:   make(map[string][]net.Addr, len(names)),
+	}
+	for _, name := range names {
+		r.waiting[name] = true
+	}
+	return r
+}
+
+// Ready marks the listeners of name as up.
+func (r *readiness) Ready(name string, listeners []net.Listener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, l := range listeners {
+		r.addrs[name] = append(r.addrs[name], l.Addr())
+	}
+	if !r.waiting[name] {
+		return
+	}
+	delete(r.waiting, name)
+	if len(r.waiting) > 0 {
+		return
+	}
+	close(r.up)
+	if r.server.Health == nil {
+		return
+	}
+	r.server.Health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	for service := range r.server.Grpc.GetServiceInfo() {
+		r.server.Health.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+	infof("listeners up, serving")
+}
+
+// Up is closed once every listener is up.
+func (r *readiness) Up() <-chan struct{} {
+	return r.up
+}
+
+// Addrs returns the addresses the listeners of name are bound to.
+func (r *readiness) Addrs(name string) []net.Addr {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]net.Addr(nil), r.addrs[name]...)
+}
+
+// Serving reports whether the gRPC server accepts calls: its listeners are
+// up and, with the health service, it has not started shutting down.
+func (r *readiness) Serving() bool {
+	select {
+	case <-r.up:
+	default:
+		return false
+	}
+	if r.server.Health == nil {
+		return true
+	}
+	resp, err := r.server.Health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	return err == nil && resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+}
+
+// healthz is the liveness probe, it answers as long as the REST server
+// does.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+// readyz is the readiness probe, 503 until the gRPC server accepts calls
+// and again once it drains, so a load balancer stops sending REST requests
+// the gateway cannot serve.
+func (r *readiness) readyz(w http.ResponseWriter, req *http.Request) {
+	if !r.Serving() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// versionHandler reports the build of the server.
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"version": version,
+		"commit":  buildCommit(),
+		"build":   buildFlavor,
+		"go":      runtime.Version(),
+	})
+}
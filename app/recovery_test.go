@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-cancel/config"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// panicking is a simulator whose steps panic, the handlers calling it panic
+// on whatever goroutine runs them.
+type panicking struct{}
+
+func (panicking) Name() string { return "panicking" }
+func (panicking) Count() int   { return 3 }
+func (panicking) Step(ctx context.Context, step string) error {
+	panic("simulated handler bug")
+}
+
+// TestHandlerPanicThroughFullChain panics List behind the coalescer and the
+// admission queue, which run it on goroutines of their own: the call fails
+// with Internal and the server keeps serving.
+func TestHandlerPanicThroughFullChain(t *testing.T) {
+	simulators["panicking"] = panicking{}
+	defer delete(simulators, "panicking")
+
+	cfg := config.DefaultServer()
+	cfg.GRPCListen, cfg.RESTListen = "127.0.0.1:0", "127.0.0.1:0"
+	a := New(cfg, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := a.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer a.Stop(context.Background())
+
+	conn, err := grpc.Dial(a.GRPCAddr(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	client := cities.NewCitiesServiceClient(conn)
+
+	callCtx := metadata.AppendToOutgoingContext(ctx, "simulator", "panicking")
+	_, err = client.List(callCtx, &cities.EmptyMessage{})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("List with a panicking handler: got %v, want Internal", err)
+	}
+
+	if _, err := client.GetServerInfo(ctx, &cities.EmptyMessage{}); err != nil {
+		t.Fatalf("GetServerInfo after the panic: %v", err)
+	}
+}
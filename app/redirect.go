@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// serveRedirect answers every request on listeners with a redirect to the
+// same URL over HTTPS on port, until ctx ends. 308 keeps the method and the
+// body of the request.
+func serveRedirect(ctx context.Context, listeners []net.Listener, port string) {
+	srv := &http.Server{Handler: redirectHTTPS(port), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	serveAll("rest-redirect", listeners, srv.Serve)
+}
+
+func redirectHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.TrimSuffix(strings.TrimPrefix(r.Host, "["), "]")
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		u := *r.URL
+		u.Scheme, u.Host = "https", host
+		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+)
+
+// CityRepository is the storage behind Create and the city statistics.
+type CityRepository interface {
+	// BatchInsert stores all names in one transaction and returns the created
+	// cities in the same order.
+	BatchInsert(ctx context.Context, names []string) ([]*cities.City, error)
+	// All returns every stored city in the order they were inserted.
+	All(ctx context.Context) ([]*cities.City, error)
+}
+
+// memoryRepository keeps cities in memory. commitDelay simulates the time a
+// real database needs to commit a transaction, and conflictRate the share of
+// transactions that fail with a serialization error.
+type memoryRepository struct {
+	mu           sync.Mutex
+	ids          IDGenerator
+	cities       []*cities.City
+	commitDelay  time.Duration
+	conflictRate float64
+	version      atomic.Pointer[repositoryVersion]
+}
+
+// repositoryVersion tells the content of a repository apart from an earlier
+// one: every write increments N and sets Modified. Epoch is when the
+// repository was created, N starts over with every repository and so with
+// every process.
+type repositoryVersion struct {
+	Epoch    int64
+	N        uint64
+	Modified time.Time
+}
+
+func newMemoryRepository(commitDelay time.Duration, ids IDGenerator) *memoryRepository {
+	r := &memoryRepository{commitDelay: commitDelay, ids: ids}
+	now := time.Now()
+	r.version.Store(&repositoryVersion{Epoch: now.UnixNano(), Modified: now})
+	return r
+}
+
+// Version returns the version of the content without taking the lock, so
+// a request for unchanged cities costs nothing.
+func (r *memoryRepository) Version() repositoryVersion {
+	return *r.version.Load()
+}
+
+// bump moves to the next version, r.mu must be held.
+func (r *memoryRepository) bump() {
+	v := r.version.Load()
+	r.version.Store(&repositoryVersion{Epoch: v.Epoch, N: v.N + 1, Modified: time.Now()})
+}
+
+func (r *memoryRepository) BatchInsert(ctx context.Context, names []string) ([]*cities.City, error) {
+	select {
+	case <-ctx.Done():
+		return nil, grpcerr.FromContext(ctx)
+	case <-time.After(r.commitDelay):
+	}
+
+	if rand.Float64() < r.conflictRate {
+		return nil, errSerialization
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]*cities.City, 0, len(names))
+	for _, name := range names {
+		list = append(list, &cities.City{Id: r.ids.NewID(), Name: name})
+	}
+	r.cities = append(r.cities, list...)
+	r.bump()
+
+	return list, nil
+}
+
+func (r *memoryRepository) All(ctx context.Context) ([]*cities.City, error) {
+	if err := grpcerr.FromContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]*cities.City, len(r.cities))
+	copy(list, r.cities)
+	return list, nil
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+
+	"go-cancel/ctxmeta"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestID returns the request id of the call, the one the client sent or
+// the one the server made up for it.
+func requestID(ctx context.Context) string {
+	if id := ctxmeta.RequestID(ctx); id != "" {
+		return id
+	}
+	return "-"
+}
+
+// requestIDUnary and requestIDStream run first on every call, NewServer puts
+// them even before the handler root. They read the propagated values of the
+// metadata and echo the request id in the response headers. The REST server
+// does the same with httpmw.RequestID.
+func requestIDUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, id := ctxmeta.EnsureRequestID(ctxmeta.FromIncoming(ctx))
+	grpc.SetHeader(ctx, metadata.Pairs(ctxmeta.RequestIDKey, id))
+	return handler(ctx, req)
+}
+
+func requestIDStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, id := ctxmeta.EnsureRequestID(ctxmeta.FromIncoming(ss.Context()))
+	ss.SetHeader(metadata.Pairs(ctxmeta.RequestIDKey, id))
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
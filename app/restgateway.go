@@ -0,0 +1,179 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// restGatewayBuffer is the size of each direction of an in-process
+// connection of the REST gateway.
+const restGatewayBuffer = 1 << 20
+
+// restClientKey carries the address of the HTTP client of a gateway call,
+// the peer of the call is the in-process connection.
+const restClientKey = "rest-client-addr"
+
+// restGateway serves the REST routes generated from the google.api.http
+// options of cities.proto. It calls the gRPC server through an in-process
+// connection, so a REST request goes through the same interceptors as a
+// gRPC call: its Grpc-Timeout header becomes the deadline of the call, and
+// the status of the call the HTTP status of the response.
+type restGateway struct {
+	http.Handler
+	lis  *bufconn.Listener
+	conn *grpc.ClientConn
+}
+
+func newRestGateway(ctx context.Context) (*restGateway, error) {
+	lis := bufconn.Listen(restGatewayBuffer)
+	conn, err := grpc.DialContext(ctx, "bufconn",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(restGatewayHeader),
+		runtime.WithMetadata(restGatewayMetadata),
+		runtime.WithProtoErrorHandler(restGatewayError),
+	)
+	// The mux serves a route with the first handler registered for it.
+	mux.Handle(http.MethodGet, listPattern, handleList(mux, cities.NewCitiesServiceClient(conn)))
+	if err := cities.RegisterCitiesServiceHandler(ctx, mux, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &restGateway{Handler: mux, lis: lis, conn: conn}, nil
+}
+
+// listPattern is the route of List, the pattern of the generated handler.
+var listPattern = runtime.MustPattern(runtime.NewPattern(1,
+	[]int{int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1},
+	[]string{"v1", "cities"}, "", runtime.AssumeColonVerbOpt(true)))
+
+// handleList serves List like the generated handler does, but marshals the
+// cities with a listMarshaler: a client that went away stops the encoding,
+// as it did before the gateway.
+func handleList(mux *runtime.ServeMux, client cities.CitiesServiceClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r)
+		if err != nil {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+			return
+		}
+
+		var md runtime.ServerMetadata
+		list, err := client.List(ctx, &cities.EmptyMessage{}, grpc.Header(&md.HeaderMD), grpc.Trailer(&md.TrailerMD))
+		ctx = runtime.NewServerMetadataContext(r.Context(), md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, listMarshaler{Marshaler: outbound, ctx: r.Context()}, w, r, list, mux.GetForwardResponseOptions()...)
+	}
+}
+
+// listMarshaler marshals the Cities of List with encodeJSONArray, which
+// checks ctx between the cities, and anything else with Marshaler.
+type listMarshaler struct {
+	runtime.Marshaler
+	ctx context.Context
+}
+
+func (m listMarshaler) Marshal(v interface{}) ([]byte, error) {
+	list, ok := v.(*cities.Cities)
+	if !ok || len(list.GetCity()) == 0 {
+		return m.Marshaler.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"city":`)
+	if err := encodeJSONArray(m.ctx, &buf, list.GetCity()); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Listener is the listener of the in-process connections, the gRPC server
+// serves on it and stops it.
+func (g *restGateway) Listener() net.Listener {
+	return g.lis
+}
+
+func (g *restGateway) Close() error {
+	return g.conn.Close()
+}
+
+// restGatewayMetadata sends the values httpmw.RequestID read from the
+// headers, and the simulator preset, as the metadata a gRPC client would,
+// the address of the HTTP client and the id of its watchGateway.
+func restGatewayMetadata(ctx context.Context, r *http.Request) metadata.MD {
+	md, _ := metadata.FromOutgoingContext(ctxmeta.ToOutgoing(r.Context()))
+	md = md.Copy()
+	if name := r.Header.Get("Simulator"); name != "" {
+		md.Set("simulator", name)
+	}
+	md.Set(restClientKey, r.RemoteAddr)
+	if id, ok := r.Context().Value(restWatchIDKey{}).(string); ok {
+		md.Set(restWatchKey, id)
+	}
+	return md
+}
+
+// restGatewayHeader forwards the headers runtime.DefaultHeaderMatcher does,
+// but a Grpc-Metadata-Rest-Client-Addr header: an HTTP client sending it
+// would choose the address its calls are rate limited by. The same goes for
+// the id of the watch of the call.
+func restGatewayHeader(key string) (string, bool) {
+	h, ok := runtime.DefaultHeaderMatcher(key)
+	if !ok || strings.EqualFold(h, restClientKey) || strings.EqualFold(h, restWatchKey) {
+		return "", false
+	}
+	return h, true
+}
+
+// clientAddr is the address of the client of a call: the peer, or for a
+// call of the REST gateway the HTTP client it forwarded. The metadata of
+// the other peers is not trusted, a gRPC client could send any address. The
+// value of restGatewayMetadata is the last one, the gateway joins it after
+// the ones of the headers.
+func clientAddr(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	if p.Addr.Network() == "bufconn" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get(restClientKey); len(v) > 0 {
+			return v[len(v)-1], true
+		}
+	}
+	return p.Addr.String(), true
+}
+
+// restGatewayError answers with the status of grpcerr.HTTPStatus, 499 for
+// a client that went away and 504 for a call out of time included, and a
+// grpcerr.HTTPError body.
+func restGatewayError(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	grpcerr.WriteJSON(w, err, ctxmeta.RequestID(r.Context()))
+}
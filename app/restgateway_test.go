@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestRestGatewaySpoofedClientAddr sends the metadata header of the client
+// address the gateway forwards: the rate limit is still keyed on the
+// address the HTTP request came from.
+func TestRestGatewaySpoofedClientAddr(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	gw, err := newRestGateway(ctx)
+	if err != nil {
+		t.Fatalf("gateway: %v", err)
+	}
+	defer gw.Close()
+
+	limiter := newClientRateLimiter(1, 1, false)
+	keys := make(chan string, 1)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		keys <- limiter.key(ctx)
+		return &cities.Cities{}, nil
+	}))
+	cities.RegisterCitiesServiceServer(srv, &cities.UnimplementedCitiesServiceServer{})
+	go srv.Serve(gw.Listener())
+	defer srv.Stop()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/cities", nil)
+	req.RemoteAddr = "192.0.2.7:40000"
+	req.Header.Set("Grpc-Metadata-Rest-Client-Addr", "198.51.100.1:1")
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /v1/cities: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	if key := <-keys; key != "192.0.2.7" {
+		t.Fatalf("rate limit key: got %q, want the host of RemoteAddr 192.0.2.7", key)
+	}
+}
+
+// TestRestGatewayList gets /v1/cities through the gateway: the body is the
+// Cities the server returned, and a client gone before it is encoded stops
+// the encoding.
+func TestRestGatewayList(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	gw, err := newRestGateway(ctx)
+	if err != nil {
+		t.Fatalf("gateway: %v", err)
+	}
+	defer gw.Close()
+
+	want := &cities.Cities{City: []*cities.City{
+		{Id: "1", Name: "Bandung"},
+		{Id: "2", Name: "Surabaya", Detail: &cities.CityDetail{Region: "East Java", Population: 2874000}},
+	}}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return want, nil
+	}))
+	cities.RegisterCitiesServiceServer(srv, &cities.UnimplementedCitiesServiceServer{})
+	go srv.Serve(gw.Listener())
+	defer srv.Stop()
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/cities", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /v1/cities: status %d, body %s", rec.Code, rec.Body)
+	}
+	got := &cities.Cities{}
+	if err := protojson.Unmarshal(rec.Body.Bytes(), got); err != nil {
+		t.Fatalf("body %s: %v", rec.Body, err)
+	}
+	if !proto.Equal(got, want) {
+		t.Fatalf("body: got %v, want %v", got, want)
+	}
+
+	gone, disconnect := context.WithCancel(context.Background())
+	disconnect()
+	before := restEncodingAborted.Value()
+	_, err = listMarshaler{Marshaler: &runtime.JSONPb{OrigName: true}, ctx: gone}.Marshal(want)
+	if status.Code(err) != codes.Canceled || restEncodingAborted.Value() != before+1 {
+		t.Fatalf("marshal for a client gone: got %v, %d encodings aborted", err, restEncodingAborted.Value()-before)
+	}
+}
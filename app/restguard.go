@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// restGuard puts the REST routes answered outside the gateway through the
+// interceptors guarding the gRPC calls, the authentication, the RBAC and
+// the rate limiter, as a call of the method they mirror. A request they
+// reject is answered with the status of the call and a grpcerr.HTTPError.
+type restGuard struct {
+	chain []grpc.UnaryServerInterceptor
+}
+
+// Use appends an interceptor to the guard, they run in the order added.
+func (g *restGuard) Use(unary grpc.UnaryServerInterceptor) {
+	g.chain = append(g.chain, unary)
+}
+
+// HTTP guards next as a call of method. The interceptors see the
+// Authorization header as metadata and the client address as the peer.
+func (g *restGuard) HTTP(method string, next http.Handler) http.Handler {
+	if g == nil || len(g.chain) == 0 {
+		return next
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if v := r.Header.Get("Authorization"); v != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", v))
+		}
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: httpAddr(r.RemoteAddr)})
+
+		var i int
+		var handler grpc.UnaryHandler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			if i == len(g.chain) {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return nil, nil
+			}
+			i++
+			return g.chain[i-1](ctx, req, info, handler)
+		}
+		if _, err := handler(ctx, nil); err != nil {
+			grpcerr.WriteJSON(w, err, ctxmeta.RequestID(r.Context()))
+		}
+	})
+}
+
+// httpAddr is the address of an HTTP client, as the peer of a call.
+type httpAddr string
+
+var _ net.Addr = httpAddr("")
+
+func (a httpAddr) Network() string { return "tcp" }
+func (a httpAddr) String() string  { return string(a) }
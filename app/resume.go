@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// resumeTokenPrefix starts the first tokens, which only hold the id of
+	// the last city. They are still accepted.
+	resumeTokenPrefix = "city:"
+	// resumeStatePrefix starts the tokens holding a JSON resumeState.
+	resumeStatePrefix = "city2:"
+)
+
+// resumableMetadata is the request metadata a resume token carries, so a
+// resumed stream behaves like the interrupted one without the client sending
+// it again. The client may send any of it itself, so the token needs no
+// signature.
+var resumableMetadata = []string{"session-id", "locale", "simulator"}
+
+// resumeState is what a resume token remembers of a stream: how far it got
+// and the request-scoped values it ran with.
+type resumeState struct {
+	Last              int               `json:"last"`
+	Metadata          map[string]string `json:"md,omitempty"`
+	BatchSize         uint32            `json:"batch_size,omitempty"`
+	Enrich            bool              `json:"enrich,omitempty"`
+	EnrichParallelism uint32            `json:"enrich_parallelism,omitempty"`
+}
+
+// token encodes the state with last as the id of the last city sent. Clients
+// treat it as opaque.
+func (s resumeState) token(last int) string {
+	s.Last = last
+	data, err := json.Marshal(s)
+	if err != nil {
+		// Only strings and numbers, it cannot fail.
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(append([]byte(resumeStatePrefix), data...))
+}
+
+// parseResumeState decodes a token, the zero state for an empty one.
+func parseResumeState(token string) (resumeState, error) {
+	var s resumeState
+	if token == "" {
+		return s, nil
+	}
+
+	invalid := status.Error(codes.InvalidArgument, "invalid resume token")
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return s, invalid
+	}
+
+	switch text := string(data); {
+	case strings.HasPrefix(text, resumeStatePrefix):
+		if err := json.Unmarshal(data[len(resumeStatePrefix):], &s); err != nil {
+			return s, invalid
+		}
+	case strings.HasPrefix(text, resumeTokenPrefix):
+		if s.Last, err = strconv.Atoi(strings.TrimPrefix(text, resumeTokenPrefix)); err != nil {
+			return s, invalid
+		}
+	default:
+		return s, invalid
+	}
+
+	if s.Last < 0 {
+		return s, invalid
+	}
+	return s, nil
+}
+
+// resumeStream restores the values carried by the resume token of in. Values
+// sent with the call win over the token, except that enrich cannot be turned
+// off once a stream has it. It returns the context and request for the rest
+// of the call, and the state the next tokens are built from.
+func resumeStream(ctx context.Context, in *cities.ListStreamRequest) (context.Context, *cities.ListStreamRequest, resumeState, error) {
+	state, err := parseResumeState(in.GetResumeToken())
+	if err != nil {
+		return ctx, in, state, err
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	var values map[string]string
+	for _, key := range resumableMetadata {
+		v := md.Get(key)
+		if len(v) == 0 {
+			restored, ok := state.Metadata[key]
+			if !ok {
+				continue
+			}
+			md.Set(key, restored)
+			v = []string{restored}
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[key] = v[0]
+	}
+	ctx = ctxmeta.FromIncoming(metadata.NewIncomingContext(ctx, md))
+
+	// The simulator was picked before the token was read.
+	sim, err := incomingSimulator(ctx)
+	if err != nil {
+		return ctx, in, state, err
+	}
+	ctx = withSimulator(ctx, sim)
+
+	in = proto.Clone(in).(*cities.ListStreamRequest)
+	if in.BatchSize == 0 {
+		in.BatchSize = state.BatchSize
+	}
+	if !in.Enrich {
+		in.Enrich = state.Enrich
+	}
+	if in.EnrichParallelism == 0 {
+		in.EnrichParallelism = state.EnrichParallelism
+	}
+
+	next := resumeState{
+		Last:              state.Last,
+		Metadata:          values,
+		BatchSize:         in.BatchSize,
+		Enrich:            in.Enrich,
+		EnrichParallelism: in.EnrichParallelism,
+	}
+	return ctx, in, next, nil
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"math/rand"
+	"time"
+
+	"go-cancel/deadline"
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errSerialization is what a database returns when a transaction lost a
+// conflict with another one. The transaction was rolled back, running it
+// again is safe.
+var errSerialization = errors.New("could not serialize access due to concurrent update")
+
+var repositoryRetries = expvar.NewMap("repository_retries")
+
+// transient reports whether err is worth another attempt.
+func transient(err error) bool {
+	return errors.Is(err, errSerialization)
+}
+
+// retryingRepository retries transient errors of the repository with
+// exponential backoff and jitter. It never waits past the deadline of the
+// request: when the next wait would not fit, the last error is returned
+// right away, and a cancelled request stops retrying at once.
+type retryingRepository struct {
+	CityRepository
+	attempts int
+	base     time.Duration
+	max      time.Duration
+}
+
+func newRetryingRepository(repo CityRepository, attempts int, base, max time.Duration) *retryingRepository {
+	return &retryingRepository{CityRepository: repo, attempts: attempts, base: base, max: max}
+}
+
+func (r *retryingRepository) BatchInsert(ctx context.Context, names []string) ([]*cities.City, error) {
+	var list []*cities.City
+	err := r.retry(ctx, "BatchInsert", func() (err error) {
+		list, err = r.CityRepository.BatchInsert(ctx, names)
+		return err
+	})
+	return list, err
+}
+
+func (r *retryingRepository) All(ctx context.Context) ([]*cities.City, error) {
+	var list []*cities.City
+	err := r.retry(ctx, "All", func() (err error) {
+		list, err = r.CityRepository.All(ctx)
+		return err
+	})
+	return list, err
+}
+
+func (r *retryingRepository) retry(ctx context.Context, op string, call func() error) error {
+	wait := r.base
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || !transient(err) {
+			return err
+		}
+		if attempt == r.attempts {
+			return status.Errorf(codes.Aborted, "%s failed after %d attempts: %v", op, attempt, err)
+		}
+
+		// Full jitter spreads the retries of conflicting requests.
+		d := time.Duration(rand.Int63n(int64(wait)) + 1)
+		if remaining, ok := deadline.RemainingBudget(ctx); ok && remaining <= d {
+			return status.Errorf(codes.Aborted, "%s: no time left to retry: %v", op, err)
+		}
+
+		repositoryRetries.Add(op, 1)
+		debugf("%s: retrying in %s after %v", op, d, err)
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+		if err := grpcerr.FromContext(ctx); err != nil {
+			return err
+		}
+
+		if wait *= 2; wait > r.max {
+			wait = r.max
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// selftestPropagation is how long a handler may run on after its client
+// went away before the scenario fails.
+const selftestPropagation = 2 * time.Second
+
+type selftestCheck struct {
+	name string
+	run  func(ctx context.Context, st *selftestServer) error
+}
+
+// Selftest boots a server and a client in this process and runs the
+// cancellation scenarios of the tutorial against them, checking both what
+// the client sees and that the handler on the server stopped. It prints a
+// pass/fail report and returns the exit code.
+//
+//	go run . selftest
+func Selftest(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 30*time.Second, "how long each scenario may take")
+	fs.Parse(args)
+
+	fmt.Printf("%s %s/%s, %s build\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, buildFlavor)
+
+	st, err := startSelftestServer()
+	if err != nil {
+		fmt.Printf("cannot start the server: %s\n", err)
+		return 2
+	}
+	defer st.Close()
+
+	checks := []selftestCheck{
+		{"client cancelling a stream stops the handler", selftestCancelStream},
+		{"client deadline stops a unary handler", selftestUnaryDeadline},
+		{"expired deadline fails before the handler runs", selftestExpiredDeadline},
+		{"short deadline on a stream stops the handler", selftestStreamDeadline},
+		{"server still serves after the cancellations", selftestStillServing},
+		// Last, it stops the server.
+		{"shutdown cancels the streams left after the drain", selftestShutdown},
+	}
+
+	failed := 0
+	for _, c := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		err := c.run(ctx, st)
+		cancel()
+
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL  %s: %s\n", c.name, err)
+			continue
+		}
+		fmt.Printf("PASS  %s\n", c.name)
+	}
+
+	fmt.Printf("%d/%d checks passed\n", len(checks)-failed, len(checks))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// selftestServer is a CitiesService on a loopback port, with a client
+// connected to it. ended receives the result of every handler once it
+// returned.
+type selftestServer struct {
+	rpc    *RpcServer
+	conn   *grpc.ClientConn
+	client cities.CitiesServiceClient
+	ended  chan error
+}
+
+func startSelftestServer() (*selftestServer, error) {
+	ids, err := newIDGenerator("ulid", 0)
+	if err != nil {
+		return nil, err
+	}
+	repo := newMemoryRepository(0, ids)
+
+	st := &selftestServer{ended: make(chan error, 16)}
+	st.rpc = NewServer(
+		WithInterceptors(st.unary, st.stream),
+		WithInterceptors(normalizeDeadlineUnary, normalizeDeadlineStream),
+		WithInterceptors(simulatorUnary, simulatorStream),
+		WithInterceptors(methodTimeout(cities.MethodTimeouts).Unary, methodTimeout(cities.MethodTimeouts).Stream),
+	)
+	cities.RegisterCitiesServiceServer(st.rpc.Grpc, &citiesServer{
+		repo:   repo,
+		broker: newBroker(),
+		info:   newServerInfo(map[string]string{"build": buildFlavor}, []string{"selftest"}),
+	})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	go st.rpc.Grpc.Serve(lis)
+
+	st.conn, err = grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		st.rpc.Grpc.Stop()
+		return nil, err
+	}
+	st.client = cities.NewCitiesServiceClient(st.conn)
+	return st, nil
+}
+
+func (st *selftestServer) Close() {
+	st.conn.Close()
+	st.rpc.Grpc.Stop()
+}
+
+func (st *selftestServer) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	st.ended <- err
+	return resp, err
+}
+
+func (st *selftestServer) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	st.ended <- err
+	return err
+}
+
+// handlerEnded waits for the next handler to return with the code want.
+func (st *selftestServer) handlerEnded(want codes.Code) error {
+	select {
+	case err := <-st.ended:
+		if got := status.Code(err); got != want {
+			return fmt.Errorf("handler returned %s (%v), want %s", got, err, want)
+		}
+		return nil
+	case <-time.After(selftestPropagation):
+		return fmt.Errorf("handler still running %s after the client went away", selftestPropagation)
+	}
+}
+
+func expectSelftestCode(err error, want codes.Code) error {
+	if got := status.Code(err); got != want {
+		return fmt.Errorf("client got %s (%v), want %s", got, err, want)
+	}
+	return nil
+}
+
+func selftestCancelStream(ctx context.Context, st *selftestServer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := st.client.ListStream(ctx, &cities.ListStreamRequest{})
+	if err != nil {
+		return err
+	}
+	if _, err := stream.Recv(); err != nil {
+		return fmt.Errorf("first message: %w", err)
+	}
+
+	cancel()
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if err := expectSelftestCode(err, codes.Canceled); err != nil {
+		return err
+	}
+	return st.handlerEnded(codes.Canceled)
+}
+
+func selftestUnaryDeadline(ctx context.Context, st *selftestServer) error {
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+
+	_, err := st.client.List(ctx, &cities.EmptyMessage{})
+	if err := expectSelftestCode(err, codes.DeadlineExceeded); err != nil {
+		return err
+	}
+	return st.handlerEnded(codes.DeadlineExceeded)
+}
+
+// selftestExpiredDeadline sends a call whose deadline has passed, gRPC fails
+// it on the client without a handler ever running.
+func selftestExpiredDeadline(ctx context.Context, st *selftestServer) error {
+	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err := st.client.List(ctx, &cities.EmptyMessage{})
+	if err := expectSelftestCode(err, codes.DeadlineExceeded); err != nil {
+		return err
+	}
+	select {
+	case err := <-st.ended:
+		return fmt.Errorf("a handler ran and returned %s", status.Code(err))
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+func selftestStreamDeadline(ctx context.Context, st *selftestServer) error {
+	ctx, cancel := context.WithTimeout(ctx, 1500*time.Millisecond)
+	defer cancel()
+
+	stream, err := st.client.ListStream(ctx, &cities.ListStreamRequest{})
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if err := expectSelftestCode(err, codes.DeadlineExceeded); err != nil {
+		return err
+	}
+	return st.handlerEnded(codes.DeadlineExceeded)
+}
+
+func selftestStillServing(ctx context.Context, st *selftestServer) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	info, err := st.client.GetServerInfo(ctx, &cities.EmptyMessage{})
+	if err == nil && info == nil {
+		err = errors.New("empty response")
+	}
+	if err != nil {
+		return err
+	}
+	return st.handlerEnded(codes.OK)
+}
+
+// selftestShutdown shuts the server down under an open stream, which must
+// end with errServerShutdown once the drain is over.
+func selftestShutdown(ctx context.Context, st *selftestServer) error {
+	stream, err := st.client.ListStream(ctx, &cities.ListStreamRequest{})
+	if err != nil {
+		return err
+	}
+	if _, err := stream.Recv(); err != nil {
+		return fmt.Errorf("first message: %w", err)
+	}
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- st.rpc.Shutdown(200 * time.Millisecond) }()
+
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if err := expectSelftestCode(err, status.Code(errServerShutdown)); err != nil {
+		return err
+	}
+	var interrupted *shutdownError
+	if err := <-shutdown; !errors.As(err, &interrupted) {
+		return fmt.Errorf("shutdown returned %v, want the interrupted calls", err)
+	}
+	return st.handlerEnded(status.Code(errServerShutdown))
+}
@@ -0,0 +1,519 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go-cancel/citiesrpc"
+	"go-cancel/config"
+	"go-cancel/deadline"
+	"go-cancel/grpcerr"
+	"go-cancel/httpmw"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+type RpcServer struct {
+	Grpc *grpc.Server
+	// Health is nil unless the server was built WithHealth.
+	Health *health.Server
+
+	// unary is the interceptor chain of the unary calls, the other
+	// transports run their calls through it too.
+	unary []grpc.UnaryServerInterceptor
+	// root is the first interceptor, Shutdown cancels the calls through it.
+	root *handlerRoot
+}
+
+// CallUnary runs handler behind the unary interceptors, as if the gRPC
+// server had received a call of fullMethod.
+func (s *RpcServer) CallUnary(ctx context.Context, fullMethod string, req interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	info := &grpc.UnaryServerInfo{FullMethod: fullMethod}
+	for i := len(s.unary) - 1; i >= 0; i-- {
+		interceptor, next := s.unary[i], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+	return handler(ctx, req)
+}
+
+// Shutdown stops the server, letting the calls in flight finish for at most
+// drain. The calls still running then are cancelled with errServerShutdown
+// and the server is stopped, a *shutdownError tells which they were.
+func (s *RpcServer) Shutdown(drain time.Duration) error {
+	if s.Health != nil {
+		s.Health.Shutdown()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Grpc.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(drain):
+	}
+
+	interrupted := s.root.Cancel(errServerShutdown)
+	log.Printf("grpc calls still running after the drain timeout of %s, cancelled %d", drain, len(interrupted))
+	// Handlers honouring their context return the cause as their status,
+	// the connections are only closed under the others.
+	select {
+	case <-done:
+	case <-time.After(handlerGrace):
+		s.Grpc.Stop()
+		<-done
+	}
+	return &shutdownError{drain: drain, interrupted: interrupted}
+}
+
+func NewServer(opts ...Option) *RpcServer {
+	var o serverOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.root == nil {
+		o.root = context.Background()
+	}
+	root := newHandlerRoot(o.root)
+	unary := append([]grpc.UnaryServerInterceptor{requestIDUnary, root.Unary}, o.unary...)
+	stream := append([]grpc.StreamServerInterceptor{requestIDStream, root.Stream}, o.stream...)
+	grpcOpts := append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}, o.grpc...)
+
+	rpcServer := &RpcServer{
+		Grpc:  grpc.NewServer(grpcOpts...),
+		unary: unary,
+		root:  root,
+	}
+
+	if o.health {
+		rpcServer.Health = health.NewServer()
+		// Until the listeners are up, see readiness.
+		rpcServer.Health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		healthpb.RegisterHealthServer(rpcServer.Grpc, rpcServer.Health)
+	}
+	if o.reflection {
+		reflection.Register(rpcServer.Grpc)
+	}
+
+	return rpcServer
+}
+
+// runRpcServer serves gRPC on the listeners of listen until ctx ends, then
+// stops gracefully, returning once the calls in flight are done or drain has
+// passed. It tells ready once it listens.
+func runRpcServer(ctx context.Context, listen func() ([]net.Listener, error), rpcServer *RpcServer, drain time.Duration, ready *readiness) error {
+	// Started first, the server stops with ctx even when the listeners
+	// fail, the tunnel serves on it too.
+	stopped := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		stopped <- rpcServer.Shutdown(drain)
+	}()
+
+	listeners, err := listen()
+	if err != nil {
+		return err
+	}
+	ready.Ready("grpc", listeners)
+
+	err = serveAll("grpc", listeners, rpcServer.Grpc.Serve)
+	if ctx.Err() != nil {
+		if stopErr := <-stopped; stopErr != nil {
+			return stopErr
+		}
+	}
+	return err
+}
+
+// runRestServer serves the REST API, the routes of gateway included, on the
+// listeners of listen until ctx ends, then shuts down gracefully, closing
+// the connections still busy after the shutdown timeout of cfg. The request
+// contexts derive from handlers. It serves HTTPS with tc, and then redirects
+// the plaintext requests of the REST_REDIRECT_LISTEN addresses to it. It
+// tells ready once it listens.
+func runRestServer(ctx, handlers context.Context, cfg config.Server, listen func() ([]net.Listener, error), tc *tls.Config, rpcServer *RpcServer, tunnel *wsListener, gateway *restGateway, guard *restGuard, memRepo *memoryRepository, admin *adminServer, shed *gatewayShedder, netRPC http.Handler, audit *auditLog, ready *readiness) error {
+	mux := http.NewServeMux()
+	if tunnel != nil {
+		mux.Handle(tunnel.Addr().String(), tunnel)
+	}
+	// The probes stay outside the maintenance and the shedding, a server
+	// under load is still alive.
+	mux.HandleFunc("/healthz", healthz)
+	mux.HandleFunc("/readyz", ready.readyz)
+	mux.HandleFunc("/version", versionHandler)
+	// The admin routes take the admin token like the AdminService, and are
+	// not served without one.
+	if cfg.AdminToken != "" {
+		token := adminAuth(cfg.AdminToken)
+		mux.Handle("/admin/log", token.HTTP(http.HandlerFunc(adminLog)))
+		mux.Handle("/admin/repository", token.HTTP(adminRepository(memRepo)))
+		mux.Handle("/admin/maintenance", token.HTTP(http.HandlerFunc(admin.adminMaintenance)))
+		mux.Handle("/admin/calls", token.HTTP(http.HandlerFunc(admin.adminCalls)))
+		mux.Handle("/admin/latency", token.HTTP(http.HandlerFunc(admin.adminLatency)))
+		mux.Handle("/admin/static-snapshot", token.HTTP(http.HandlerFunc(admin.adminStaticSnapshot)))
+		mux.Handle("/admin/reload", token.HTTP(http.HandlerFunc(admin.adminReload)))
+		if audit != nil {
+			mux.Handle("/admin/audit", token.HTTP(http.HandlerFunc(audit.adminAudit)))
+		}
+	}
+	mux.Handle(citiesrpc.Path, netRPC)
+	flavorRoutes(mux)
+	// The data routes the gateway does not serve are guarded as the calls
+	// they mirror.
+	mux.Handle("/cities/stored", accessLog(guard.HTTP("/cities.CitiesService/List", admin.maintenance.HTTP(shed.Handler(storedCities(memRepo), true)))))
+	mux.Handle("/cities/ndjson", accessLog(guard.HTTP("/cities.CitiesService/ListStream", admin.maintenance.HTTP(shed.Handler(simulatorHTTP(ndjson(cfg.RESTWriteTimeout)), false)))))
+	mux.Handle("/cities/stream", accessLog(guard.HTTP("/cities.CitiesService/ListStream", admin.maintenance.HTTP(shed.Handler(simulatorHTTP(sse(cfg.RESTWriteTimeout)), false)))))
+	if ws := wsCities(gateway, cfg.RESTWriteTimeout); ws != nil {
+		mux.Handle("/ws/cities", accessLog(admin.maintenance.HTTP(shed.Handler(ws, false))))
+	}
+	mux.Handle("/v1/cities/stream", accessLog(admin.maintenance.HTTP(shed.Handler(watchGateway("ListStream", gateway), false))))
+	mux.Handle("/v1/", accessLog(admin.maintenance.HTTP(shed.Handler(gateway, true))))
+	mux.HandleFunc("/", legacyList)
+
+	listeners, err := listen()
+	if err != nil {
+		return err
+	}
+	if tc != nil {
+		_, port, _ := net.SplitHostPort(listeners[0].Addr().String())
+		if cfg.RESTRedirectListen != "" {
+			redirect, err := listenAll("rest-redirect", listenAddrs(cfg.RESTRedirectListen, ""))
+			if err != nil {
+				for _, l := range listeners {
+					l.Close()
+				}
+				return err
+			}
+			go serveRedirect(ctx, redirect, port)
+		}
+		for i, l := range listeners {
+			listeners[i] = tls.NewListener(l, tc)
+		}
+	}
+	ready.Ready("rest", listeners)
+
+	// A slow or idle client holds a connection for a bounded time. The
+	// WebSocket tunnel is unaffected, hijacking clears the deadlines.
+	srv := &http.Server{
+		Handler:           httpmw.RequestID(mux),
+		ReadHeaderTimeout: cfg.RESTReadHeaderTimeout,
+		ReadTimeout:       cfg.RESTReadTimeout,
+		WriteTimeout:      cfg.RESTResponseTimeout,
+		IdleTimeout:       cfg.RESTIdleTimeout,
+		MaxHeaderBytes:    int(cfg.RESTMaxHeaderBytes),
+		BaseContext:       func(net.Listener) context.Context { return handlers },
+	}
+	drain := cfg.ShutdownTimeout
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		drainCtx, cancel := context.WithTimeout(context.Background(), drain)
+		defer cancel()
+		err := srv.Shutdown(drainCtx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("rest requests still running after the drain timeout of %s, closing their connections", drain)
+			err = srv.Close()
+		}
+		shutdown <- err
+	}()
+
+	// Serve returns as soon as Shutdown starts, the requests in flight are
+	// only done when it returns.
+	err = serveAll("rest", listeners, srv.Serve)
+	if errors.Is(err, http.ErrServerClosed) {
+		return <-shutdown
+	}
+	return err
+}
+
+// legacyList sends the clients of the hand-written List route, which
+// answered on any path, to its gateway route. Only / and /cities are
+// redirected, the other paths are unknown.
+func legacyList(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "/cities" {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, "/v1/cities", http.StatusPermanentRedirect)
+}
+
+type citiesServer struct {
+	repo    CityRepository
+	creator *createBatcher
+	stats   *statsScheduler
+	broker  *broker
+	info    *serverInfo
+	cache   *listCache
+}
+
+func (u *citiesServer) ListStream(in *cities.ListStreamRequest, stream cities.CitiesService_ListStreamServer) error {
+	ctx := stream.Context()
+	// Deferred first, it runs once the prefetcher stopped too.
+	defer gatewayStopped(ctx)()
+	select {
+	case <-ctx.Done():
+		return grpcerr.FromContext(ctx)
+	default:
+	}
+
+	ctx, in, state, err := resumeStream(ctx, in)
+	if err != nil {
+		return err
+	}
+	last := state.Last
+	if session := state.Metadata["session-id"]; session != "" && last > 0 {
+		debugf("request-id %s session %s resumes ListStream after city %d", requestID(ctx), session, last)
+	}
+
+	batchSize := int(in.GetBatchSize())
+	var batch []*cities.City
+
+	// Each city is produced when its turn comes, the enrichment only looks
+	// a few cities ahead.
+	sim := simulatorFrom(ctx)
+	cityAt := simulatedCity
+	if static, ok := staticSnapshot(); ok {
+		// The enrichment looks up the details with the simulator of ctx.
+		sim = instant{count: len(static)}
+		ctx = withSimulator(ctx, sim)
+		cityAt = func(_ context.Context, i int) (*cities.City, error) {
+			return static[i-1], nil
+		}
+	}
+	count := sim.Count()
+
+	var details *prefetcher
+	if in.GetEnrich() {
+		parallel := int(in.GetEnrichParallelism())
+		if parallel == 0 {
+			parallel = 4
+		}
+		if parallel > 16 {
+			return status.Error(codes.InvalidArgument, "enrich_parallelism must not be above 16")
+		}
+
+		details = newPrefetcher(ctx, last+1, count, cityAt, parallel, lookupCityDetail)
+		defer details.Close()
+	}
+
+	// sent and lastSent only count cities that went out, so the trailer
+	// tells exactly how far a stream got when the server ends it early. A
+	// client that cancels the call itself does not get trailers.
+	sent, lastSent := 0, last
+	partial := func(err error) error {
+		stream.SetTrailer(metadata.Pairs("items-sent", strconv.Itoa(sent), "resume-token", state.token(lastSent)))
+		return err
+	}
+
+	// Past the soft deadline a batching stream flushes what it holds and
+	// sends the rest one by one, the client keeps what arrived before the
+	// hard deadline ends the call.
+	var progress atomic.Int64
+	progress.Store(int64(last))
+	soft := softDeadline(ctx, "ListStream", func() string {
+		return fmt.Sprintf("city %d of %d", progress.Load(), count)
+	})
+	defer soft.Stop()
+
+	var extension *extensionPlanner
+	if in.GetAllowExtension() {
+		extension = newExtensionPlanner(count)
+	}
+
+	for i := last + 1; i <= count; i++ {
+		streamDebug(ctx, i)
+
+		if ext := extension.check(ctx, i-last-1, lastSent); ext != nil {
+			ext.ResumeToken = state.token(lastSent)
+			if err := stream.Send(&cities.CityStream{Extension: ext, ResumeToken: ext.ResumeToken}); err != nil {
+				if err := grpcerr.FromContext(ctx); err != nil {
+					return partial(err)
+				}
+				return partial(status.Errorf(codes.Unknown, "cannot send stream response: %v", err))
+			}
+		}
+		if err := sim.Step(ctx, stepStream); err != nil {
+			return partial(err)
+		}
+		progress.Store(int64(i))
+
+		var city *cities.City
+		if details != nil {
+			city, err = details.Next(ctx)
+		} else {
+			city, err = cityAt(ctx, i)
+		}
+		if err != nil {
+			return partial(err)
+		}
+		res := &cities.CityStream{City: city, ResumeToken: state.token(i)}
+
+		if batchSize > 1 {
+			batch = append(batch, city)
+			if len(batch) < batchSize && i < count && !soft.Passed() {
+				continue
+			}
+			res = &cities.CityStream{Cities: batch, ResumeToken: state.token(i)}
+		}
+
+		if err := stream.Send(res); err != nil {
+			if err := grpcerr.FromContext(ctx); err != nil {
+				return partial(err)
+			}
+			return partial(status.Errorf(codes.Unknown, "cannot send stream response: %v", err))
+		}
+
+		if batchSize > 1 {
+			sent += len(batch)
+			batch = nil
+		} else {
+			sent++
+		}
+		lastSent = i
+	}
+
+	streamDebug(ctx, "tes")
+
+	return nil
+}
+
+func (u *citiesServer) List(ctx context.Context, in *cities.EmptyMessage) (*cities.Cities, error) {
+	/*select {
+	case <-ctx.Done():
+		return nil, grpcerr.FromContext(ctx)
+	default:
+	} */
+
+	if list, ok := staticSnapshot(); ok {
+		return &cities.Cities{City: list}, grpcerr.FromContext(ctx)
+	}
+
+	// The cache was primed by the default simulator and names, a call that
+	// picked another preset or locale generates its own cities.
+	if simulatorFrom(ctx) == defaultSimulator && namesFrom(ctx) == defaultNames {
+		if list, ok := u.cache.Get(); ok {
+			return &cities.Cities{City: list}, nil
+		}
+	}
+
+	soft := softDeadline(ctx, "List", nil)
+	defer soft.Stop()
+	list, err := generateCities(ctx, soft)
+	if err != nil {
+		return nil, err
+	}
+	if soft.Passed() {
+		// The REST gateway calls List without a gRPC stream, there is
+		// no trailer to set then.
+		grpc.SetTrailer(ctx, metadata.Pairs("degraded", "soft-deadline"))
+	}
+
+	for i := 1; i < 10; i++ {
+		streamDebug(ctx, i)
+	}
+
+	return &cities.Cities{City: list}, nil
+}
+
+// generateCities builds the list List returns, with the default simulator
+// it takes about five seconds. Once soft, if not nil, passes, the cities
+// left skip the simulated work, the cheaper path a real backend would
+// take, so the list makes it before the hard deadline.
+func generateCities(ctx context.Context, soft *deadline.Soft) ([]*cities.City, error) {
+	sim := simulatorFrom(ctx)
+
+	var list []*cities.City
+	for i := 1; i <= sim.Count(); i++ {
+		err := grpcerr.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		city, err := simulatedCity(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, city)
+		if soft != nil && soft.Passed() {
+			continue
+		}
+		if err := sim.Step(ctx, stepList); err != nil {
+			return nil, err
+		}
+		streamDebug(ctx, i)
+	}
+
+	return list, grpcerr.FromContext(ctx)
+}
+
+func (u *citiesServer) Create(ctx context.Context, in *cities.CreateCityRequest) (*cities.City, error) {
+	if strings.TrimSpace(in.GetName()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	if in.GetValidateOnly() {
+		// A dry run that ran out of time must fail like the real call would.
+		if err := grpcerr.FromContext(ctx); err != nil {
+			return nil, err
+		}
+		return &cities.City{Name: in.GetName()}, nil
+	}
+
+	return u.creator.Create(ctx, in.GetName())
+}
+
+func (u *citiesServer) Stats(ctx context.Context, in *cities.StatsRequest) (*cities.CityStats, error) {
+	if in.GetFresh() {
+		return u.stats.Fresh(ctx)
+	}
+
+	return u.stats.Last()
+}
+
+// sleep pauses for d, returning early with the context error when ctx ends.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return grpcerr.FromContext(ctx)
+	case <-t.C:
+		return nil
+	}
+}
+
+var letters = []rune("abcdefghijklmnopqrstuvwxyz")
+
+func randSeq(n int) string {
+	b := make([]rune, n)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return strings.Title(string(b))
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// TestInterceptorOrder builds a server WithInterceptors three times: the
+// unary and the stream interceptors run in the order they were added.
+func TestInterceptorOrder(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+	record := func(name string) Option {
+		return WithInterceptors(
+			func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				mu.Lock()
+				ran = append(ran, "unary "+name)
+				mu.Unlock()
+				return handler(ctx, req)
+			},
+			func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				mu.Lock()
+				ran = append(ran, "stream "+name)
+				mu.Unlock()
+				return handler(srv, ss)
+			},
+		)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	s := NewServer(WithRootContext(ctx), record("first"), record("second"), record("third"))
+	cities.RegisterCitiesServiceServer(s.Grpc, &cities.UnimplementedCitiesServiceServer{})
+
+	lis := bufconn.Listen(restGatewayBuffer)
+	go s.Grpc.Serve(lis)
+	defer s.Grpc.Stop()
+
+	conn, err := grpc.DialContext(ctx, "bufconn", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	client := cities.NewCitiesServiceClient(conn)
+
+	tests := []struct {
+		name string
+		call func() error
+		want []string
+	}{
+		{
+			name: "unary",
+			call: func() error {
+				_, err := client.List(ctx, &cities.EmptyMessage{})
+				return err
+			},
+			want: []string{"unary first", "unary second", "unary third"},
+		},
+		{
+			name: "stream",
+			call: func() error {
+				stream, err := client.ListStream(ctx, &cities.ListStreamRequest{})
+				if err != nil {
+					return err
+				}
+				_, err = stream.Recv()
+				return err
+			},
+			want: []string{"stream first", "stream second", "stream third"},
+		},
+		{
+			name: "CallUnary",
+			call: func() error {
+				_, err := s.CallUnary(ctx, "/cities.CitiesService/List", &cities.EmptyMessage{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return &cities.Cities{}, nil
+				})
+				return err
+			},
+			want: []string{"unary first", "unary second", "unary third"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			ran = nil
+			mu.Unlock()
+
+			// The unimplemented service fails the calls, only the
+			// interceptors in front of it matter.
+			tt.call()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(ran, tt.want) {
+				t.Fatalf("interceptors ran %v, want %v", ran, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"go-cancel/pb/cities"
+)
+
+// version and commit are set at build time:
+//
+//	go build -ldflags "-X go-cancel/app.version=1.2.0 -X go-cancel/app.commit=$(git rev-parse HEAD)"
+//
+// Without them commit falls back to the revision Go stamped into the binary.
+var (
+	version = "dev"
+	commit  = ""
+)
+
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if revision == "" {
+		return "unknown"
+	}
+	if modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// serverInfo is what GetServerInfo reports. config holds the settings run
+// ended up with, after defaults and environment variables.
+type serverInfo struct {
+	started  time.Time
+	commit   string
+	config   map[string]string
+	features []string
+	digest   string
+}
+
+func newServerInfo(config map[string]string, features []string) *serverInfo {
+	return &serverInfo{
+		started:  time.Now(),
+		commit:   buildCommit(),
+		config:   config,
+		features: features,
+		digest:   configDigest(config),
+	}
+}
+
+// configDigest hashes the settings in key order, equal settings give equal
+// digests whatever order they were collected in.
+func configDigest(config map[string]string) string {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(config[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (u *citiesServer) GetServerInfo(ctx context.Context, in *cities.EmptyMessage) (*cities.ServerInfo, error) {
+	config := make(map[string]string, len(u.info.config))
+	for k, v := range u.info.config {
+		config[k] = v
+	}
+
+	return &cities.ServerInfo{
+		Version:      version,
+		Commit:       u.info.commit,
+		StartedAt:    u.info.started.UnixNano() / int64(time.Millisecond),
+		UptimeMs:     time.Since(u.info.started).Milliseconds(),
+		Config:       config,
+		ConfigDigest: u.info.digest,
+		Features:     append([]string(nil), u.info.features...),
+	}, nil
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errServerShutdown is the cause of the calls cut off when the drain timeout
+// passes, clients see Unavailable and may retry on another server.
+var errServerShutdown = status.Error(codes.Unavailable, "server shutting down")
+
+// handlerGrace is how long the cancelled handlers get to return their status
+// before the connections are closed under them.
+const handlerGrace = time.Second
+
+// handlerRoot is the root of every handler context of an RpcServer.
+// Cancelling it, or the context it derives from, interrupts all the calls
+// still running and the ones arriving after.
+type handlerRoot struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu    sync.Mutex
+	next  int
+	calls map[int]*runningCall
+}
+
+type runningCall struct {
+	method    string
+	requestID string
+	start     time.Time
+	cancel    context.CancelCauseFunc
+}
+
+func newHandlerRoot(parent context.Context) *handlerRoot {
+	ctx, cancel := context.WithCancelCause(parent)
+	h := &handlerRoot{ctx: ctx, cancel: cancel, calls: make(map[int]*runningCall)}
+	go func() {
+		<-ctx.Done()
+		h.cancelCalls(context.Cause(ctx))
+	}()
+	return h
+}
+
+// track registers the call, done must be called when it ends.
+func (h *handlerRoot) track(ctx context.Context, method string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	h.mu.Lock()
+	if h.ctx.Err() != nil {
+		h.mu.Unlock()
+		cancel(context.Cause(h.ctx))
+		return ctx, func() {}
+	}
+	h.next++
+	n := h.next
+	h.calls[n] = &runningCall{method: method, requestID: requestID(ctx), start: time.Now(), cancel: cancel}
+	h.mu.Unlock()
+
+	return ctx, func() {
+		h.mu.Lock()
+		delete(h.calls, n)
+		h.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Cancel cancels the root with cause and describes the calls it
+// interrupted, the longest running first.
+func (h *handlerRoot) Cancel(cause error) []string {
+	interrupted := h.cancelCalls(cause)
+	h.cancel(cause)
+	return interrupted
+}
+
+func (h *handlerRoot) cancelCalls(cause error) []string {
+	h.mu.Lock()
+	calls := make([]*runningCall, 0, len(h.calls))
+	for _, c := range h.calls {
+		calls = append(calls, c)
+	}
+	h.mu.Unlock()
+
+	sort.Slice(calls, func(i, j int) bool { return calls[i].start.Before(calls[j].start) })
+	interrupted := make([]string, len(calls))
+	for i, c := range calls {
+		c.cancel(cause)
+		interrupted[i] = fmt.Sprintf("%s (request-id %s, running %s)", c.method, c.requestID, time.Since(c.start).Round(time.Millisecond))
+	}
+	return interrupted
+}
+
+func (h *handlerRoot) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, done := h.track(ctx, info.FullMethod)
+	defer done()
+	return handler(ctx, req)
+}
+
+func (h *handlerRoot) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, done := h.track(ss.Context(), info.FullMethod)
+	defer done()
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// shutdownError is returned when the drain timeout passed before the calls
+// in flight were done.
+type shutdownError struct {
+	drain       time.Duration
+	interrupted []string
+}
+
+func (e *shutdownError) Error() string {
+	if len(e.interrupted) == 0 {
+		return fmt.Sprintf("forced stop after the drain timeout of %s", e.drain)
+	}
+	return fmt.Sprintf("forced stop after the drain timeout of %s, interrupted %d handlers: %s",
+		e.drain, len(e.interrupted), strings.Join(e.interrupted, ", "))
+}
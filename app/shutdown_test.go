@@ -0,0 +1,158 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go-cancel/config"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestStopDuringListStream opens ListStream, stops the App after a few
+// messages and checks the stream ends cleanly or with Unavailable within
+// the drain window, and that nothing of the App keeps running.
+func TestStopDuringListStream(t *testing.T) {
+	const received = 3
+
+	tests := []struct {
+		name   string
+		cities int
+		drain  time.Duration
+		// stop bounds Stop, zero waits for the server.
+		stop time.Duration
+		// wantCode is how the stream ends, OK for io.EOF.
+		wantCode codes.Code
+		wantStop func(error) bool
+	}{
+		{
+			name:     "stream drained",
+			cities:   6,
+			drain:    5 * time.Second,
+			wantCode: codes.OK,
+			wantStop: func(err error) bool { return err == nil },
+		},
+		{
+			name:     "stream cancelled after the drain timeout",
+			cities:   1000,
+			drain:    200 * time.Millisecond,
+			wantCode: codes.Unavailable,
+			wantStop: func(err error) bool {
+				var shutdown *shutdownError
+				return errors.As(err, &shutdown)
+			},
+		},
+		{
+			name:     "Stop out of time",
+			cities:   1000,
+			drain:    5 * time.Second,
+			stop:     200 * time.Millisecond,
+			wantCode: codes.Unavailable,
+			wantStop: func(err error) bool { return err == context.DeadlineExceeded },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultServer()
+			cfg.GRPCListen, cfg.RESTListen = "127.0.0.1:0", "127.0.0.1:0"
+			cfg.ListSize, cfg.StreamInterval = tt.cities, 20*time.Millisecond
+			cfg.ShutdownTimeout = tt.drain
+			a := New(cfg, nil)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := a.Start(ctx); err != nil {
+				t.Fatalf("start: %v", err)
+			}
+
+			conn, err := grpc.Dial(a.GRPCAddr(), grpc.WithInsecure())
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			stream, err := cities.NewCitiesServiceClient(conn).ListStream(ctx, &cities.ListStreamRequest{})
+			if err != nil {
+				t.Fatalf("ListStream: %v", err)
+			}
+			for i := 0; i < received; i++ {
+				if _, err := stream.Recv(); err != nil {
+					t.Fatalf("message %d: %v", i+1, err)
+				}
+			}
+
+			stopCtx := context.Background()
+			if tt.stop > 0 {
+				var cancelStop context.CancelFunc
+				stopCtx, cancelStop = context.WithTimeout(stopCtx, tt.stop)
+				defer cancelStop()
+			}
+			stopped := time.Now()
+			stopErr := make(chan error, 1)
+			go func() { stopErr <- a.Stop(stopCtx) }()
+
+			n := received
+			for {
+				if _, err = stream.Recv(); err != nil {
+					break
+				}
+				n++
+			}
+			took := time.Since(stopped)
+			if err == io.EOF {
+				err = nil
+			}
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("stream ended with %v, want %s", err, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && n != tt.cities {
+				t.Errorf("stream drained %d cities, want %d", n, tt.cities)
+			}
+			if window := tt.drain + handlerGrace; took > window {
+				t.Errorf("stream took %s to end after Stop, above the drain window of %s", took, window)
+			}
+
+			if err := <-stopErr; !tt.wantStop(err) {
+				t.Errorf("Stop: unexpected %v", err)
+			}
+			conn.Close()
+			select {
+			case <-a.Done():
+			case <-time.After(tt.drain + 2*handlerGrace):
+				t.Fatal("App still running after Stop")
+			}
+
+			if left := leftGoroutines(2 * time.Second); len(left) > 0 {
+				t.Errorf("%d goroutines left behind:\n%s", len(left), strings.Join(left, "\n\n"))
+			}
+		})
+	}
+}
+
+// leftGoroutines waits up to timeout for the goroutines started since the
+// test began to end and returns the stacks of the ones left. The signal
+// loop os/signal keeps for the life of the process, once watchLogReload
+// asked for SIGHUP, is not counted.
+func leftGoroutines(timeout time.Duration) []string {
+	end := time.Now().Add(timeout)
+	for {
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		var left []string
+		for _, g := range strings.Split(string(buf), "\n\n") {
+			if strings.Contains(g, "testing.tRunner") || strings.Contains(g, "testing.(*M)") || strings.Contains(g, "os/signal.loop") {
+				continue
+			}
+			left = append(left, g)
+		}
+		if len(left) == 0 || time.Now().After(end) {
+			return left
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
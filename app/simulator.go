@@ -0,0 +1,240 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Steps of the demo that pretend to do work.
+const (
+	stepList   = "list"   // List generating one city
+	stepStream = "stream" // ListStream and /cities/ndjson producing one city
+	stepLookup = "lookup" // the detail lookup of an enriched stream
+	stepEnrich = "enrich" // the enrichment stage of TransformCities
+	stepStats  = "stats"  // the statistics looking at one city
+
+	stepSnapshot = "snapshot" // a WatchCities snapshot of every city
+
+	stepHandshake = "handshake" // the key agreement of an encrypted call
+)
+
+// Simulator decides the artificial work of the demo: how many cities the
+// list calls produce and how long, and how reliably, every step runs.
+type Simulator interface {
+	Name() string
+	// Count is the number of cities List and ListStream produce.
+	Count() int
+	// Step pretends to do the work of step. It returns early with the
+	// context error when ctx ends.
+	Step(ctx context.Context, step string) error
+}
+
+// preset is a Simulator with fixed settings. A step without a delay takes
+// no time.
+type preset struct {
+	name     string
+	count    int
+	delays   map[string]func() time.Duration
+	failRate float64
+}
+
+func (p *preset) Name() string {
+	return p.name
+}
+
+func (p *preset) Count() int {
+	return p.count
+}
+
+func (p *preset) Step(ctx context.Context, step string) error {
+	d := time.Duration(extraLatency.Load())
+	if delay, ok := p.delays[step]; ok {
+		d += delay()
+	}
+	if d > 0 {
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	if p.failRate > 0 && rand.Float64() < p.failRate {
+		return status.Errorf(codes.Unavailable, "simulated %s failure", step)
+	}
+	return nil
+}
+
+func fixed(d time.Duration) func() time.Duration {
+	return func() time.Duration { return d }
+}
+
+// between returns a random delay in [min, max).
+func between(min, max time.Duration) func() time.Duration {
+	return func() time.Duration { return min + time.Duration(rand.Int63n(int64(max-min))) }
+}
+
+// extraLatency is added to every step of every preset, the admin API sets
+// it.
+var extraLatency atomic.Int64
+
+// defaultSimulator is what the demo always did, it is used when a call does
+// not pick a preset.
+var defaultSimulator = &preset{
+	name:  "default",
+	count: 49,
+	delays: map[string]func() time.Duration{
+		stepList:   fixed(100 * time.Millisecond),
+		stepStream: fixed(time.Second),
+		stepLookup: between(0, 300*time.Millisecond),
+		stepEnrich: between(0, 300*time.Millisecond),
+		stepStats:  fixed(10 * time.Millisecond),
+
+		stepHandshake: between(0, 50*time.Millisecond),
+	},
+}
+
+// configureDefaultSimulator sets how many cities the default simulator
+// lists and how long it takes to stream each, the flaky preset shares its
+// delays.
+func configureDefaultSimulator(count int, streamInterval time.Duration) {
+	defaultSimulator.count = count
+	defaultSimulator.delays[stepStream] = fixed(streamInterval)
+}
+
+var simulators = map[string]Simulator{
+	"default": defaultSimulator,
+	"fast": &preset{
+		name:  "fast",
+		count: 49,
+		delays: map[string]func() time.Duration{
+			stepStream: fixed(10 * time.Millisecond),
+		},
+	},
+	// slow-backend makes every call miss the usual client deadlines, and the
+	// enrichment stage, the key agreement and the watch snapshots miss their
+	// timeouts.
+	"slow-backend": &preset{
+		name:  "slow-backend",
+		count: 49,
+		delays: map[string]func() time.Duration{
+			stepList:   fixed(500 * time.Millisecond),
+			stepStream: fixed(3 * time.Second),
+			stepLookup: between(500*time.Millisecond, 1500*time.Millisecond),
+			stepEnrich: between(250*time.Millisecond, 600*time.Millisecond),
+			stepStats:  fixed(100 * time.Millisecond),
+
+			stepSnapshot:  fixed(3 * time.Second),
+			stepHandshake: fixed(time.Second),
+		},
+	},
+	"flaky": &preset{
+		name:     "flaky",
+		count:    49,
+		delays:   defaultSimulator.delays,
+		failRate: 0.1,
+	},
+	"huge-dataset": &preset{
+		name:  "huge-dataset",
+		count: 10000,
+		delays: map[string]func() time.Duration{
+			stepList:   fixed(time.Millisecond),
+			stepStream: fixed(20 * time.Millisecond),
+			stepLookup: between(0, 30*time.Millisecond),
+			stepEnrich: between(0, 30*time.Millisecond),
+		},
+	},
+}
+
+// lookupSimulator returns the preset called name, the default one when name
+// is empty.
+func lookupSimulator(name string) (Simulator, error) {
+	if name == "" {
+		return defaultSimulator, nil
+	}
+	if s, ok := simulators[name]; ok {
+		return s, nil
+	}
+
+	names := make([]string, 0, len(simulators))
+	for n := range simulators {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, status.Errorf(codes.InvalidArgument, "unknown simulator %q, use one of %s", name, strings.Join(names, ", "))
+}
+
+type simulatorKey struct{}
+
+func withSimulator(ctx context.Context, s Simulator) context.Context {
+	return context.WithValue(ctx, simulatorKey{}, s)
+}
+
+// simulatorFrom returns the simulator of the call, work started outside of
+// a call uses the default one.
+func simulatorFrom(ctx context.Context) Simulator {
+	if s, ok := ctx.Value(simulatorKey{}).(Simulator); ok {
+		return s
+	}
+	return defaultSimulator
+}
+
+// incomingSimulator reads the preset named by the simulator metadata.
+func incomingSimulator(ctx context.Context) (Simulator, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var name string
+	if v := md.Get("simulator"); len(v) > 0 {
+		name = v[0]
+	}
+	return lookupSimulator(name)
+}
+
+func simulatorUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	s, err := incomingSimulator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(withSimulator(ctx, s), req)
+}
+
+func simulatorStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	s, err := incomingSimulator(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: withSimulator(ss.Context(), s)})
+}
+
+// simulatorHTTP does the same for the REST server, the preset is named by
+// the Simulator header.
+func simulatorHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := lookupSimulator(r.Header.Get("Simulator"))
+		if err != nil {
+			http.Error(w, status.Convert(err).Message(), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withSimulator(r.Context(), s)))
+	})
+}
+
+// simulatedCity is the i-th city of the lists the demo generates, named for
+// the locale of ctx.
+func simulatedCity(ctx context.Context, i int) (*cities.City, error) {
+	name, err := namesFrom(ctx).Name(ctx, i)
+	if err != nil {
+		return nil, err
+	}
+	return &cities.City{Id: strconv.Itoa(i), Name: name}, nil
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// http2Preface starts every HTTP/2 connection without TLS, so every gRPC
+// connection, and no HTTP/1.1 one.
+const http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// sharedPort serves gRPC and REST on the same listeners, for firewalls that
+// open one port. It tells the connections apart by their first bytes: the
+// HTTP/2 preface goes to gRPC, anything else to REST. The REST server thus
+// only speaks HTTP/1.1 there, and neither side can use TLS.
+type sharedPort struct {
+	addrs []string
+	sniff time.Duration
+
+	once sync.Once
+	err  error
+	grpc []net.Listener
+	rest []net.Listener
+}
+
+// newSharedPort listens on addrs once either server asks for its listeners.
+// A connection not telling what it is within sniff is closed.
+func newSharedPort(addrs []string, sniff time.Duration) *sharedPort {
+	return &sharedPort{addrs: addrs, sniff: sniff}
+}
+
+func (p *sharedPort) listen() {
+	p.once.Do(func() {
+		var listeners []net.Listener
+		listeners, p.err = listenAll("shared", p.addrs)
+		for _, l := range listeners {
+			grpc, rest := p.split(l)
+			p.grpc = append(p.grpc, grpc)
+			p.rest = append(p.rest, rest)
+		}
+	})
+}
+
+// GRPC and REST return the listeners of each server.
+func (p *sharedPort) GRPC() ([]net.Listener, error) {
+	p.listen()
+	return p.grpc, p.err
+}
+
+func (p *sharedPort) REST() ([]net.Listener, error) {
+	p.listen()
+	return p.rest, p.err
+}
+
+// split accepts the connections of l and sorts them between two listeners.
+// l is closed once both are.
+func (p *sharedPort) split(l net.Listener) (grpc, rest *sortedListener) {
+	var remaining sync.WaitGroup
+	remaining.Add(2)
+	closed := make(chan struct{})
+	grpc = &sortedListener{Listener: l, conns: make(chan net.Conn), closed: make(chan struct{}), release: remaining.Done}
+	rest = &sortedListener{Listener: l, conns: make(chan net.Conn), closed: make(chan struct{}), release: remaining.Done}
+	go func() {
+		remaining.Wait()
+		close(closed)
+		l.Close()
+	}()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				select {
+				case <-closed:
+				default:
+					log.Printf("shared: listener %s stopped: %s", l.Addr(), err)
+				}
+				grpc.fail(err)
+				rest.fail(err)
+				return
+			}
+			go p.sort(conn, grpc, rest)
+		}
+	}()
+	return grpc, rest
+}
+
+// sort reads as much of the first bytes of conn as it takes to tell whether
+// they are the HTTP/2 preface, and hands the connection, bytes included, to
+// its listener.
+func (p *sharedPort) sort(conn net.Conn, grpc, rest *sortedListener) {
+	conn.SetReadDeadline(time.Now().Add(p.sniff))
+	var buf []byte
+	chunk := make([]byte, len(http2Preface))
+	for len(buf) < len(http2Preface) && bytes.HasPrefix([]byte(http2Preface), buf) {
+		n, err := conn.Read(chunk[:len(http2Preface)-len(buf)])
+		buf = append(buf, chunk[:n]...)
+		if err != nil {
+			conn.Close()
+			return
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	to := rest
+	if string(buf) == http2Preface {
+		to = grpc
+	}
+	to.deliver(&sniffedConn{Conn: conn, r: io.MultiReader(bytes.NewReader(buf), conn)})
+}
+
+// sortedListener is one side of a split listener.
+type sortedListener struct {
+	net.Listener
+	conns   chan net.Conn
+	closed  chan struct{}
+	once    sync.Once
+	release func()
+	// released calls release once, after fail too.
+	released sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+func (l *sortedListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.err != nil {
+			return nil, l.err
+		}
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *sortedListener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.closed:
+		conn.Close()
+	}
+}
+
+// fail ends Accept with the error of the shared listener.
+func (l *sortedListener) fail(err error) {
+	l.mu.Lock()
+	l.err = err
+	l.mu.Unlock()
+	l.once.Do(func() { close(l.closed) })
+}
+
+// Close stops this side only, the shared listener closes with the last one.
+func (l *sortedListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	l.released.Do(l.release)
+	return nil
+}
+
+// sniffedConn replays the bytes read to sort the connection.
+type sniffedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *sniffedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+)
+
+// staticCities is the snapshot List and ListStream serve while the static
+// mode is on: the cities of the default simulator with the default names,
+// built once when the mode is turned on and never changed, sent without any
+// simulated step. It gives the benchmarks a baseline where a call costs
+// nothing but the interceptors, the deadlines and the cancellation checks
+// around it. nil when the mode is off, the admin API turns it on and off.
+var staticCities atomic.Pointer[[]*cities.City]
+
+// setStaticSnapshot turns the static mode on or off.
+func setStaticSnapshot(enabled bool) {
+	if !enabled {
+		staticCities.Store(nil)
+		log.Printf("static snapshot disabled")
+		return
+	}
+	if staticCities.Load() != nil {
+		return
+	}
+
+	list := make([]*cities.City, defaultSimulator.Count())
+	for i := range list {
+		// Only a cancelled context fails a name.
+		list[i], _ = simulatedCity(context.Background(), i+1)
+	}
+	staticCities.Store(&list)
+	log.Printf("static snapshot enabled with %d cities", len(list))
+}
+
+// staticSnapshot returns the snapshot, false when the static mode is off.
+// The cities are shared by every call and must not be modified.
+func staticSnapshot() ([]*cities.City, bool) {
+	list := staticCities.Load()
+	if list == nil {
+		return nil, false
+	}
+	return *list, true
+}
+
+// instant is the simulator of the calls served from the snapshot, its steps
+// take no time and only report a context that ended.
+type instant struct {
+	count int
+}
+
+func (s instant) Name() string {
+	return "static"
+}
+
+func (s instant) Count() int {
+	return s.count
+}
+
+func (s instant) Step(ctx context.Context, step string) error {
+	return grpcerr.FromContext(ctx)
+}
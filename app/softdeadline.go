@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"time"
+
+	"go-cancel/deadline"
+)
+
+// softDeadlineFraction is the share of its budget a List or ListStream call
+// may use before it is warned, 0 disables the warning.
+var softDeadlineFraction float64
+
+var softDeadlines = expvar.NewMap("soft_deadlines")
+
+// softDeadline returns the soft deadline of a call of method, logging it
+// with the progress the call reports, if any, when it passes. The handler
+// reacts to it through the returned Soft, and must Stop it when it returns.
+func softDeadline(ctx context.Context, method string, progress func() string) *deadline.Soft {
+	remaining, _ := deadline.RemainingBudget(ctx)
+	return deadline.NewSoft(ctx, softDeadlineFraction, func() {
+		softDeadlines.Add(method, 1)
+		at := ""
+		if progress != nil {
+			at = " at " + progress()
+		}
+		log.Printf("warning: request-id %s %s used %.0f%% of its %s budget%s", requestID(ctx), method, softDeadlineFraction*100, remaining.Round(time.Millisecond), at)
+	})
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc/status"
+)
+
+// sse streams the cities of ListStream as Server-Sent Events, one city
+// event per city, its id the position of the city, flushed as soon as it
+// is written. A complete stream ends with an end event carrying the count,
+// one ended by its deadline or by a failure of the producer with an error
+// event carrying a grpcerr.HTTPError. A client that goes away cancels the
+// request context, which stops the producer at once, as a cancelled
+// ListStream does. Each event gets writeTimeout to reach the client.
+func sse(writeTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		produced := produceCities(ctx)
+
+		// Wait for the producer before returning, so it is measured too.
+		stopped := watchPropagation(r.Context(), "sse")
+		defer func() {
+			cancel()
+			for range produced.Cities {
+			}
+			stopped()
+		}()
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			log.Println("request-id", requestID(r.Context()), "error flushing event stream", err)
+			return
+		}
+
+		count := 0
+		send := func(event, id string, data interface{}) error {
+			if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				log.Println("request-id", requestID(r.Context()), "error setting write deadline", err)
+			}
+			if err := writeEvent(w, event, id, data); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		for city := range produced.Cities {
+			count++
+			if err := send("city", city.Id, city); err != nil {
+				log.Println("request-id", requestID(r.Context()), "error writing city, stopping stream", err)
+				return
+			}
+		}
+
+		sendError := func(err error) {
+			st := status.Convert(err)
+			body := grpcerr.HTTPError{Code: st.Code().String(), Message: st.Message(), RequestID: ctxmeta.RequestID(r.Context())}
+			if err := send("error", "", body); err != nil {
+				log.Println("request-id", requestID(r.Context()), "error writing error event", err)
+			}
+		}
+		if err := grpcerr.FromContext(ctx); err != nil {
+			log.Println("request-id", requestID(r.Context()), "error streaming cities", status.Convert(err).Message())
+			// Nobody reads the event of a client that went away.
+			if r.Context().Err() != nil && !errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+				return
+			}
+			sendError(err)
+			return
+		}
+		if err := produced.Err(); err != nil {
+			log.Println("request-id", requestID(r.Context()), "error producing cities", status.Convert(err).Message())
+			sendError(err)
+			return
+		}
+
+		if err := send("end", "", ndjsonEnd{Complete: true, Count: count}); err != nil {
+			log.Println("request-id", requestID(r.Context()), "error finishing stream", err)
+		}
+	}
+}
+
+// writeEvent writes one event, data encoded as JSON on a single data line.
+func writeEvent(w io.Writer, event, id string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		_, err = fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", event, id, b)
+	} else {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// statsRun is one recomputation, shared by every request waiting for it.
+type statsRun struct {
+	done  chan struct{}
+	stats *cities.CityStats
+	err   error
+}
+
+// statsScheduler refreshes the city statistics in the background. Each
+// recomputation runs under ceiling, independent of the requests waiting for
+// it, so a cancelled caller never aborts work other callers depend on.
+type statsScheduler struct {
+	repo     CityRepository
+	interval time.Duration
+	ceiling  time.Duration
+
+	mu      sync.Mutex
+	last    *cities.CityStats
+	running *statsRun
+}
+
+func newStatsScheduler(repo CityRepository, interval, ceiling time.Duration) *statsScheduler {
+	return &statsScheduler{repo: repo, interval: interval, ceiling: ceiling}
+}
+
+// run refreshes the statistics every interval until ctx ends.
+func (s *statsScheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.refresh()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh starts a recomputation, or joins the one already in progress.
+func (s *statsScheduler) refresh() *statsRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running != nil {
+		return s.running
+	}
+
+	r := &statsRun{done: make(chan struct{})}
+	s.running = r
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.ceiling)
+		defer cancel()
+
+		r.stats, r.err = computeStats(ctx, s.repo)
+		if r.err != nil {
+			log.Println("error recomputing city stats", r.err)
+		}
+
+		s.mu.Lock()
+		if r.err == nil {
+			s.last = r.stats
+		}
+		s.running = nil
+		s.mu.Unlock()
+		close(r.done)
+	}()
+
+	return r
+}
+
+// Fresh waits, bounded by ctx, for a recomputation that is running now.
+func (s *statsScheduler) Fresh(ctx context.Context) (*cities.CityStats, error) {
+	r := s.refresh()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return nil, grpcerr.FromContext(ctx)
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return proto.Clone(r.stats).(*cities.CityStats), nil
+}
+
+// Last returns the cached statistics, marked stale when they are older than
+// one refresh interval.
+func (s *statsScheduler) Last() (*cities.CityStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last == nil {
+		return nil, status.Error(codes.Unavailable, "stats are not computed yet")
+	}
+
+	stats := proto.Clone(s.last).(*cities.CityStats)
+	stats.Stale = time.Since(time.Unix(0, stats.ComputedAt*int64(time.Millisecond))) > s.interval
+	return stats, nil
+}
+
+func computeStats(ctx context.Context, repo CityRepository) (*cities.CityStats, error) {
+	list, err := repo.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &cities.CityStats{Count: uint32(len(list))}
+	var total int
+	for _, city := range list {
+		if err := grpcerr.FromContext(ctx); err != nil {
+			return nil, err
+		}
+		// Pretend every city needs an expensive lookup.
+		if err := simulatorFrom(ctx).Step(ctx, stepStats); err != nil {
+			return nil, err
+		}
+
+		total += len(city.Name)
+		if len(city.Name) > len(stats.LongestName) {
+			stats.LongestName = city.Name
+		}
+	}
+
+	if len(list) > 0 {
+		stats.AverageNameLength = float64(total) / float64(len(list))
+	}
+	stats.ComputedAt = time.Now().UnixNano() / int64(time.Millisecond)
+
+	return stats, nil
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc/status"
+)
+
+// storedCities serves the cities of the repository on GET /cities/stored,
+// with the repository version as ETag, "<epoch>-<N>", and Last-Modified. A
+// client sending back either gets a 304 while nothing was written, answered
+// from the version alone, so it spends none of its deadline on data it
+// already has.
+//
+// The version is read before the cities: a write in between makes the body
+// newer than its ETag, and the next request gets it again rather than
+// missing it.
+func storedCities(repo *memoryRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		v := repo.Version()
+		// The epoch keeps a restarted server from matching the ETags of the
+		// previous one.
+		etag := `"` + strconv.FormatInt(v.Epoch, 36) + "-" + strconv.FormatUint(v.N, 10) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", v.Modified.UTC().Format(http.TimeFormat))
+		if notModified(r, etag, v.Modified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		list, err := repo.All(r.Context())
+		if err != nil {
+			log.Println("request-id", requestID(r.Context()), "error get stored cities", status.Convert(grpcerr.FromError(err)).Message())
+			grpcerr.WriteJSON(w, err, ctxmeta.RequestID(r.Context()))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodHead {
+			return
+		}
+		if err := encodeJSONArray(r.Context(), w, list); err != nil {
+			log.Println("request-id", requestID(r.Context()), "error writing result", status.Convert(err).Message())
+		}
+	}
+}
+
+// notModified evaluates the conditional headers of r like RFC 9110 does:
+// If-None-Match when sent, If-Modified-Since otherwise, at the one second
+// precision of HTTP dates.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// strictCancellation checks the lesson of this repository: a handler stops
+// once its context ends. A handler still running grace after that is a
+// violation and is logged. In fail mode its call also fails with Internal,
+// and Err reports every violation, so a test embedding the App fails when
+// it stops.
+type strictCancellation struct {
+	grace time.Duration
+	fail  bool
+
+	mu         sync.Mutex
+	violations []error
+}
+
+func newStrictCancellation(mode string, grace time.Duration) *strictCancellation {
+	if mode == "" || mode == "off" {
+		return nil
+	}
+	return &strictCancellation{grace: grace, fail: mode == "fail"}
+}
+
+// observe starts watching the call, done returns the error the call ends
+// with.
+func (s *strictCancellation) observe(ctx context.Context, method string) (done func(err error) error) {
+	ended := make(chan time.Time, 1)
+	returned := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			ended <- time.Now()
+		case <-returned:
+			ended <- time.Time{}
+		}
+	}()
+
+	return func(err error) error {
+		close(returned)
+
+		at := <-ended
+		if at.IsZero() {
+			return err
+		}
+		overran := time.Since(at)
+		if overran <= s.grace {
+			return err
+		}
+
+		violation := fmt.Errorf("%s ran %s after its context ended (%v), above %s", method, overran, context.Cause(ctx), s.grace)
+		log.Printf("error: strict cancellation: %s", violation)
+		if !s.fail {
+			return err
+		}
+		s.mu.Lock()
+		s.violations = append(s.violations, violation)
+		s.mu.Unlock()
+		return status.Error(codes.Internal, violation.Error())
+	}
+}
+
+func (s *strictCancellation) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return s.call(ctx, info.FullMethod, handler, req)
+}
+
+// call observes handler where it runs. The admission queue and the
+// coalescer run the unary handlers on goroutines of their own and return
+// once the context of the call ends, Unary does not see how long the
+// handler keeps running after it. Without strict mode it only calls
+// handler.
+func (s *strictCancellation) call(ctx context.Context, method string, handler grpc.UnaryHandler, req interface{}) (interface{}, error) {
+	if s == nil {
+		return handler(ctx, req)
+	}
+	done := s.observe(ctx, method)
+	resp, err := handler(ctx, req)
+	if err = done(err); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *strictCancellation) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	done := s.observe(ss.Context(), info.FullMethod)
+	return done(handler(srv, ss))
+}
+
+// Err joins the violations of fail mode, nil without any.
+func (s *strictCancellation) Err() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.violations...)
+}
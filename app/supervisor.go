@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// supervisor runs the servers of run(). The first server failing stops the
+// others, Wait waits for all of them and reports every error, not only the
+// first. Stop stops them without a failure, on a signal.
+type supervisor struct {
+	g        *errgroup.Group
+	ctx      context.Context
+	stopping context.Context
+	stop     context.CancelFunc
+
+	// onFailure is called with the error of a server failing before Stop.
+	onFailure func(error)
+
+	mu   sync.Mutex
+	errs []error
+}
+
+func newSupervisor(ctx context.Context, onFailure func(error)) *supervisor {
+	stopping, stop := context.WithCancel(ctx)
+	g, gctx := errgroup.WithContext(stopping)
+	return &supervisor{g: g, ctx: gctx, stopping: stopping, stop: stop, onFailure: onFailure}
+}
+
+// Context ends when the servers are to stop, for a failure or for Stop.
+func (s *supervisor) Context() context.Context {
+	return s.ctx
+}
+
+func (s *supervisor) Stop() {
+	s.stop()
+}
+
+// Go runs the server called name. serve must return once its context ends.
+func (s *supervisor) Go(name string, serve func(ctx context.Context) error) {
+	s.g.Go(func() error {
+		err := serve(s.ctx)
+		if err == nil {
+			return nil
+		}
+		if s.stopping.Err() == nil {
+			log.Printf("%s server failed, stopping the others: %s", name, err)
+			if s.onFailure != nil {
+				s.onFailure(err)
+			}
+		}
+		s.mu.Lock()
+		s.errs = append(s.errs, err)
+		s.mu.Unlock()
+		return err
+	})
+}
+
+// Wait waits for every server and returns their errors joined.
+func (s *supervisor) Wait() error {
+	s.g.Wait()
+	s.stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.errs...)
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"go-cancel/deadline"
+
+	"google.golang.org/grpc"
+)
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// normalizeDeadlineUnary pins a wall clock deadline to the monotonic clock
+// before anything measures it, see deadline.Normalize.
+func normalizeDeadlineUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, cancel := deadline.Normalize(ctx)
+	defer cancel()
+	return handler(ctx, req)
+}
+
+func normalizeDeadlineStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, cancel := deadline.Normalize(ss.Context())
+	defer cancel()
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// methodTimeout bounds every call by the timeout configured for its method,
+// usually the generated cities.MethodTimeouts. A client deadline that is
+// already shorter wins.
+type methodTimeout map[string]time.Duration
+
+func (t methodTimeout) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	timeout, ok := t[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return handler(ctx, req)
+}
+
+func (t methodTimeout) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	timeout, ok := t[info.FullMethod]
+	if !ok {
+		return handler(srv, ss)
+	}
+
+	ctx, cancel := context.WithTimeout(ss.Context(), timeout)
+	defer cancel()
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// defaultDeadline gives the calls that arrive without a deadline one, so no
+// handler runs unbounded against a client that never sets one. The methods
+// in exempt are long-lived by design, watches for example.
+type defaultDeadline struct {
+	timeout time.Duration
+	exempt  map[string]bool
+}
+
+func newDefaultDeadline(timeout time.Duration, exempt ...string) *defaultDeadline {
+	d := &defaultDeadline{timeout: timeout, exempt: make(map[string]bool, len(exempt))}
+	for _, m := range exempt {
+		d.exempt[m] = true
+	}
+	return d
+}
+
+func (d *defaultDeadline) bounds(ctx context.Context, method string) bool {
+	if d.timeout <= 0 || d.exempt[method] {
+		return false
+	}
+	_, ok := ctx.Deadline()
+	return !ok
+}
+
+func (d *defaultDeadline) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !d.bounds(ctx, info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	return handler(ctx, req)
+}
+
+func (d *defaultDeadline) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !d.bounds(ss.Context(), info.FullMethod) {
+		return handler(srv, ss)
+	}
+
+	ctx, cancel := context.WithTimeout(ss.Context(), d.timeout)
+	defer cancel()
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
@@ -0,0 +1,116 @@
+//go:build !minimal
+
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-cancel/config"
+)
+
+// tlsOptions serves gRPC over TLS with tc, plaintext when it is nil.
+func tlsOptions(tc *tls.Config) []Option {
+	if tc == nil {
+		return nil
+	}
+	return []Option{WithTLS(tc)}
+}
+
+// serverTLS returns the TLS settings of the gRPC server, and of the REST
+// server with rest_tls, nil when they serve plaintext. With tls_client_ca
+// the clients must present a certificate chaining to it. It generates the
+// self-signed certificate, so it runs once.
+func serverTLS(cfg config.Server) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	switch {
+	case cfg.TLSCert != "":
+		cert, err = tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS_CERT or TLS_KEY: %w", err)
+		}
+	case cfg.TLSSelfSigned:
+		cert, err = selfSignedCert()
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate the self-signed certificate: %w", err)
+		}
+	default:
+		return nil, nil
+	}
+
+	tc := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if cfg.TLSClientCA != "" {
+		pool, err := loadCertPool(cfg.TLSClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS_CLIENT_CA: %w", err)
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no PEM certificate in " + path)
+	}
+	return pool, nil
+}
+
+// selfSignedCert generates a certificate for localhost valid for a day and
+// writes it to the temporary directory, the client trusts it with
+// -tls-ca.
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "go-cancel development"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	path := filepath.Join(os.TempDir(), "go-cancel-dev-cert.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return tls.Certificate{}, err
+	}
+	log.Printf("self-signed TLS certificate written to %s, for development only", path)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// transformStage is one step of the TransformCities pipeline. Each stage runs
+// under its own timeout, carved out of the stream context.
+type transformStage struct {
+	name    string
+	timeout time.Duration
+	run     func(ctx context.Context, city *cities.City) (*cities.City, error)
+}
+
+func (u *citiesServer) transformStages() []transformStage {
+	return []transformStage{
+		{name: "validation", timeout: 50 * time.Millisecond, run: validateCity},
+		{name: "enrichment", timeout: 200 * time.Millisecond, run: enrichCity},
+		{name: "persistence", timeout: 500 * time.Millisecond, run: u.persistCity},
+	}
+}
+
+// Default timeouts of the two directions of TransformCities. A client can
+// pick others with the recv-timeout and send-timeout metadata.
+const (
+	transformRecvTimeout = 30 * time.Second
+	transformSendTimeout = 10 * time.Second
+)
+
+// directionTimeouts reads the per direction timeouts of a call.
+func directionTimeouts(ctx context.Context) (recv, send time.Duration, err error) {
+	recv, send = transformRecvTimeout, transformSendTimeout
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	for key, d := range map[string]*time.Duration{"recv-timeout": &recv, "send-timeout": &send} {
+		v := md.Get(key)
+		if len(v) == 0 {
+			continue
+		}
+		parsed, err := time.ParseDuration(v[0])
+		if err != nil || parsed <= 0 {
+			return 0, 0, status.Errorf(codes.InvalidArgument, "invalid %s %q", key, v[0])
+		}
+		*d = parsed
+	}
+	return recv, send, nil
+}
+
+// TransformCities times the two directions separately. When the client sends
+// nothing for the receive timeout, or takes longer than the send timeout to
+// read a result, the call ends with DeadlineExceeded and the
+// timeout-direction trailer says which side stalled. Every city received
+// before that has been answered.
+func (u *citiesServer) TransformCities(stream cities.CitiesService_TransformCitiesServer) error {
+	ctx := stream.Context()
+	stages := u.transformStages()
+
+	recvTimeout, sendTimeout, err := directionTimeouts(ctx)
+	if err != nil {
+		return err
+	}
+
+	received := make(chan *cities.City)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			in, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+
+			select {
+			case received <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	idle := time.NewTimer(recvTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case in := <-received:
+			res := transform(ctx, stages, in)
+			if err := grpcerr.FromContext(ctx); err != nil {
+				return err
+			}
+			if err := sendWithin(ctx, stream, res, sendTimeout); err != nil {
+				return err
+			}
+
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(recvTimeout)
+
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			if ctxErr := grpcerr.FromContext(ctx); ctxErr != nil {
+				return ctxErr
+			}
+			return err
+
+		case <-idle.C:
+			stream.SetTrailer(metadata.Pairs("timeout-direction", "receive"))
+			return status.Errorf(codes.DeadlineExceeded, "client sent nothing for %s", recvTimeout)
+
+		case <-ctx.Done():
+			return grpcerr.FromContext(ctx)
+		}
+	}
+}
+
+// sendWithin sends res and ends the call when the client took longer than
+// timeout to make room for it. A pending Send cannot be left behind, grpc-go
+// only unblocks it when the call ends, so a client that never reads again is
+// stopped by the deadline of the call or by cancelling it.
+func sendWithin(ctx context.Context, stream cities.CitiesService_TransformCitiesServer, res *cities.TransformResult, timeout time.Duration) error {
+	start := time.Now()
+	if err := stream.Send(res); err != nil {
+		if err := grpcerr.FromContext(ctx); err != nil {
+			return err
+		}
+		return status.Errorf(codes.Unknown, "cannot send stream response: %v", err)
+	}
+
+	if took := time.Since(start); took > timeout {
+		stream.SetTrailer(metadata.Pairs("timeout-direction", "send"))
+		return status.Errorf(codes.DeadlineExceeded, "client took %s to read a result, above %s", took.Round(time.Millisecond), timeout)
+	}
+	return nil
+}
+
+// transform passes city through every stage, stopping at the first failure.
+func transform(ctx context.Context, stages []transformStage, city *cities.City) *cities.TransformResult {
+	for _, stage := range stages {
+		stageCtx, cancel := context.WithTimeout(ctx, stage.timeout)
+		out, err := stage.run(stageCtx, city)
+		if err == nil {
+			err = grpcerr.FromContext(stageCtx)
+		}
+		cancel()
+
+		if err != nil {
+			st, _ := status.FromError(err)
+			return &cities.TransformResult{Stage: stage.name, Code: int32(st.Code()), Message: st.Message()}
+		}
+		city = out
+	}
+
+	return &cities.TransformResult{City: city}
+}
+
+func validateCity(ctx context.Context, city *cities.City) (*cities.City, error) {
+	name := strings.TrimSpace(city.GetName())
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if len(name) > 50 {
+		return nil, status.Error(codes.InvalidArgument, "name is longer than 50 characters")
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && r != ' ' && r != '-' {
+			return nil, status.Errorf(codes.InvalidArgument, "name contains %q", r)
+		}
+	}
+
+	return &cities.City{Name: name}, nil
+}
+
+// enrichCity normalizes the name. The lookup it pretends to do is slow
+// enough to miss its timeout now and then.
+func enrichCity(ctx context.Context, city *cities.City) (*cities.City, error) {
+	if err := simulatorFrom(ctx).Step(ctx, stepEnrich); err != nil {
+		return nil, err
+	}
+
+	return &cities.City{Name: strings.Title(strings.ToLower(city.Name))}, nil
+}
+
+func (u *citiesServer) persistCity(ctx context.Context, city *cities.City) (*cities.City, error) {
+	list, err := u.repo.BatchInsert(ctx, []string{city.Name})
+	if err != nil {
+		return nil, err
+	}
+	return list[0], nil
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var watchdogKills = expvar.NewInt("watchdog_kills")
+
+// errRuntimeCeiling is the cause the watchdog cancels a call with, it tells
+// a ceiling apart from a client deadline or a shutdown.
+var errRuntimeCeiling = status.Error(codes.Aborted, "handler exceeded the runtime ceiling")
+
+// watchdog cancels the calls of the registry running longer than ceiling,
+// whatever deadline their client gave them, and writes a goroutine dump to
+// dir the first time it finds one in a scan, so the stuck handlers can be
+// read afterwards. The methods in exempt are long-lived by design.
+type watchdog struct {
+	calls   *callRegistry
+	ceiling time.Duration
+	dir     string
+	exempt  map[string]bool
+}
+
+func newWatchdog(calls *callRegistry, ceiling time.Duration, dir string, exempt ...string) *watchdog {
+	w := &watchdog{calls: calls, ceiling: ceiling, dir: dir, exempt: make(map[string]bool, len(exempt))}
+	for _, m := range exempt {
+		w.exempt[m] = true
+	}
+	return w
+}
+
+// run scans the registry until ctx ends, a tenth of the ceiling apart, at
+// least every ten seconds and at most every millisecond.
+func (w *watchdog) run(ctx context.Context) {
+	every := w.ceiling / 10
+	switch {
+	case every > 10*time.Second:
+		every = 10 * time.Second
+	case every < time.Millisecond:
+		// A ceiling under 10ns would make it zero, which NewTicker
+		// does not take.
+		every = time.Millisecond
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.scan()
+		}
+	}
+}
+
+func (w *watchdog) scan() {
+	dumped := false
+	for _, call := range w.calls.Overdue(time.Now().Add(-w.ceiling)) {
+		if w.exempt[call.method] {
+			continue
+		}
+		if !dumped {
+			dumped = true
+			if path, err := w.dump(); err != nil {
+				log.Printf("error: watchdog: cannot write the goroutine dump: %v", err)
+			} else {
+				log.Printf("watchdog: goroutine dump written to %s", path)
+			}
+		}
+		log.Printf("error: watchdog: request-id %s %s running for %s, above %s, cancelled", call.id, call.method, time.Since(call.start).Round(time.Millisecond), w.ceiling)
+		watchdogKills.Add(1)
+		call.cancel(errRuntimeCeiling)
+	}
+}
+
+// dump writes the stacks of every goroutine, taken before the overdue calls
+// are cancelled.
+func (w *watchdog) dump() (string, error) {
+	path := filepath.Join(w.dir, fmt.Sprintf("goroutines-%s.txt", time.Now().UTC().Format("20060102T150405.000")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestWatchdogTinyCeiling runs the watchdog with a ceiling under 10ns, too
+// short for a tenth of it to be a ticker period: it still scans and
+// cancels the call.
+func TestWatchdogTinyCeiling(t *testing.T) {
+	calls := newCallRegistry()
+	callCtx, done := calls.track(context.Background(), citiesServicePrefix+"List")
+	defer done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go newWatchdog(calls, 5*time.Nanosecond, t.TempDir()).run(ctx)
+
+	select {
+	case <-callCtx.Done():
+		if cause := context.Cause(callCtx); cause != errRuntimeCeiling {
+			t.Fatalf("call cancelled with %v, want %v", cause, errRuntimeCeiling)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call not cancelled by the watchdog")
+	}
+}
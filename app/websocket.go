@@ -0,0 +1,93 @@
+//go:build !minimal
+
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+
+	"nhooyr.io/websocket"
+)
+
+var errListenerClosed = errors.New("websocket listener closed")
+
+// wsListener turns WebSocket connections accepted by the REST server into a
+// net.Listener the gRPC server can Serve, for networks that block HTTP/2.
+// Closing the socket closes the gRPC transport, which cancels the context of
+// every stream running on it.
+type wsListener struct {
+	conns chan net.Conn
+	addr  wsAddr
+
+	once   sync.Once
+	closed chan struct{}
+}
+
+func newWsListener(path string) *wsListener {
+	return &wsListener{
+		conns:  make(chan net.Conn),
+		addr:   wsAddr(path),
+		closed: make(chan struct{}),
+	}
+}
+
+func (l *wsListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, errListenerClosed
+	}
+}
+
+func (l *wsListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *wsListener) Addr() net.Addr {
+	return l.addr
+}
+
+// ServeHTTP upgrades the request and hands the tunnel to the gRPC server. It
+// returns once the tunnel is closed by either side.
+func (l *wsListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		log.Println("error accepting websocket", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn := &wsConn{Conn: websocket.NetConn(ctx, c, websocket.MessageBinary), cancel: cancel}
+	select {
+	case l.conns <- conn:
+	case <-l.closed:
+		c.Close(websocket.StatusGoingAway, "server is shutting down")
+		return
+	}
+
+	<-ctx.Done()
+}
+
+// wsConn cancels the tunnel context when gRPC closes the connection.
+type wsConn struct {
+	net.Conn
+	cancel context.CancelFunc
+}
+
+func (c *wsConn) Close() error {
+	defer c.cancel()
+	return c.Conn.Close()
+}
+
+type wsAddr string
+
+func (a wsAddr) Network() string { return "websocket" }
+func (a wsAddr) String() string  { return string(a) }
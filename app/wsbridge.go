@@ -0,0 +1,125 @@
+//go:build !minimal
+
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"nhooyr.io/websocket"
+)
+
+// wsStatusBase is added to the gRPC code of a stream that failed to make
+// the close code of the socket, in the 4000-4999 range left to
+// applications: 4004 is DEADLINE_EXCEEDED.
+const wsStatusBase = 4000
+
+// wsCityStream is the JSON of the CityStream messages, the field names of
+// the proto like the REST gateway.
+var wsCityStream = protojson.MarshalOptions{UseProtoNames: true}
+
+// wsCities bridges ListStream to browsers: it upgrades the request to a
+// WebSocket and sends every CityStream message of the call as a text
+// message. The query parameters are the fields of the ListStreamRequest,
+// as on /v1/cities/stream. The call goes through the in-process connection
+// of gateway, and its context ends with the socket: a browser closing the
+// tab cancels the ListStream on the server. The socket closes normally
+// once the stream is complete, with wsStatusBase plus the gRPC code when it
+// failed. Each message gets writeTimeout to reach the client.
+func wsCities(gateway *restGateway, writeTimeout time.Duration) http.Handler {
+	client := cities.NewCitiesServiceClient(gateway.conn)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &cities.ListStreamRequest{}
+		if err := runtime.PopulateQueryParameters(req, r.URL.Query(), &utilities.DoubleArray{}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			log.Println("request-id", requestID(r.Context()), "error accepting websocket", err)
+			return
+		}
+		defer c.Close(websocket.StatusInternalError, "")
+
+		// The client sends nothing, CloseRead tells when it closes the socket
+		// or the connection drops. It gets no deadline, reading past one
+		// would drop the connection before the close code is sent. The call
+		// context derives from the request context, which carries the request
+		// id and the budget of the headers.
+		closed := c.CloseRead(context.Background())
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-closed.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		// The gateway forwards the Authorization header itself, the bridge
+		// calls the connection directly.
+		md := restGatewayMetadata(ctx, r)
+		if v := r.Header.Get("Authorization"); v != "" {
+			md.Set("authorization", v)
+		}
+		ctx = metadata.NewOutgoingContext(ctx, md)
+
+		stream, err := client.ListStream(ctx, req)
+		if err != nil {
+			closeWithStatus(c, err)
+			return
+		}
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				c.Close(websocket.StatusNormalClosure, "complete")
+				return
+			}
+			if err != nil {
+				if errors.Is(ctx.Err(), context.Canceled) {
+					debugf("request-id %s websocket closed by the client, ListStream cancelled", requestID(r.Context()))
+					return
+				}
+				closeWithStatus(c, err)
+				return
+			}
+
+			data, err := wsCityStream.Marshal(msg)
+			if err != nil {
+				closeWithStatus(c, status.Error(codes.Internal, err.Error()))
+				return
+			}
+			writeCtx, cancel := context.WithTimeout(ctx, writeTimeout)
+			err = c.Write(writeCtx, websocket.MessageText, data)
+			cancel()
+			if err != nil {
+				log.Println("request-id", requestID(r.Context()), "error writing websocket message, stopping stream", err)
+				return
+			}
+		}
+	})
+}
+
+// closeWithStatus closes c with the gRPC status of err, its message as the
+// reason, cut to the 123 bytes a close frame holds.
+func closeWithStatus(c *websocket.Conn, err error) {
+	st := status.Convert(err)
+	reason := st.Message()
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	c.Close(websocket.StatusCode(wsStatusBase+int(st.Code())), reason)
+}
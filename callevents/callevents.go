@@ -0,0 +1,126 @@
+// Package callevents reports the calls of a gRPC client to the application
+// running it, so it can feed its own metrics or logs: when a call starts
+// and ends, and why it ended, and when the client repeats or resumes one.
+//
+// An application implements Events, embedding Nop for the events it does
+// not care about, and installs it with UnaryClientInterceptor and
+// StreamClientInterceptor:
+//
+//	type metrics struct{ callevents.Nop }
+//
+//	func (metrics) OnCallEnd(method string, reason callevents.Termination, err error, elapsed time.Duration) {
+//		callDuration.WithLabelValues(method, string(reason)).Observe(elapsed.Seconds())
+//	}
+//
+//	conn, err := grpc.Dial(addr,
+//		grpc.WithChainUnaryInterceptor(callevents.UnaryClientInterceptor(metrics{})),
+//		grpc.WithChainStreamInterceptor(callevents.StreamClientInterceptor(metrics{})),
+//	)
+package callevents
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Termination is why a call ended.
+type Termination string
+
+const (
+	Completed        Termination = "completed"
+	Cancelled        Termination = "cancelled"
+	DeadlineExceeded Termination = "deadline_exceeded"
+	Failed           Termination = "failed"
+)
+
+// TerminationOf returns why a call returning err ended, io.EOF being the
+// end of a completed stream.
+func TerminationOf(err error) Termination {
+	switch {
+	case err == nil || err == io.EOF:
+		return Completed
+	case status.Code(err) == codes.Canceled:
+		return Cancelled
+	case status.Code(err) == codes.DeadlineExceeded:
+		return DeadlineExceeded
+	}
+	return Failed
+}
+
+// Events is told about the calls of a client. The methods are called on the
+// goroutine of the call and must not block.
+type Events interface {
+	// OnCallStart and OnCallEnd bracket every gRPC call, err is nil for a
+	// completed one.
+	OnCallStart(method string)
+	OnCallEnd(method string, reason Termination, err error, elapsed time.Duration)
+	// OnRetry reports a call repeated another way after err, e.g. List
+	// falling back to the REST gateway.
+	OnRetry(method, how string, err error)
+	// OnResume reports a stream resuming in a new call, after it was cut
+	// off or because the server got an extension.
+	OnResume(method, why string)
+}
+
+// Nop ignores every event. Embedded in an Events, it keeps the
+// implementation compiling when events are added.
+type Nop struct{}
+
+func (Nop) OnCallStart(string)                                  {}
+func (Nop) OnCallEnd(string, Termination, error, time.Duration) {}
+func (Nop) OnRetry(string, string, error)                       {}
+func (Nop) OnResume(string, string)                             {}
+
+// UnaryClientInterceptor reports the start and the end of every unary call.
+func UnaryClientInterceptor(events Events) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		events.OnCallStart(method)
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		events.OnCallEnd(method, TerminationOf(err), err, time.Since(start))
+		return err
+	}
+}
+
+// StreamClientInterceptor reports the start and the end of every stream,
+// which ends at the first error its receive returns.
+func StreamClientInterceptor(events Events) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		events.OnCallStart(method)
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			events.OnCallEnd(method, TerminationOf(err), err, time.Since(start))
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, events: events, method: method, start: start}, nil
+	}
+}
+
+// clientStream reports the end of the stream at the first error its
+// receive returns, io.EOF for a completed one.
+type clientStream struct {
+	grpc.ClientStream
+	events Events
+	method string
+	start  time.Time
+	done   bool
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && !s.done {
+		s.done = true
+		reported := err
+		if err == io.EOF {
+			reported = nil
+		}
+		s.events.OnCallEnd(s.method, TerminationOf(err), reported, time.Since(s.start))
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package callevents
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ends records the OnCallEnd events, Nop takes the others.
+type ends struct {
+	Nop
+	reasons []Termination
+}
+
+func (e *ends) OnCallEnd(method string, reason Termination, err error, elapsed time.Duration) {
+	e.reasons = append(e.reasons, reason)
+}
+
+// recvStream answers RecvMsg with errs, one per call.
+type recvStream struct {
+	grpc.ClientStream
+	errs []error
+}
+
+func (s *recvStream) RecvMsg(m interface{}) error {
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	tests := []struct {
+		name string
+		errs []error
+		want Termination
+	}{
+		{name: "completed", errs: []error{nil, io.EOF, io.EOF}, want: Completed},
+		{name: "cancelled", errs: []error{nil, status.Error(codes.Canceled, "context canceled"), io.EOF}, want: Cancelled},
+		{name: "deadline exceeded", errs: []error{status.Error(codes.DeadlineExceeded, "deadline"), io.EOF}, want: DeadlineExceeded},
+		{name: "failed", errs: []error{errors.New("connection reset"), io.EOF}, want: Failed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &ends{}
+			streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return &recvStream{errs: tt.errs}, nil
+			}
+			cs, err := StreamClientInterceptor(events)(context.Background(), &grpc.StreamDesc{}, nil, "/cities.CitiesService/ListStream", streamer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range tt.errs {
+				cs.RecvMsg(nil)
+			}
+			if len(events.reasons) != 1 || events.reasons[0] != tt.want {
+				t.Fatalf("reported %v, want one %s", events.reasons, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Package citiesrpc holds the types of the net/rpc binding of CitiesService,
+// shared by the server and its clients.
+//
+// net/rpc has no context, no metadata and no status codes. The arguments of
+// every call carry the metadata and the time the client is willing to wait,
+// which the server turns into the context of the call. An error reaches the
+// client as its text only. A client that stops waiting cannot tell the
+// server, the call runs on until its timeout.
+package citiesrpc
+
+import (
+	"context"
+	"time"
+
+	"go-cancel/deadline"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// ServiceName is the name the service is registered under, methods are
+// called as "CitiesService.List".
+const ServiceName = "CitiesService"
+
+// Path is where the REST server accepts net/rpc connections, see
+// rpc.DialHTTPPath.
+const Path = "/netrpc"
+
+// Call is what every call sends besides its request.
+type Call struct {
+	// Metadata is sent to the server as gRPC request metadata.
+	Metadata map[string]string
+	// Timeout bounds the call on the server, zero leaves only the method
+	// timeout.
+	Timeout time.Duration
+}
+
+// CallFrom takes the outgoing metadata and the deadline of ctx.
+func CallFrom(ctx context.Context) Call {
+	var c Call
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		c.Metadata = make(map[string]string, len(md))
+		for key, values := range md {
+			if len(values) > 0 {
+				c.Metadata[key] = values[0]
+			}
+		}
+	}
+	if remaining, ok := deadline.RemainingBudget(ctx); ok {
+		c.Timeout = remaining
+	}
+	return c
+}
+
+type ListArgs struct {
+	Call
+}
+
+type CreateArgs struct {
+	Call
+	Request *cities.CreateCityRequest
+}
+
+type StatsArgs struct {
+	Call
+	Request *cities.StatsRequest
+}
+
+type ServerInfoArgs struct {
+	Call
+}
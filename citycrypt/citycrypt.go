@@ -0,0 +1,94 @@
+// Package citycrypt encrypts City payloads between one client and the server
+// with a key of their own for every request.
+//
+// The client sends an X25519 public key in the city-key metadata. The server
+// answers with its own in the city-key header, both sides derive the same
+// AES-256-GCM key from the shared secret and the server seals every City it
+// returns.
+//
+// The keys are exchanged unauthenticated, so this only protects against
+// passive observers of a plain text transport: whoever can rewrite the
+// metadata can put a key of its own on both sides and read the cities. The
+// client must rely on TLS to know it talks to the server.
+package citycrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// MetadataKey carries the public key of the client in the request metadata
+// and the one of the server in the response header.
+const MetadataKey = "city-key"
+
+// GenerateKey returns a fresh key pair, it must only be used for one request.
+func GenerateKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// EncodeKey formats a public key for the metadata.
+func EncodeKey(pub *ecdh.PublicKey) string {
+	return base64.RawStdEncoding.EncodeToString(pub.Bytes())
+}
+
+// ParseKey reads a public key formatted by EncodeKey.
+func ParseKey(s string) (*ecdh.PublicKey, error) {
+	b, err := base64.RawStdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", MetadataKey, err)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", MetadataKey, err)
+	}
+	return pub, nil
+}
+
+// Sealer encrypts and decrypts the payloads of one request.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// Agree derives the key of the request from the private key of one side and
+// the public key of the other. Both sides get the same Sealer.
+func Agree(priv *ecdh.PrivateKey, peer *ecdh.PublicKey) (*Sealer, error) {
+	secret, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	key := sha256.Sum256(append([]byte("go-cancel city payload\x00"), secret...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, the nonce is prepended to the result.
+func (s *Sealer) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts what Seal returned.
+func (s *Sealer) Open(sealed []byte) ([]byte, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errors.New("sealed payload too short")
+	}
+	return s.aead.Open(nil, sealed[:n], sealed[n:], nil)
+}
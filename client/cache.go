@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"go-cancel/pb/cities"
+)
+
+// staleError comes with the cached cities List returns when the server could
+// not be reached. err is why the call failed.
+type staleError struct {
+	age time.Duration
+	err error
+}
+
+func (e *staleError) Error() string {
+	return fmt.Sprintf("serving cities cached %s ago: %v", e.age.Round(time.Second), e.err)
+}
+
+func (e *staleError) Unwrap() error {
+	return e.err
+}
+
+// listCache keeps the latest successful List result in a JSON file. Entries
+// older than ttl are not served. The files it cannot read are reported to
+// messages.
+type listCache struct {
+	path     string
+	ttl      time.Duration
+	messages io.Writer
+}
+
+type cachedList struct {
+	SavedAt time.Time      `json:"saved_at"`
+	Cities  []*cities.City `json:"cities"`
+}
+
+func (c *listCache) Save(list []*cities.City, now time.Time) error {
+	data, err := json.Marshal(cachedList{SavedAt: now, Cities: list})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, data, 0600)
+}
+
+// Load returns the cached cities, or false when there are none fresh enough
+// at now.
+func (c *listCache) Load(now time.Time) (cachedList, bool) {
+	var cached cachedList
+
+	data, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(c.messages, "cannot read list cache: %s\n", err)
+		}
+		return cached, false
+	}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		fmt.Fprintf(c.messages, "cannot parse list cache: %s\n", err)
+		return cached, false
+	}
+
+	return cached, now.Sub(cached.SavedAt) <= c.ttl
+}
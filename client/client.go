@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go-cancel/callevents"
+	"go-cancel/clock"
+	"go-cancel/pb/cities"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// errDrained is returned by ListStream when Close stopped the stream at a
+// message boundary. The resume token has been saved by then.
+var errDrained = errors.New("stream drained on close")
+
+// Client wraps the gRPC connection and keeps track of the streams it opened,
+// so Close can stop them cleanly.
+type Client struct {
+	conn   *grpc.ClientConn
+	cities cities.CitiesServiceClient
+	tokens *tokenStore
+
+	// clock times everything the client waits for, newClient sets
+	// clock.System.
+	clock clock.Clock
+
+	// drainTimeout is how long Close waits for active streams to finish the
+	// message they are handling. Zero cancels them immediately.
+	drainTimeout time.Duration
+
+	// restURL is the REST gateway List falls back to when the gRPC call
+	// is unreachable within fallbackWindow. Empty disables it.
+	restURL        string
+	fallbackWindow time.Duration
+	// rest sends the fallback requests, the client settings for an https
+	// gateway.
+	rest *http.Client
+
+	// cache serves the last successful List when the server cannot be
+	// reached. Nil disables it.
+	cache *listCache
+
+	// validate checks every received city and prints the anomalies.
+	validate bool
+	// messages receives what the client prints besides the cities,
+	// newClient sets stdout.
+	messages io.Writer
+
+	// enrich asks ListStream for city details, looked up that many cities
+	// ahead of the stream. Zero disables it.
+	enrich uint32
+
+	// extension answers the ExtensionRequests of ListStream. Nil does not
+	// take part in the protocol, the stream ends at its deadline.
+	extension *extensionPolicy
+
+	// slowStart paces the streams that resume from a saved token. Nil
+	// reads them at full speed.
+	slowStart *slowStart
+
+	// events is told about the retries and resumes of the client, the
+	// interceptors built with it about every call. newClient sets
+	// callevents.Nop.
+	events callevents.Events
+
+	mu      sync.Mutex
+	streams map[*trackedStream]struct{}
+	wg      sync.WaitGroup
+}
+
+func newClient(conn *grpc.ClientConn, tokens *tokenStore, drainTimeout time.Duration) *Client {
+	return &Client{
+		conn:         conn,
+		cities:       cities.NewCitiesServiceClient(conn),
+		tokens:       tokens,
+		clock:        clock.System{},
+		events:       callevents.Nop{},
+		drainTimeout: drainTimeout,
+		rest:         http.DefaultClient,
+		messages:     os.Stdout,
+		streams:      make(map[*trackedStream]struct{}),
+	}
+}
+
+// trackedStream guards the handling of one message, so a drain never cuts a
+// message in half.
+type trackedStream struct {
+	mu       sync.Mutex
+	draining bool
+	cancel   context.CancelFunc
+}
+
+func (s *trackedStream) drain() {
+	s.mu.Lock()
+	s.draining = true
+	s.cancel()
+	s.mu.Unlock()
+}
+
+// ListStream calls fn for every city, resuming from the saved token if there
+// is one. The token is saved whenever the stream stops early. A batchSize
+// above one asks the server for chunks of that many cities per message.
+//
+// When the server asks for more time and the extension policy grants it,
+// the call is cancelled and the stream resumes in a new call whose deadline
+// no longer derives from ctx. Close still stops it.
+func (c *Client) ListStream(ctx context.Context, batchSize uint32, fn func(*cities.City) error) error {
+	stop, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := &trackedStream{cancel: cancel}
+	c.mu.Lock()
+	c.streams[s] = struct{}{}
+	c.wg.Add(1)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.streams, s)
+		c.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	if c.validate {
+		v, handle := newCityValidator("ListStream", c.messages), fn
+		fn = func(city *cities.City) error {
+			v.Check(city)
+			return handle(city)
+		}
+	}
+
+	callCtx, cancelCall := linkCancel(ctx, stop)
+	for granted := 0; ; granted++ {
+		err := c.listStream(callCtx, s, batchSize, fn, granted)
+		cancelCall()
+
+		var ext *extensionGranted
+		if !errors.As(err, &ext) {
+			return err
+		}
+
+		// The metadata of the call, such as the simulator, goes along.
+		md, _ := metadata.FromOutgoingContext(ctx)
+		timeoutCtx, cancelTimeout := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), md), ext.timeout)
+		linked, cancelLinked := linkCancel(timeoutCtx, stop)
+		callCtx, cancelCall = linked, func() {
+			cancelLinked()
+			cancelTimeout()
+		}
+	}
+}
+
+// listStream runs one call of ListStream, granted is the number of
+// extensions given before it.
+func (c *Client) listStream(ctx context.Context, s *trackedStream, batchSize uint32, fn func(*cities.City) error, granted int) error {
+	token := c.tokens.Get("ListStream")
+	stream, err := c.cities.ListStream(ctx, &cities.ListStreamRequest{
+		ResumeToken:       token,
+		BatchSize:         batchSize,
+		Enrich:            c.enrich > 0,
+		EnrichParallelism: c.enrich,
+		AllowExtension:    c.extension != nil,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only a stream that was cut off starts slowly, not one resumed
+	// because the server got an extension.
+	var pace *pacer
+	switch {
+	case granted > 0:
+		c.events.OnResume("/cities.CitiesService/ListStream", "after an extension")
+	case token != "":
+		c.events.OnResume("/cities.CitiesService/ListStream", "from a saved token")
+		pace = c.slowStart.pacer(c.clock)
+	}
+
+	for first := true; ; first = false {
+		if !first {
+			pace.wait(ctx)
+		}
+		resp, err := stream.Recv()
+
+		s.mu.Lock()
+		if s.draining {
+			s.mu.Unlock()
+			return c.saveToken(token, errDrained)
+		}
+		if err == io.EOF {
+			s.mu.Unlock()
+			return c.saveToken("", nil)
+		}
+		if err != nil {
+			s.mu.Unlock()
+			return c.saveToken(token, err)
+		}
+
+		if ext := resp.GetExtension(); ext != nil {
+			token = resp.GetResumeToken()
+			s.mu.Unlock()
+
+			timeout, deny := c.extension.decide(ext, granted)
+			if deny != nil {
+				fmt.Fprintf(c.messages, "server asked for %dms more at %.0f%%, denied: %s\n", ext.GetRequestedMs(), 100*ext.GetProgress(), deny)
+				continue
+			}
+			fmt.Fprintf(c.messages, "server asked for %dms more at %.0f%%, resuming with a deadline of %s\n", ext.GetRequestedMs(), 100*ext.GetProgress(), timeout)
+			return c.saveToken(token, &extensionGranted{timeout: timeout})
+		}
+
+		err = handleCities(resp, fn)
+		token = resp.GetResumeToken()
+		s.mu.Unlock()
+
+		if err != nil {
+			return c.saveToken(token, err)
+		}
+	}
+}
+
+// handleCities calls fn for the single city or for every city of a chunk.
+func handleCities(resp *cities.CityStream, fn func(*cities.City) error) error {
+	if resp.GetCity() != nil {
+		return fn(resp.GetCity())
+	}
+	for _, city := range resp.GetCities() {
+		if err := fn(city); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) saveToken(token string, err error) error {
+	if saveErr := c.tokens.Set("ListStream", token); saveErr != nil && err == nil {
+		return saveErr
+	}
+	return err
+}
+
+// Close drains the active streams, waiting at most drainTimeout for them to
+// reach a message boundary, then closes the connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	active := make([]*trackedStream, 0, len(c.streams))
+	for s := range c.streams {
+		active = append(active, s)
+	}
+	c.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		for _, s := range active {
+			s.drain()
+		}
+		c.wg.Wait()
+		close(drained)
+	}()
+
+	timeout, stop := c.clock.NewTimer(c.drainTimeout)
+	defer stop()
+	select {
+	case <-drained:
+	case <-timeout:
+		for _, s := range active {
+			s.cancel()
+		}
+	}
+
+	return c.conn.Close()
+}
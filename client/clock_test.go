@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-cancel/clock"
+	"go-cancel/clock/clocktest"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// stubCities answers List with list, ListPage with listPage and ListStream
+// with listStream, the other methods are not called.
+type stubCities struct {
+	cities.CitiesServiceClient
+	list       func() (*cities.Cities, error)
+	listPage   func(in *cities.ListPageRequest) (*cities.CityPage, error)
+	listStream func(ctx context.Context, in *cities.ListStreamRequest) (cities.CitiesService_ListStreamClient, error)
+}
+
+func (s stubCities) List(ctx context.Context, in *cities.EmptyMessage, opts ...grpc.CallOption) (*cities.Cities, error) {
+	return s.list()
+}
+
+func (s stubCities) ListPage(ctx context.Context, in *cities.ListPageRequest, opts ...grpc.CallOption) (*cities.CityPage, error) {
+	return s.listPage(in)
+}
+
+func (s stubCities) ListStream(ctx context.Context, in *cities.ListStreamRequest, opts ...grpc.CallOption) (cities.CitiesService_ListStreamClient, error) {
+	return s.listStream(ctx, in)
+}
+
+// newTestClient returns a client timed by clk whose connection is never
+// used.
+func newTestClient(t *testing.T, clk clock.Clock) *Client {
+	t.Helper()
+	conn, err := grpc.Dial("passthrough:///unused", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	c := newClient(conn, nil, time.Second)
+	c.clock = clk
+	c.messages = io.Discard
+	return c
+}
+
+// waitForTimers waits for the code under test to block on n timers of clk.
+func waitForTimers(t *testing.T, clk *clocktest.Manual, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for clk.Waiters() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d timers pending, want %d", clk.Waiters(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitDone(t *testing.T, done <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s still blocked", what)
+	}
+}
+
+func TestSlowStartOnManualClock(t *testing.T) {
+	clk := clocktest.NewManual(time.Unix(0, 0))
+	s := &slowStart{initial: 8 * time.Millisecond}
+	p := s.pacer(clk)
+
+	for _, gap := range []time.Duration{8 * time.Millisecond, 4 * time.Millisecond, 2 * time.Millisecond, time.Millisecond} {
+		done := make(chan struct{})
+		go func() {
+			p.wait(context.Background())
+			close(done)
+		}()
+		waitForTimers(t, clk, 1)
+
+		clk.Advance(gap - time.Nanosecond)
+		select {
+		case <-done:
+			t.Fatalf("wait of %s returned before its gap passed", gap)
+		default:
+		}
+		clk.Advance(time.Nanosecond)
+		waitDone(t, done, "wait")
+	}
+
+	// Below a millisecond the stream is read at full speed.
+	p.wait(context.Background())
+	if s.paced != 4 || s.waited != 15*time.Millisecond {
+		t.Fatalf("paced %d messages for %s, want 4 for 15ms", s.paced, s.waited)
+	}
+}
+
+func TestCloseDrainTimeoutOnManualClock(t *testing.T) {
+	tests := []struct {
+		name string
+		// finish ends the stream before the drain timeout.
+		finish bool
+	}{
+		{name: "stream reaches a message boundary", finish: true},
+		{name: "stream stuck past the drain timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clocktest.NewManual(time.Unix(0, 0))
+			c := newTestClient(t, clk)
+
+			s := &trackedStream{cancel: func() {}}
+			c.streams[s] = struct{}{}
+			c.wg.Add(1)
+
+			done := make(chan struct{})
+			go func() {
+				c.Close()
+				close(done)
+			}()
+			waitForTimers(t, clk, 1)
+
+			if tt.finish {
+				c.wg.Done()
+				waitDone(t, done, "Close")
+				return
+			}
+			defer c.wg.Done()
+
+			clk.Advance(c.drainTimeout - time.Nanosecond)
+			select {
+			case <-done:
+				t.Fatal("Close returned before the drain timeout")
+			default:
+			}
+			clk.Advance(time.Nanosecond)
+			waitDone(t, done, "Close")
+		})
+	}
+}
+
+func TestFallbackWindowOnManualClock(t *testing.T) {
+	body, err := protojson.Marshal(&cities.Cities{City: []*cities.City{{Name: "Bandung"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// took is how long the failing gRPC call took.
+		took         time.Duration
+		wantFallback bool
+	}{
+		{name: "unavailable within the window", took: time.Second - time.Nanosecond, wantFallback: true},
+		{name: "unavailable after the window", took: time.Second + time.Nanosecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fellBack := false
+			rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fellBack = true
+				w.Write(body)
+			}))
+			defer rest.Close()
+
+			clk := clocktest.NewManual(time.Unix(0, 0))
+			c := newTestClient(t, clk)
+			defer c.Close()
+			c.restURL, c.fallbackWindow = rest.URL, time.Second
+			c.cities = stubCities{list: func() (*cities.Cities, error) {
+				clk.Advance(tt.took)
+				return nil, status.Error(codes.Unavailable, "connection refused")
+			}}
+
+			list, err := c.List(context.Background())
+			if fellBack != tt.wantFallback {
+				t.Fatalf("fell back to REST: %v, want %v", fellBack, tt.wantFallback)
+			}
+			if tt.wantFallback && (err != nil || len(list) != 1) {
+				t.Fatalf("List through REST: %v, %v", list, err)
+			}
+			if !tt.wantFallback && status.Code(err) != codes.Unavailable {
+				t.Fatalf("List: got %v, want Unavailable", err)
+			}
+		})
+	}
+}
+
+func TestCacheTTLOnManualClock(t *testing.T) {
+	clk := clocktest.NewManual(time.Unix(0, 0))
+	c := newTestClient(t, clk)
+	defer c.Close()
+	c.cache = &listCache{path: filepath.Join(t.TempDir(), "cache.json"), ttl: time.Minute, messages: io.Discard}
+
+	up := true
+	c.cities = stubCities{list: func() (*cities.Cities, error) {
+		if up {
+			return &cities.Cities{City: []*cities.City{{Name: "Bandung"}}}, nil
+		}
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}}
+	if _, err := c.List(context.Background()); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	up = false
+
+	clk.Advance(time.Minute)
+	list, err := c.List(context.Background())
+	var stale *staleError
+	if !errors.As(err, &stale) || len(list) != 1 || stale.age != time.Minute {
+		t.Fatalf("List at the TTL: got %v, %v, want the cached city with a staleError", list, err)
+	}
+
+	clk.Advance(time.Nanosecond)
+	if list, err := c.List(context.Background()); status.Code(err) != codes.Unavailable || list != nil {
+		t.Fatalf("List past the TTL: got %v, %v, want Unavailable", list, err)
+	}
+}
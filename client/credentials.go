@@ -0,0 +1,19 @@
+package main
+
+import (
+	"golang.org/x/net/context"
+)
+
+// bearerToken is a credentials.PerRPCCredentials sending the token with
+// every call, the server reads the caller's identity and roles from it.
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity lets the token go over the plaintext connections
+// of this demo too, -tls keeps it off the wire.
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
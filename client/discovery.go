@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/resolver"
+)
+
+// discoveryScheme is the target scheme of the backends found by a
+// dnsDiscovery, e.g. discovery:///cities.internal:9099.
+const discoveryScheme = "discovery"
+
+// dnsDiscovery resolves the host of the target again every interval, give or
+// take a fifth so that clients started together do not query the DNS in
+// step, and hands every address it lists to the connection. Closing the
+// connection stops the refresh loop and cancels the lookup in flight.
+type dnsDiscovery struct {
+	interval time.Duration
+	lookup   func(ctx context.Context, host string) ([]string, error)
+
+	mu        sync.Mutex
+	target    string
+	addrs     []string
+	refreshed time.Time
+	lookups   int
+	failures  int
+	lastErr   error
+	closed    bool
+}
+
+func newDNSDiscovery(interval time.Duration) *dnsDiscovery {
+	return &dnsDiscovery{interval: interval, lookup: net.DefaultResolver.LookupHost}
+}
+
+// Scheme implements resolver.Builder.
+func (d *dnsDiscovery) Scheme() string {
+	return discoveryScheme
+}
+
+// Build implements resolver.Builder, it starts the refresh loop of target.
+func (d *dnsDiscovery) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	host, port, err := net.SplitHostPort(target.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("discovery target %q: %w", target.Endpoint, err)
+	}
+
+	d.mu.Lock()
+	d.target = target.Endpoint
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &discoveryResolver{owner: d, cc: cc, host: host, port: port, now: make(chan struct{}, 1), cancel: cancel, done: make(chan struct{})}
+	go r.run(ctx)
+	return r, nil
+}
+
+// jittered returns the wait before the next lookup.
+func (d *dnsDiscovery) jittered() time.Duration {
+	return d.interval + time.Duration((rand.Float64()*0.4-0.2)*float64(d.interval))
+}
+
+func (d *dnsDiscovery) record(addrs []string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lookups++
+	if err != nil {
+		d.failures++
+		d.lastErr = err
+		return
+	}
+	d.addrs = addrs
+	d.refreshed = time.Now()
+	d.lastErr = nil
+}
+
+// String reports the state of the resolver for the debug output.
+func (d *dnsDiscovery) String() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state := "running"
+	if d.closed {
+		state = "stopped"
+	}
+	s := fmt.Sprintf("discovery %s: %s, %d lookups, %d failed, backends [%s]", d.target, state, d.lookups, d.failures, strings.Join(d.addrs, " "))
+	if !d.refreshed.IsZero() {
+		s += fmt.Sprintf(", refreshed %s ago", time.Since(d.refreshed).Round(time.Millisecond))
+	}
+	if d.lastErr != nil {
+		s += fmt.Sprintf(", last lookup failed: %v", d.lastErr)
+	}
+	return s
+}
+
+// discoveryResolver is the resolver of one connection.
+type discoveryResolver struct {
+	owner      *dnsDiscovery
+	cc         resolver.ClientConn
+	host, port string
+
+	now    chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (r *discoveryResolver) run(ctx context.Context) {
+	defer close(r.done)
+	for {
+		r.refresh(ctx)
+
+		timer := time.NewTimer(r.owner.jittered())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-r.now:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh looks the host up once. A failed lookup keeps the backends found
+// before, only the connection is told about the error.
+func (r *discoveryResolver) refresh(ctx context.Context) {
+	hosts, err := r.owner.lookup(ctx, r.host)
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil && len(hosts) == 0 {
+		err = fmt.Errorf("no address for %s", r.host)
+	}
+	if err != nil {
+		r.owner.record(nil, err)
+		r.cc.ReportError(err)
+		return
+	}
+
+	sort.Strings(hosts)
+	addrs := make([]string, len(hosts))
+	state := resolver.State{Addresses: make([]resolver.Address, len(hosts))}
+	for i, h := range hosts {
+		addrs[i] = net.JoinHostPort(h, r.port)
+		state.Addresses[i] = resolver.Address{Addr: addrs[i]}
+	}
+	r.owner.record(addrs, nil)
+	r.cc.UpdateState(state)
+}
+
+// ResolveNow implements resolver.Resolver, the connection asks for it when
+// a backend fails.
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+// Close implements resolver.Resolver, it returns once the refresh loop
+// stopped.
+func (r *discoveryResolver) Close() {
+	r.cancel()
+	<-r.done
+
+	r.owner.mu.Lock()
+	r.owner.closed = true
+	r.owner.mu.Unlock()
+}
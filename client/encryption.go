@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go-cancel/citycrypt"
+	"go-cancel/pb/cities"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// encryptUnary asks the server to seal the cities of the call with a key of
+// its own and opens them in the reply.
+func encryptUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	priv, err := citycrypt.GenerateKey()
+	if err != nil {
+		return err
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, citycrypt.MetadataKey, citycrypt.EncodeKey(priv.PublicKey()))
+
+	var header metadata.MD
+	if err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...); err != nil {
+		return err
+	}
+
+	sealer, err := agreeKey(priv, header)
+	if err != nil {
+		return err
+	}
+	return openResponse(sealer, reply)
+}
+
+func encryptStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	priv, err := citycrypt.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, citycrypt.MetadataKey, citycrypt.EncodeKey(priv.PublicKey()))
+
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &openingStream{ClientStream: cs, priv: priv}, nil
+}
+
+// openingStream opens the cities of every received message. The key of the
+// server arrives with the header, before the first message.
+type openingStream struct {
+	grpc.ClientStream
+	priv *ecdh.PrivateKey
+
+	once   sync.Once
+	sealer *citycrypt.Sealer
+	err    error
+}
+
+func (s *openingStream) RecvMsg(m interface{}) error {
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	s.once.Do(func() {
+		header, err := s.Header()
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.sealer, s.err = agreeKey(s.priv, header)
+	})
+	if s.err != nil {
+		return s.err
+	}
+	return openResponse(s.sealer, m)
+}
+
+func agreeKey(priv *ecdh.PrivateKey, header metadata.MD) (*citycrypt.Sealer, error) {
+	v := header.Get(citycrypt.MetadataKey)
+	if len(v) == 0 {
+		return nil, errors.New("the server did not agree on a key, it does not support encryption")
+	}
+	peer, err := citycrypt.ParseKey(v[0])
+	if err != nil {
+		return nil, err
+	}
+	return citycrypt.Agree(priv, peer)
+}
+
+// openResponse replaces every sealed City of m with its content.
+func openResponse(s *citycrypt.Sealer, m interface{}) error {
+	var list []*cities.City
+	switch r := m.(type) {
+	case *cities.City:
+		list = []*cities.City{r}
+	case *cities.Cities:
+		list = r.City
+	case *cities.CityStream:
+		list = append([]*cities.City{r.City}, r.Cities...)
+	case *cities.CityEvent:
+		list = []*cities.City{r.City}
+	case *cities.TransformResult:
+		list = []*cities.City{r.City}
+	}
+
+	for _, city := range list {
+		if city == nil {
+			continue
+		}
+		if len(city.Sealed) == 0 {
+			return fmt.Errorf("the server sent city %q in the clear", city.GetId())
+		}
+		data, err := s.Open(city.Sealed)
+		if err != nil {
+			return fmt.Errorf("open city: %w", err)
+		}
+		if err := proto.Unmarshal(data, city); err != nil {
+			return fmt.Errorf("open city: %w", err)
+		}
+	}
+	return nil
+}
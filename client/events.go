@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go-cancel/callevents"
+
+	"google.golang.org/grpc/status"
+)
+
+// logEvents prints the events to stderr, behind -events.
+type logEvents struct{}
+
+func (logEvents) OnCallStart(method string) {
+	fmt.Fprintf(os.Stderr, "event: start %s\n", method)
+}
+
+func (logEvents) OnCallEnd(method string, reason callevents.Termination, err error, elapsed time.Duration) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "event: end %s %s after %s: %s\n", method, reason, elapsed.Round(time.Millisecond), status.Convert(err).Message())
+		return
+	}
+	fmt.Fprintf(os.Stderr, "event: end %s %s after %s\n", method, reason, elapsed.Round(time.Millisecond))
+}
+
+func (logEvents) OnRetry(method, how string, err error) {
+	fmt.Fprintf(os.Stderr, "event: retry %s with %s after %s\n", method, how, status.Convert(err).Message())
+}
+
+func (logEvents) OnResume(method, why string) {
+	fmt.Fprintf(os.Stderr, "event: resume %s %s\n", method, why)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"go-cancel/pb/cities"
+
+	"golang.org/x/net/context"
+)
+
+// errTruncated is returned by Export when the export ended without its end
+// marker, the cities received so far are incomplete.
+var errTruncated = errors.New("export is truncated")
+
+// Export calls fn for every exported city. It returns errTruncated, or the
+// call's error, unless the end marker arrived.
+func (c *Client) Export(ctx context.Context, compress bool, fn func(*cities.City) error) error {
+	stream, err := c.cities.ExportCities(ctx, &cities.ExportRequest{Gzip: compress})
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				pw.Close()
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(chunk.GetData()); err != nil {
+				return
+			}
+		}
+	}()
+	defer pr.Close()
+
+	var r io.Reader = pr
+	if compress {
+		z, err := gzip.NewReader(pr)
+		if err != nil {
+			return exportError(err)
+		}
+		r = z
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var line struct {
+			ID       string `json:"id"`
+			Name     string `json:"name"`
+			Complete bool   `json:"complete"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return err
+		}
+		if line.Complete {
+			return nil
+		}
+		if err := fn(&cities.City{Id: line.ID, Name: line.Name}); err != nil {
+			return err
+		}
+	}
+	return exportError(scanner.Err())
+}
+
+// exportError keeps the call's error, an export that merely stopped short is
+// errTruncated.
+func exportError(err error) error {
+	if err == nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errTruncated
+	}
+	return err
+}
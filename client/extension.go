@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"golang.org/x/net/context"
+)
+
+// extensionPolicy decides whether ListStream gives the server the extra time
+// it asks for in an ExtensionRequest.
+type extensionPolicy struct {
+	// minProgress is the share of the stream the server must have sent.
+	minProgress float64
+	// max is the number of extensions granted to one ListStream.
+	max int
+	// longest is the longest extension granted.
+	longest time.Duration
+}
+
+// decide returns the timeout of the resumed call, or why the request is
+// denied. granted is the number of extensions given so far.
+func (p *extensionPolicy) decide(req *cities.ExtensionRequest, granted int) (time.Duration, error) {
+	requested := time.Duration(req.GetRequestedMs()) * time.Millisecond
+	switch {
+	case req.GetProgress() < p.minProgress:
+		return 0, fmt.Errorf("only %.0f%% done, %.0f%% required", 100*req.GetProgress(), 100*p.minProgress)
+	case granted >= p.max:
+		return 0, fmt.Errorf("already extended %d times", granted)
+	case requested > p.longest:
+		return 0, fmt.Errorf("%s is more than the %s allowed", requested, p.longest)
+	}
+
+	// A quarter more than asked, the estimate of the server is an average.
+	timeout := requested + requested/4
+	if timeout > p.longest {
+		timeout = p.longest
+	}
+	return timeout, nil
+}
+
+// extensionGranted ends one call of ListStream, which resumes under a new
+// deadline of timeout.
+type extensionGranted struct {
+	timeout time.Duration
+}
+
+func (e *extensionGranted) Error() string {
+	return fmt.Sprintf("extension of %s granted", e.timeout)
+}
+
+// linkCancel returns a copy of ctx that is also cancelled when stop ends.
+func linkCancel(ctx, stop context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-stop.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-cancel/clock"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+)
+
+func TestExtensionPolicyDecide(t *testing.T) {
+	p := &extensionPolicy{minProgress: 0.75, max: 2, longest: time.Second}
+
+	tests := []struct {
+		name        string
+		progress    float64
+		requestedMs int64
+		granted     int
+		want        time.Duration
+		wantDeny    bool
+	}{
+		{name: "granted with a quarter more", progress: 0.8, requestedMs: 400, want: 500 * time.Millisecond},
+		{name: "granted up to the longest", progress: 0.8, requestedMs: 900, want: time.Second},
+		{name: "granted at the minimum progress", progress: 0.75, requestedMs: 100, granted: 1, want: 125 * time.Millisecond},
+		{name: "denied before the minimum progress", progress: 0.5, requestedMs: 100, wantDeny: true},
+		{name: "denied past the extensions allowed", progress: 0.9, requestedMs: 100, granted: 2, wantDeny: true},
+		{name: "denied longer than the longest", progress: 0.9, requestedMs: 1001, wantDeny: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &cities.ExtensionRequest{Progress: tt.progress, RequestedMs: tt.requestedMs}
+			timeout, deny := p.decide(req, tt.granted)
+			if tt.wantDeny {
+				if deny == nil {
+					t.Fatalf("granted %s, want a denial", timeout)
+				}
+				return
+			}
+			if deny != nil || timeout != tt.want {
+				t.Fatalf("got %s, %v, want %s", timeout, deny, tt.want)
+			}
+		})
+	}
+}
+
+// scriptedStream answers Recv with resps, then io.EOF.
+type scriptedStream struct {
+	grpc.ClientStream
+	resps []*cities.CityStream
+}
+
+func (s *scriptedStream) Recv() (*cities.CityStream, error) {
+	if len(s.resps) == 0 {
+		return nil, io.EOF
+	}
+	resp := s.resps[0]
+	s.resps = s.resps[1:]
+	return resp, nil
+}
+
+func TestListStreamExtension(t *testing.T) {
+	city := func(name, token string) *cities.CityStream {
+		return &cities.CityStream{City: &cities.City{Name: name}, ResumeToken: token}
+	}
+	extension := func(progress float64, token string) *cities.CityStream {
+		return &cities.CityStream{
+			Extension:   &cities.ExtensionRequest{Progress: progress, RequestedMs: 400, ResumeToken: token},
+			ResumeToken: token,
+		}
+	}
+
+	tests := []struct {
+		name string
+		// calls are the responses of each call of ListStream.
+		calls      [][]*cities.CityStream
+		wantCities []string
+		// wantTokens are the resume tokens the calls were made with.
+		wantTokens []string
+	}{
+		{
+			name: "accepted",
+			calls: [][]*cities.CityStream{
+				{city("Bandung", "1"), extension(0.8, "1"), city("never sent", "2")},
+				{city("Bogor", "2")},
+			},
+			wantCities: []string{"Bandung", "Bogor"},
+			wantTokens: []string{"", "1"},
+		},
+		{
+			name: "denied for too little progress",
+			calls: [][]*cities.CityStream{
+				{city("Bandung", "1"), extension(0.5, "1"), city("Bogor", "2")},
+			},
+			wantCities: []string{"Bandung", "Bogor"},
+			wantTokens: []string{""},
+		},
+		{
+			name: "denied past the extensions allowed",
+			calls: [][]*cities.CityStream{
+				{city("Bandung", "1"), extension(0.8, "1")},
+				{city("Bogor", "2"), extension(0.9, "2"), city("Cirebon", "3")},
+			},
+			wantCities: []string{"Bandung", "Bogor", "Cirebon"},
+			wantTokens: []string{"", "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := loadTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := newTestClient(t, clock.System{})
+			defer c.Close()
+			c.tokens = tokens
+			c.extension = &extensionPolicy{minProgress: 0.75, max: 1, longest: time.Second}
+
+			var gotTokens []string
+			var callCtxs []context.Context
+			c.cities = stubCities{listStream: func(ctx context.Context, in *cities.ListStreamRequest) (cities.CitiesService_ListStreamClient, error) {
+				n := len(gotTokens)
+				if n == len(tt.calls) {
+					t.Fatalf("call %d of ListStream, want %d", n+1, len(tt.calls))
+				}
+				if !in.GetAllowExtension() {
+					t.Error("ListStream does not allow extensions")
+				}
+				gotTokens = append(gotTokens, in.GetResumeToken())
+				callCtxs = append(callCtxs, ctx)
+				return &scriptedStream{resps: tt.calls[n]}, nil
+			}}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			var got []string
+			err = c.ListStream(ctx, 1, func(city *cities.City) error {
+				got = append(got, city.GetName())
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ListStream: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.wantCities) {
+				t.Errorf("got cities %v, want %v", got, tt.wantCities)
+			}
+			if !reflect.DeepEqual(gotTokens, tt.wantTokens) {
+				t.Errorf("called with tokens %q, want %q", gotTokens, tt.wantTokens)
+			}
+
+			// A resumed call runs under the granted timeout, not the minute
+			// of ctx, and the call it replaces is cancelled.
+			for i, callCtx := range callCtxs[1:] {
+				dl, ok := callCtx.Deadline()
+				if left := time.Until(dl); !ok || left > 500*time.Millisecond {
+					t.Errorf("resumed call %d has %s left, want at most the 500ms granted", i+1, left)
+				}
+				if callCtxs[i].Err() == nil {
+					t.Errorf("call %d still running after the extension", i)
+				}
+			}
+		})
+	}
+}
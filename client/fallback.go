@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// List fetches the cities over gRPC. When the gRPC endpoint is unreachable,
+// that is the call fails within fallbackWindow with an error that
+// grpcerr.Unreachable reports, the call is repeated against the REST
+// gateway with whatever is left of ctx. A server shedding the call with a
+// RetryInfo is not unreachable, its gateway would shed the call too. When
+// the REST call fails as well, the cached result of the last successful
+// List is returned with a *staleError.
+func (c *Client) List(ctx context.Context) ([]*cities.City, error) {
+	list, err := c.list(ctx)
+	if err == nil && c.validate {
+		v := newCityValidator("List", c.messages)
+		for _, city := range list {
+			v.Check(city)
+		}
+	}
+	if c.cache == nil {
+		return list, err
+	}
+
+	if err == nil {
+		if err := c.cache.Save(list, c.clock.Now()); err != nil {
+			fmt.Fprintf(c.messages, "cannot save list cache: %s\n", err)
+		}
+		return list, nil
+	}
+
+	// An error of the call itself, a bad or cancelled request, is not
+	// papered over with the cache.
+	if grpcerr.CallerFault(err) {
+		return nil, err
+	}
+	now := c.clock.Now()
+	cached, ok := c.cache.Load(now)
+	if !ok {
+		return nil, err
+	}
+	return cached.Cities, &staleError{age: now.Sub(cached.SavedAt), err: err}
+}
+
+func (c *Client) list(ctx context.Context) ([]*cities.City, error) {
+	start := c.clock.Now()
+	list, err := c.cities.List(ctx, &cities.EmptyMessage{})
+	if err == nil {
+		return list.GetCity(), nil
+	}
+
+	if c.restURL == "" || !grpcerr.Unreachable(err) || c.clock.Now().Sub(start) > c.fallbackWindow {
+		return nil, err
+	}
+
+	fmt.Fprintf(c.messages, "gRPC unavailable (%s), falling back to %s\n", status.Convert(err).Message(), c.restURL)
+	c.events.OnRetry("/cities.CitiesService/List", "the REST gateway", err)
+	return c.restList(ctx)
+}
+
+func (c *Client) restList(ctx context.Context) ([]*cities.City, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.restURL+"/v1/cities", nil)
+	if err != nil {
+		return nil, err
+	}
+	// The REST server takes the simulator preset from a header.
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if v := md.Get("simulator"); len(v) > 0 {
+		req.Header.Set("Simulator", v[0])
+	}
+	id := newRequestID()
+	ctxmeta.ToHTTP(ctxmeta.WithRequestID(ctx, id), req.Header)
+	fmt.Fprintf(c.messages, "request-id %s GET %s/v1/cities\n", id, c.restURL)
+
+	resp, err := c.rest.Do(req)
+	if err != nil {
+		if err := grpcerr.FromContext(ctx); err != nil {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Unavailable, "rest fallback: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if err := grpcerr.FromContext(ctx); err != nil {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Unavailable, "rest fallback: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// The gateway answers with a grpcerr.HTTPError, its code tells a
+		// bad request from a server in trouble where the HTTP status is
+		// ambiguous.
+		var body grpcerr.HTTPError
+		if err := json.Unmarshal(data, &body); err != nil || body.Code == "" {
+			return nil, status.Errorf(codes.Unknown, "rest fallback: %s", resp.Status)
+		}
+		return nil, body.Err()
+	}
+	var list cities.Cities
+	if err := protojson.Unmarshal(data, &list); err != nil {
+		return nil, status.Errorf(codes.Internal, "rest fallback: %v", err)
+	}
+	return list.City, nil
+}
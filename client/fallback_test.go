@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-cancel/clock/clocktest"
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestRestFallbackError fails the REST fallback with an error of the
+// gateway: the code of its body comes back, and only a server in trouble
+// is papered over with the cache.
+func TestRestFallbackError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  codes.Code
+		wantStale bool
+	}{
+		{name: "not found", err: status.Error(codes.NotFound, "no such route"), wantCode: codes.NotFound},
+		{name: "invalid argument", err: status.Error(codes.InvalidArgument, "bad simulator"), wantCode: codes.InvalidArgument},
+		{name: "unavailable", err: status.Error(codes.Unavailable, "maintenance"), wantCode: codes.Unavailable, wantStale: true},
+		{name: "deadline exceeded", err: status.Error(codes.DeadlineExceeded, "deadline is exceeded"), wantCode: codes.DeadlineExceeded, wantStale: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				grpcerr.WriteJSON(w, tt.err, "req-1")
+			}))
+			defer rest.Close()
+
+			clk := clocktest.NewManual(time.Unix(0, 0))
+			c := newTestClient(t, clk)
+			defer c.Close()
+			c.restURL, c.fallbackWindow = rest.URL, time.Second
+			c.cache = &listCache{path: filepath.Join(t.TempDir(), "cache.json"), ttl: time.Minute, messages: io.Discard}
+			if err := c.cache.Save([]*cities.City{{Name: "Bandung"}}, clk.Now()); err != nil {
+				t.Fatal(err)
+			}
+			c.cities = stubCities{list: func() (*cities.Cities, error) {
+				return nil, status.Error(codes.Unavailable, "connection refused")
+			}}
+
+			list, err := c.List(context.Background())
+			var stale *staleError
+			if errors.As(err, &stale) != tt.wantStale {
+				t.Fatalf("List: got %v, %v, stale result %v", list, err, tt.wantStale)
+			}
+			if tt.wantStale {
+				err = stale.err
+			}
+			if st := status.Convert(err); st.Code() != tt.wantCode || st.Message() != status.Convert(tt.err).Message() {
+				t.Fatalf("List: got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestListShedNoFallback sheds the gRPC call with a RetryInfo: the server
+// is up, so List does not repeat the call against the REST gateway.
+func TestListShedNoFallback(t *testing.T) {
+	var restCalls int
+	rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		restCalls++
+		w.Write([]byte(`{"city":[]}`))
+	}))
+	defer rest.Close()
+
+	clk := clocktest.NewManual(time.Unix(0, 0))
+	c := newTestClient(t, clk)
+	defer c.Close()
+	c.restURL, c.fallbackWindow = rest.URL, time.Second
+	shed := grpcerr.RetryError(codes.Unavailable, "server is overloaded", time.Second)
+	c.cities = stubCities{list: func() (*cities.Cities, error) {
+		return nil, shed
+	}}
+
+	if _, err := c.List(context.Background()); status.Code(err) != codes.Unavailable {
+		t.Fatalf("List: got %v, want %v", err, shed)
+	}
+	if restCalls != 0 {
+		t.Fatalf("REST gateway called %d times, want none", restCalls)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"go-cancel/ctxmeta"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+func processingTime(trailer metadata.MD) string {
+	if v := trailer.Get("processing-time"); len(v) > 0 {
+		return v[0]
+	}
+	return "unknown"
+}
+
+// requestIDUnary sends a fresh request-id with every call and prints it to
+// w next to the processing-time trailer, so the line can be matched with
+// the server log.
+func requestIDUnary(w io.Writer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		id := newRequestID()
+		ctx = ctxmeta.ToOutgoing(ctxmeta.WithRequestID(ctx, id))
+
+		var trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+		fmt.Fprintf(w, "request-id %s %s server processing time %s\n", id, method, processingTime(trailer))
+
+		return err
+	}
+}
+
+func requestIDStream(w io.Writer) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		id := newRequestID()
+		ctx = ctxmeta.ToOutgoing(ctxmeta.WithRequestID(ctx, id))
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			fmt.Fprintf(w, "request-id %s %s failed to start\n", id, method)
+			return nil, err
+		}
+
+		return &requestIDClientStream{ClientStream: cs, w: w, id: id, method: method}, nil
+	}
+}
+
+// requestIDClientStream prints the trailer once the stream has ended.
+type requestIDClientStream struct {
+	grpc.ClientStream
+	w      io.Writer
+	id     string
+	method string
+	done   bool
+}
+
+func (s *requestIDClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && !s.done {
+		s.done = true
+		trailer := s.Trailer()
+		fmt.Fprintf(s.w, "request-id %s %s server processing time %s\n", s.id, s.method, processingTime(trailer))
+		if sent := trailer.Get("items-sent"); len(sent) > 0 {
+			fmt.Fprintf(s.w, "request-id %s %s stopped early, server sent %s items\n", s.id, s.method, sent[0])
+		}
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"golang.org/x/net/context"
+)
+
+// leakDetector hands out cancellable contexts and reports the ones whose
+// cancel func was dropped without being called. Such a context keeps its
+// timer, and its place among the children of its parent, until its
+// deadline: a client doing it on every call grows with every call.
+//
+// go vet's lostcancel check finds the cancel funcs dropped in plain sight,
+// ctx, _ := context.WithTimeout(...); the detector finds the ones lost at
+// run time, through a branch, a map or a goroutine that never calls them.
+type leakDetector struct {
+	mu     sync.Mutex
+	leaked map[string]int
+}
+
+func newLeakDetector() *leakDetector {
+	return &leakDetector{leaked: make(map[string]int)}
+}
+
+// cancelTracker is reachable from the cancel func alone, its finalizer runs
+// once the func is garbage, called or not.
+type cancelTracker struct {
+	site   string
+	called atomic.Bool
+}
+
+// WithTimeout is context.WithTimeout, with the cancel func watched.
+func (d *leakDetector) WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+
+	t := &cancelTracker{site: "unknown"}
+	if _, file, line, ok := runtime.Caller(1); ok {
+		t.site = fmt.Sprintf("%s:%d", file, line)
+	}
+	runtime.SetFinalizer(t, func(t *cancelTracker) {
+		if !t.called.Load() {
+			d.mu.Lock()
+			d.leaked[t.site]++
+			d.mu.Unlock()
+		}
+	})
+
+	return ctx, func() {
+		t.called.Store(true)
+		cancel()
+	}
+}
+
+// Check collects the garbage so the finalizers of the dropped cancel funcs
+// run, and returns the leaks found so far by call site.
+func (d *leakDetector) Check() map[string]int {
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make(map[string]int, len(d.leaked))
+	for site, n := range d.leaked {
+		out[site] = n
+	}
+	return out
+}
+
+// runLeakDemo makes n GetServerInfo calls the way a bad client does, never
+// cancelling their contexts, then n more the right way, and reports what
+// the detector found after each round.
+func runLeakDemo(ctx context.Context, client *Client, n int) {
+	detector := newLeakDetector()
+
+	fmt.Printf("%d calls forgetting defer cancel()\n", n)
+	for i := 0; i < n; i++ {
+		// The timeout is long, the leaked contexts outlive the demo.
+		callCtx, cancel := detector.WithTimeout(ctx, time.Hour)
+		if _, err := client.cities.GetServerInfo(callCtx, &cities.EmptyMessage{}); err != nil {
+			fmt.Printf("call %d failed: %s\n", i, err)
+			// Only a failed call is cleaned up.
+			cancel()
+		}
+	}
+	printLeaks(detector.Check())
+
+	detector = newLeakDetector()
+	fmt.Printf("%d calls with defer cancel()\n", n)
+	for i := 0; i < n; i++ {
+		func() {
+			callCtx, cancel := detector.WithTimeout(ctx, time.Hour)
+			defer cancel()
+			if _, err := client.cities.GetServerInfo(callCtx, &cities.EmptyMessage{}); err != nil {
+				fmt.Printf("call %d failed: %s\n", i, err)
+			}
+		}()
+	}
+	printLeaks(detector.Check())
+}
+
+func printLeaks(leaks map[string]int) {
+	if len(leaks) == 0 {
+		fmt.Println("no leaked context")
+		return
+	}
+
+	sites := make([]string, 0, len(leaks))
+	for site := range leaks {
+		sites = append(sites, site)
+	}
+	sort.Strings(sites)
+	for _, site := range sites {
+		fmt.Printf("leaked %d cancellable contexts created at %s, their cancel func was never called\n", leaks[site], site)
+	}
+}
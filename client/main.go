@@ -1,61 +1,374 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"go-cancel/callevents"
+	"go-cancel/config"
+	"go-cancel/ctxmeta"
+	"go-cancel/deadline"
 	"go-cancel/pb/cities"
 	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
 	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
 func main() {
+	batchSize := flag.Uint("batch", 0, "number of cities per stream message, 0 streams them one by one")
+	enrich := flag.Uint("enrich", 0, "have the stream look up city details this many cities ahead, 0 disables it")
+	calls := flag.Int("calls", 0, "run that many List and Stats calls concurrently instead of the stream, cancelling the List calls after a second")
+	ws := flag.String("ws", "", "tunnel gRPC through the WebSocket endpoint of the REST server at this address, e.g. localhost:8099")
+	list := flag.Bool("list", false, "call List once instead of the stream")
+	pages := flag.Bool("pages", false, "page through ListPage instead of the stream, sizing the pages to list every city within the timeout")
+	leakCancel := flag.Int("leak-cancel", 0, "make that many calls forgetting to cancel their context, then as many cancelling it, and report the leaks a runtime detector finds")
+	cacheFile := flag.String("cache", "", "file keeping the last successful List, served when the server cannot be reached; empty disables it")
+	cacheTTL := flag.Duration("cache-ttl", 10*time.Minute, "how old a cached List may be to be served")
+	validate := flag.Bool("validate", true, "check received cities and report anomalies")
+	compress := flag.Bool("gzip", false, "ask the server to gzip its responses and the export")
+	export := flag.Bool("export", false, "export the stored cities instead of the stream, reporting whether the export is complete")
+	tokenFile := flag.String("tokens", ".resume-tokens.json", "file keeping the resume tokens of interrupted streams")
+	extensions := flag.Int("extensions", 0, "how many times the stream may be extended when the server asks for more time, 0 leaves the protocol off")
+	extensionProgress := flag.Float64("extension-min-progress", 0.8, "share of the stream the server must have sent for an extension to be granted")
+	extensionMax := flag.Duration("extension-max", time.Minute, "longest extension granted")
+	encrypt := flag.Bool("encrypt", false, "have the server encrypt the cities with a key agreed for every call")
+	simulator := flag.String("simulator", "", "scenario the server simulates for these calls: fast, slow-backend, flaky or huge-dataset")
+	slowStartGap := flag.Duration("slow-start", 0, "wait that long before reading the second message of a resumed stream, halving the wait after every message; 0 reads resumed streams at full speed")
+	session := flag.String("session", "", "session-id sent with the calls")
+	locale := flag.String("locale", "", "locale sent with the calls")
+	token := flag.String("token", "", "bearer token sent with the calls, carrying the roles the server checks")
+	printEvents := flag.Bool("events", false, "print the start, end, retries and resumes of the calls to stderr")
+	partition := flag.String("partition", "", "partition the network to the server on a schedule, e.g. blackhole@1s,heal@5s, delay=300ms@0s or reset@2s")
+	netRPC := flag.String("netrpc", "", "call List once over the net/rpc binding of the REST server at this address, e.g. localhost:8099")
+	output := flag.String("output", "text", "how the stream prints the cities: text, table, json or csv; everything else goes to stderr but for text")
+	dnsRefresh := flag.Duration("dns-refresh", 0, "resolve the host of the target again about that often and balance the calls over every address it lists, 0 resolves it once")
+	cfg := config.DefaultClient()
+	settings := config.Bind(flag.CommandLine, "CITIES_CONFIG", &cfg)
+	flag.Parse()
+	if err := settings.Load(); err != nil {
+		fmt.Printf("invalid settings: %s", err)
+		return
+	}
+	out, err := newCityWriter(*output, os.Stdout)
+	if err != nil {
+		fmt.Printf("invalid -output: %s", err)
+		return
+	}
+	// Only the cities go to stdout then, the output stays parseable.
+	var messages io.Writer = os.Stdout
+	if *output != "text" {
+		messages = os.Stderr
+	}
+
 	ctx := context.Background()
 	// ctx, cancel := context.WithDeadline(ctx, time.Now().Add(3*time.Second))
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
 	defer cancel()
+	// A resumed stream gets these back from its resume token, they only
+	// need to be given when a stream starts.
+	if *locale != "" {
+		ctx = ctxmeta.WithLocale(ctx, *locale)
+	}
+	for key, value := range map[string]string{"simulator": *simulator, "session-id": *session} {
+		if value != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+		}
+	}
+
+	if *netRPC != "" {
+		callNetRPC(ctx, *netRPC)
+		return
+	}
+
+	tokens, err := loadTokenStore(*tokenFile)
+	if err != nil {
+		fmt.Fprintf(messages, "cannot load resume tokens: %s", err)
+		return
+	}
+
+	target := cfg.Target
+	var events callevents.Events = callevents.Nop{}
+	if *printEvents {
+		events = logEvents{}
+	}
+	unary := []grpc.UnaryClientInterceptor{callevents.UnaryClientInterceptor(events), requestIDUnary(messages)}
+	stream := []grpc.StreamClientInterceptor{callevents.StreamClientInterceptor(events), requestIDStream(messages)}
+	if *encrypt {
+		unary = append(unary, encryptUnary)
+		stream = append(stream, encryptStream)
+	}
+	tc, err := clientTLS(cfg)
+	if err != nil {
+		fmt.Fprintf(messages, "cannot set up TLS: %s", err)
+		return
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCredentials(tc)),
+		grpc.WithChainUnaryInterceptor(unary...),
+		grpc.WithChainStreamInterceptor(stream...),
+	}
+	var dial func(context.Context, string) (net.Conn, error)
+	if *ws != "" {
+		target = *ws
+		dial = dialWebSocket
+	}
+	if *partition != "" {
+		steps, err := parsePartitionSchedule(*partition)
+		if err != nil {
+			fmt.Fprintf(messages, "invalid -partition: %s", err)
+			return
+		}
+		partitions := newPartitionDialer(dial)
+		go partitions.run(steps, messages)
+		dial = partitions.Dial
+	}
+	if dial != nil {
+		dialOpts = append(dialOpts, grpc.WithContextDialer(dial))
+	}
+	if cfg.Keepalive > 0 {
+		timeout := cfg.KeepaliveTimeout
+		if timeout == 0 {
+			timeout = cfg.Keepalive
+		}
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.Keepalive,
+			Timeout:             timeout,
+			PermitWithoutStream: cfg.KeepaliveWithoutStream,
+		}))
+	}
+	if *compress {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+	if *token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(bearerToken(*token)))
+	}
+	var discovery *dnsDiscovery
+	// A unix socket has no host to resolve.
+	if *dnsRefresh > 0 && *ws == "" && !strings.HasPrefix(target, "unix:") {
+		discovery = newDNSDiscovery(*dnsRefresh)
+		target = discoveryScheme + ":///" + target
+		dialOpts = append(dialOpts,
+			grpc.WithResolvers(discovery),
+			grpc.WithDefaultServiceConfig(`{"loadBalancingConfig":[{"round_robin":{}}]}`),
+		)
+	}
 
 	var conn *grpc.ClientConn
-	conn, err := grpc.Dial(":9099", grpc.WithInsecure())
+	conn, err = grpc.Dial(target, dialOpts...)
 	if err != nil {
-		fmt.Printf("did not connect: %s", err)
+		fmt.Fprintf(messages, "did not connect: %s", err)
 		return
 	}
-	defer conn.Close()
 
-	city := cities.NewCitiesServiceClient(conn)
+	client := newClient(conn, tokens, cfg.Drain)
+	client.restURL = cfg.RESTURL
+	if tc != nil {
+		// The server name of the gRPC target would not do for the gateway.
+		rest := tc.Clone()
+		rest.ServerName = ""
+		client.rest = &http.Client{Transport: &http.Transport{TLSClientConfig: rest}}
+	}
+	client.events = events
+	client.fallbackWindow = cfg.Fallback
+	client.validate = *validate
+	client.messages = messages
+	client.enrich = uint32(*enrich)
+	if *slowStartGap > 0 {
+		client.slowStart = &slowStart{initial: *slowStartGap}
+	}
+	if *extensions > 0 {
+		client.extension = &extensionPolicy{minProgress: *extensionProgress, max: *extensions, longest: *extensionMax}
+	}
+	if *cacheFile != "" {
+		client.cache = &listCache{path: *cacheFile, ttl: *cacheTTL, messages: messages}
+	}
+	// Printed once the connection closed, with the refresh loop stopped.
+	if discovery != nil {
+		defer fmt.Fprintln(messages, discovery)
+	}
+	defer client.Close()
 
-	err = callStream(ctx, city)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		client.Close()
+	}()
+
+	if *list {
+		callList(ctx, client)
+		return
+	}
+
+	if *pages {
+		callPages(ctx, client)
+		return
+	}
+
+	if *export {
+		callExport(ctx, client, *compress)
+		return
+	}
+
+	if *calls > 0 {
+		runCalls(ctx, client, *calls)
+		return
+	}
+
+	if *leakCancel > 0 {
+		runLeakDemo(ctx, client, *leakCancel)
+		return
+	}
+
+	err = callStream(ctx, client, uint32(*batchSize), out)
 	if st, ok := status.FromError(err); err != nil && ok {
 		err = fmt.Errorf(st.Message())
 	}
 
 	if err != nil {
-		fmt.Printf("Error when calling grpc service: %s", err)
+		fmt.Fprintf(messages, "Error when calling grpc service: %s", err)
 		return
 	}
 }
 
-func callStream(ctx context.Context, city cities.CitiesServiceClient) error {
-	stream, err := city.ListStream(ctx, &cities.EmptyMessage{})
-	if err != nil {
-		return err
+// callStream writes every city to out as it arrives, then prints which
+// limit ended the stream to the messages of client.
+func callStream(ctx context.Context, client *Client, batchSize uint32, out cityWriter) error {
+	start := time.Now()
+	err := client.ListStream(ctx, batchSize, func(city *cities.City) error {
+		return out.Write(city, time.Since(start), budgetLeft(ctx))
+	})
+	if closeErr := out.Close(err); closeErr != nil {
+		fmt.Fprintf(client.messages, "cannot write the output: %s\n", closeErr)
+	}
+	fmt.Fprintln(client.messages, streamEnd(ctx, err, time.Since(start)))
+	if client.slowStart != nil {
+		fmt.Fprintln(client.messages, client.slowStart)
 	}
 
-	for {
-		resp, err := stream.Recv()
-		if err == io.EOF {
-			fmt.Println("end stream")
-			break
-		}
-		if err != nil {
-			return err
+	if err == errDrained {
+		fmt.Fprintln(client.messages, "stream drained, run again to resume")
+		return nil
+	}
+	return err
+}
+
+func runCalls(ctx context.Context, client *Client, n int) {
+	runner := newRunner(ctx)
+	for i := 0; i < n; i++ {
+		runner.Go(fmt.Sprintf("List#%d", i), []string{"list"}, func(ctx context.Context) (interface{}, error) {
+			return client.cities.List(ctx, &cities.EmptyMessage{})
+		})
+		runner.Go(fmt.Sprintf("Stats#%d", i), []string{"stats"}, func(ctx context.Context) (interface{}, error) {
+			return client.cities.Stats(ctx, &cities.StatsRequest{})
+		})
+	}
+
+	time.AfterFunc(time.Second, func() {
+		fmt.Printf("cancelled %d List calls\n", runner.CancelTag("list"))
+	})
+
+	for _, res := range runner.Wait() {
+		if res.Err != nil {
+			fmt.Printf("%s failed after %s: %s\n", res.Name, res.Elapsed, status.Convert(res.Err).Message())
+			continue
 		}
-		fmt.Printf("Resp : %v", resp.GetCity())
+		fmt.Printf("%s succeeded after %s: %v\n", res.Name, res.Elapsed, res.Value)
+	}
+}
+
+func budgetLeft(ctx context.Context) string {
+	remaining, ok := deadline.RemainingBudget(ctx)
+	if !ok {
+		return "no deadline"
+	}
+	return fmt.Sprintf("budget left %s", remaining.Round(time.Millisecond))
+}
+
+// streamEnd explains what stopped the stream. A DeadlineExceeded while the
+// client still had time came from a deadline on the server side.
+func streamEnd(ctx context.Context, err error, elapsed time.Duration) string {
+	elapsed = elapsed.Round(time.Millisecond)
+
+	switch {
+	case err == nil:
+		return fmt.Sprintf("stream completed after %s, no limit was hit", elapsed)
+	case err == errDrained:
+		return fmt.Sprintf("stream stopped by the interrupt after %s", elapsed)
+	case ctx.Err() == context.DeadlineExceeded:
+		return fmt.Sprintf("stream stopped by the client deadline after %s", elapsed)
+	case ctx.Err() == context.Canceled:
+		return fmt.Sprintf("stream cancelled by the client after %s", elapsed)
+	}
+
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return fmt.Sprintf("stream stopped by a server deadline after %s, with %s", elapsed, budgetLeft(ctx))
+	case codes.Canceled:
+		return fmt.Sprintf("stream cancelled by the server after %s", elapsed)
+	default:
+		return fmt.Sprintf("stream failed with %s after %s", status.Code(err), elapsed)
+	}
+}
+
+func callExport(ctx context.Context, client *Client, compress bool) {
+	n := 0
+	err := client.Export(ctx, compress, func(city *cities.City) error {
+		n++
+		fmt.Printf("Resp : %v\n", city)
+		return nil
+	})
+	if err == errTruncated {
+		fmt.Fprintf(client.messages, "Export truncated after %d cities\n", n)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(client.messages, "Error when calling grpc service: %s", status.Convert(err).Message())
+		return
+	}
+	fmt.Fprintf(client.messages, "Export complete, %d cities\n", n)
+}
+
+func callPages(ctx context.Context, client *Client) {
+	list, err := client.ListPages(ctx)
+	var partial *partialError
+	if errors.As(err, &partial) {
+		fmt.Fprintf(client.messages, "Partial result, %s\n", partial)
+	} else if err != nil {
+		fmt.Fprintf(client.messages, "Error when calling grpc service: %s", status.Convert(err).Message())
+		return
+	}
+
+	for _, city := range list {
+		fmt.Printf("Resp : %v", city)
 		println()
 	}
+}
 
-	return nil
+func callList(ctx context.Context, client *Client) {
+	list, err := client.List(ctx)
+	var stale *staleError
+	if errors.As(err, &stale) {
+		fmt.Fprintf(client.messages, "Stale result, %s\n", stale)
+	} else if err != nil {
+		fmt.Fprintf(client.messages, "Error when calling grpc service: %s", status.Convert(err).Message())
+		return
+	}
+
+	for _, city := range list {
+		fmt.Printf("Resp : %v", city)
+		println()
+	}
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/rpc"
+	"time"
+
+	"go-cancel/citiesrpc"
+	"go-cancel/pb/cities"
+
+	"golang.org/x/net/context"
+)
+
+// callNetRPC calls List over the net/rpc binding of the REST server at addr.
+// net/rpc cannot cancel a call: when ctx ends first the client stops
+// waiting, but the server only stops at the timeout sent with the call.
+func callNetRPC(ctx context.Context, addr string) {
+	c, err := rpc.DialHTTPPath("tcp", addr, citiesrpc.Path)
+	if err != nil {
+		fmt.Printf("did not connect: %s", err)
+		return
+	}
+	defer c.Close()
+
+	start := time.Now()
+	args := &citiesrpc.ListArgs{Call: citiesrpc.CallFrom(ctx)}
+	var reply cities.Cities
+	call := c.Go(citiesrpc.ServiceName+".List", args, &reply, nil)
+
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		fmt.Printf("stopped waiting after %s: %s; the server keeps working until the %s sent with the call\n",
+			time.Since(start).Round(time.Millisecond), ctx.Err(), args.Timeout.Round(time.Millisecond))
+		return
+	}
+
+	if call.Error != nil {
+		fmt.Printf("Error when calling net/rpc service after %s: %s", time.Since(start).Round(time.Millisecond), call.Error)
+		return
+	}
+	for _, city := range reply.GetCity() {
+		fmt.Printf("Resp : %v\n", city)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/status"
+)
+
+// cityWriter prints the cities of a stream as they arrive, flushing every
+// one. Close ends the output with the error that ended the stream: a stream
+// cut short is marked truncated, so a partial output is never mistaken for
+// a complete one.
+type cityWriter interface {
+	Write(city *cities.City, elapsed time.Duration, budget string) error
+	Close(err error) error
+}
+
+var outputFormats = map[string]func(io.Writer) cityWriter{
+	"text":  func(w io.Writer) cityWriter { return &textWriter{w: w} },
+	"table": func(w io.Writer) cityWriter { return &tableWriter{w: bufio.NewWriter(w)} },
+	"json":  func(w io.Writer) cityWriter { return &jsonWriter{w: bufio.NewWriter(w)} },
+	"csv":   func(w io.Writer) cityWriter { return &csvWriter{out: w, w: csv.NewWriter(w)} },
+}
+
+func newCityWriter(format string, w io.Writer) (cityWriter, error) {
+	newWriter, ok := outputFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown output %q, want text, table, json or csv", format)
+	}
+	return newWriter(w), nil
+}
+
+// truncation is why the stream stopped early, empty when it completed.
+func truncation(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case err == errDrained:
+		return "interrupted"
+	}
+	return status.Convert(err).Message()
+}
+
+// textWriter is the original output, the stream summary says how it ended.
+type textWriter struct {
+	w io.Writer
+}
+
+func (t *textWriter) Write(city *cities.City, elapsed time.Duration, budget string) error {
+	_, err := fmt.Fprintf(t.w, "Resp : %v  [elapsed %s, %s]\n", city, elapsed.Round(time.Millisecond), budget)
+	return err
+}
+
+func (t *textWriter) Close(err error) error {
+	return nil
+}
+
+type tableWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+const tableRow = "%-28s %-16s %-12s %10s %10s\n"
+
+func (t *tableWriter) Write(city *cities.City, elapsed time.Duration, budget string) error {
+	if t.n == 0 {
+		fmt.Fprintf(t.w, tableRow, "ID", "NAME", "REGION", "POPULATION", "ELAPSED")
+	}
+	t.n++
+	population := ""
+	if d := city.GetDetail(); d != nil {
+		population = strconv.FormatUint(uint64(d.GetPopulation()), 10)
+	}
+	fmt.Fprintf(t.w, tableRow, city.GetId(), city.GetName(), city.GetDetail().GetRegion(), population, elapsed.Round(time.Millisecond))
+	return t.w.Flush()
+}
+
+func (t *tableWriter) Close(err error) error {
+	if reason := truncation(err); reason != "" {
+		fmt.Fprintf(t.w, "(%d cities, truncated: %s)\n", t.n, reason)
+	} else {
+		fmt.Fprintf(t.w, "(%d cities)\n", t.n)
+	}
+	return t.w.Flush()
+}
+
+// jsonWriter streams one JSON document, the cities array first and whether
+// it is complete last:
+//
+//	{"cities":[{"id":"…","name":"…"},…],"complete":false,"error":"…"}
+type jsonWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+type jsonCity struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Region     string `json:"region,omitempty"`
+	Population uint32 `json:"population,omitempty"`
+}
+
+func (j *jsonWriter) Write(city *cities.City, elapsed time.Duration, budget string) error {
+	if j.n == 0 {
+		j.w.WriteString(`{"cities":[`)
+	} else {
+		j.w.WriteString(",")
+	}
+	j.n++
+	b, err := json.Marshal(jsonCity{
+		ID:         city.GetId(),
+		Name:       city.GetName(),
+		Region:     city.GetDetail().GetRegion(),
+		Population: city.GetDetail().GetPopulation(),
+	})
+	if err != nil {
+		return err
+	}
+	j.w.WriteString("\n")
+	j.w.Write(b)
+	return j.w.Flush()
+}
+
+func (j *jsonWriter) Close(err error) error {
+	if j.n == 0 {
+		j.w.WriteString(`{"cities":[`)
+	}
+	reason := truncation(err)
+	tail := struct {
+		Complete bool   `json:"complete"`
+		Error    string `json:"error,omitempty"`
+	}{reason == "", reason}
+	b, _ := json.Marshal(tail)
+	// The tail object is spliced into the document, without its brace.
+	fmt.Fprintf(j.w, "\n],%s\n", b[1:])
+	return j.w.Flush()
+}
+
+// csvWriter writes a header and a row per city. A truncated stream ends
+// with a "# truncated: reason" line, readers skip it with Comment set to
+// '#'.
+type csvWriter struct {
+	out io.Writer
+	w   *csv.Writer
+	n   int
+}
+
+func (c *csvWriter) Write(city *cities.City, elapsed time.Duration, budget string) error {
+	if c.n == 0 {
+		c.w.Write([]string{"id", "name", "region", "population"})
+	}
+	c.n++
+	population := ""
+	if d := city.GetDetail(); d != nil {
+		population = strconv.FormatUint(uint64(d.GetPopulation()), 10)
+	}
+	c.w.Write([]string{city.GetId(), city.GetName(), city.GetDetail().GetRegion(), population})
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close(err error) error {
+	if c.n == 0 {
+		c.w.Write([]string{"id", "name", "region", "population"})
+	}
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	if reason := truncation(err); reason != "" {
+		// Written past the csv.Writer, it would quote the line.
+		_, err := fmt.Fprintln(c.out, "# truncated: "+reason)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Page sizes of ListPages. The first page has pageSizeInitial cities, the
+// next ones as many as should take pageTarget at the pace observed so far,
+// and never more than the server sends.
+const (
+	pageSizeInitial = 10
+	pageSizeMax     = 100
+	pageTarget      = time.Second
+	// pageMargin is the share of the time left a page is planned to take,
+	// the rest absorbs a page slower than the ones before it.
+	pageMargin = 0.8
+)
+
+// partialError comes with the cities ListPages got before it had to stop,
+// err is why it stopped.
+type partialError struct {
+	got int
+	err error
+}
+
+func (e *partialError) Error() string {
+	return fmt.Sprintf("stopped after %d cities: %v", e.got, e.err)
+}
+
+func (e *partialError) Unwrap() error {
+	return e.err
+}
+
+// ListPages pages through ListPage until the last page, sizing every page
+// from the time the pages before it took so the whole list fits in the
+// deadline of ctx. When not even a page of one city is expected to make it,
+// it stops before asking for it and returns the cities so far with a
+// *partialError, as it does when a page fails after the first one.
+func (c *Client) ListPages(ctx context.Context) ([]*cities.City, error) {
+	var (
+		list  []*cities.City
+		token string
+		// perCity is how long a city of a page takes, zero until the
+		// first page is back.
+		perCity time.Duration
+	)
+	for {
+		size := pageSizeInitial
+		if perCity > 0 {
+			size = pageSize(ctx, c.clock.Now(), perCity)
+		}
+		if size == 0 {
+			left := "no time"
+			if dl, ok := ctx.Deadline(); ok {
+				left = dl.Sub(c.clock.Now()).Round(time.Millisecond).String()
+			}
+			err := status.Errorf(codes.DeadlineExceeded, "%s left, a city takes about %s", left, perCity.Round(time.Millisecond))
+			return list, &partialError{got: len(list), err: err}
+		}
+
+		start := c.clock.Now()
+		page, err := c.cities.ListPage(ctx, &cities.ListPageRequest{PageSize: uint32(size), PageToken: token})
+		if err != nil {
+			if list == nil {
+				return nil, err
+			}
+			return list, &partialError{got: len(list), err: err}
+		}
+		took := c.clock.Now().Sub(start)
+		list = append(list, page.GetCity()...)
+		fmt.Fprintf(c.messages, "page of %d cities in %s\n", len(page.GetCity()), took.Round(time.Millisecond))
+
+		if page.GetNextPageToken() == "" {
+			return list, nil
+		}
+		token = page.GetNextPageToken()
+
+		// The latest page counts as much as all the ones before it, so
+		// the size follows a server slowing down within a few pages.
+		if n := len(page.GetCity()); n > 0 {
+			observed := took / time.Duration(n)
+			if perCity == 0 {
+				perCity = observed
+			} else {
+				perCity = (perCity + observed) / 2
+			}
+		}
+	}
+}
+
+// pageSize returns how many cities at perCity fit in pageTarget and in
+// pageMargin of the time ctx has left at now, zero when not even one does.
+func pageSize(ctx context.Context, now time.Time, perCity time.Duration) int {
+	budget := pageTarget
+	if dl, ok := ctx.Deadline(); ok {
+		if left := time.Duration(float64(dl.Sub(now)) * pageMargin); left < budget {
+			budget = left
+		}
+	}
+	if budget <= 0 {
+		return 0
+	}
+
+	size := int(budget / perCity)
+	if size > pageSizeMax {
+		size = pageSizeMax
+	}
+	return size
+}
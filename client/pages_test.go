@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"go-cancel/clock/clocktest"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestListPages pages through a server taking perCity for every city of a
+// page, on a manual clock, and checks the page sizes asked for and where
+// the pagination stops.
+func TestListPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		perCity time.Duration
+		budget  time.Duration
+		// fail fails the page at that position of the list.
+		fail        int
+		wantSizes   []uint32
+		wantGot     int
+		wantPartial codes.Code
+	}{
+		{
+			name:      "fast server",
+			count:     250,
+			perCity:   time.Millisecond,
+			budget:    time.Minute,
+			wantSizes: []uint32{10, 100, 100, 100},
+			wantGot:   250,
+		},
+		{
+			name:      "pages sized to the target",
+			count:     50,
+			perCity:   100 * time.Millisecond,
+			budget:    time.Minute,
+			wantSizes: []uint32{10, 10, 10, 10, 10},
+			wantGot:   50,
+		},
+		{
+			name:    "pages shrinking with the deadline",
+			count:   50,
+			perCity: 100 * time.Millisecond,
+			budget:  3 * time.Second,
+			// 1s left after 20 cities fits 8 at 80%, then 0.2s fits
+			// one and 0.1s none.
+			wantSizes:   []uint32{10, 10, 8, 1},
+			wantGot:     29,
+			wantPartial: codes.DeadlineExceeded,
+		},
+		{
+			name:        "failed page",
+			count:       50,
+			perCity:     10 * time.Millisecond,
+			budget:      time.Minute,
+			fail:        10,
+			wantSizes:   []uint32{10, 100},
+			wantGot:     10,
+			wantPartial: codes.Unavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			clk := clocktest.NewManual(now)
+			c := newTestClient(t, clk)
+			defer c.Close()
+
+			var sizes []uint32
+			c.cities = stubCities{listPage: func(in *cities.ListPageRequest) (*cities.CityPage, error) {
+				sizes = append(sizes, in.PageSize)
+				last := 0
+				if in.PageToken != "" {
+					last, _ = strconv.Atoi(in.PageToken)
+				}
+				if tt.fail > 0 && last == tt.fail {
+					return nil, status.Error(codes.Unavailable, "connection refused")
+				}
+				page := &cities.CityPage{}
+				for i := last + 1; i <= tt.count && len(page.City) < int(in.PageSize); i++ {
+					page.City = append(page.City, &cities.City{Id: strconv.Itoa(i)})
+				}
+				clk.Advance(time.Duration(len(page.City)) * tt.perCity)
+				if next := last + len(page.City); next < tt.count {
+					page.NextPageToken = strconv.Itoa(next)
+				}
+				return page, nil
+			}}
+
+			// The deadline is only read, the manual clock is what runs out.
+			ctx, cancel := context.WithDeadline(context.Background(), now.Add(tt.budget))
+			defer cancel()
+			list, err := c.ListPages(ctx)
+
+			if !reflect.DeepEqual(sizes, tt.wantSizes) {
+				t.Errorf("asked for pages of %v, want %v", sizes, tt.wantSizes)
+			}
+			if len(list) != tt.wantGot {
+				t.Errorf("got %d cities, want %d", len(list), tt.wantGot)
+			}
+			var partial *partialError
+			if tt.wantPartial == codes.OK {
+				if err != nil {
+					t.Fatalf("ListPages: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &partial) || partial.got != tt.wantGot || status.Code(partial.err) != tt.wantPartial {
+				t.Fatalf("ListPages: got %v, want a partial result ended by %s", err, tt.wantPartial)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// partitionMode is what a partitionDialer does to the traffic.
+type partitionMode int
+
+const (
+	// partitionPass lets the traffic through.
+	partitionPass partitionMode = iota
+	// partitionBlackhole holds every byte until the partition heals, the way
+	// TCP keeps retransmitting into a dead network: nothing fails, the peer
+	// just goes silent. New connections hang.
+	partitionBlackhole
+	// partitionDelay holds every read and write for the delay.
+	partitionDelay
+	// partitionReset resets the open connections and refuses new ones.
+	partitionReset
+)
+
+var partitionModes = map[string]partitionMode{
+	"heal":      partitionPass,
+	"blackhole": partitionBlackhole,
+	"delay":     partitionDelay,
+	"reset":     partitionReset,
+}
+
+func (m partitionMode) String() string {
+	for name, mode := range partitionModes {
+		if mode == m {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// partitionDialer dials through dial and can partition its connections at
+// any time, to watch how keepalives, deadlines and cancellation cope with a
+// network that silently drops packets or one that resets connections.
+type partitionDialer struct {
+	dial func(ctx context.Context, addr string) (net.Conn, error)
+
+	mu    sync.Mutex
+	mode  partitionMode
+	delay time.Duration
+	// changed is closed and replaced on every change of mode, waking the
+	// reads and writes held by the previous one.
+	changed chan struct{}
+	conns   map[*partitionConn]struct{}
+}
+
+func newPartitionDialer(dial func(ctx context.Context, addr string) (net.Conn, error)) *partitionDialer {
+	if dial == nil {
+		dial = func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", addr)
+		}
+	}
+	return &partitionDialer{dial: dial, changed: make(chan struct{}), conns: make(map[*partitionConn]struct{})}
+}
+
+// Set switches the mode, delay only matters to partitionDelay.
+func (p *partitionDialer) Set(mode partitionMode, delay time.Duration) {
+	p.mu.Lock()
+	p.mode, p.delay = mode, delay
+	close(p.changed)
+	p.changed = make(chan struct{})
+	var reset []*partitionConn
+	if mode == partitionReset {
+		for c := range p.conns {
+			reset = append(reset, c)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, c := range reset {
+		c.reset()
+	}
+}
+
+func (p *partitionDialer) state() (partitionMode, time.Duration, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mode, p.delay, p.changed
+}
+
+// Dial is a grpc.WithContextDialer dialer.
+func (p *partitionDialer) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	for {
+		mode, _, changed := p.state()
+		if mode == partitionReset {
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+		}
+		if mode != partitionBlackhole {
+			break
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	conn, err := p.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &partitionConn{Conn: conn, dialer: p, closed: make(chan struct{})}
+	p.mu.Lock()
+	p.conns[c] = struct{}{}
+	p.mu.Unlock()
+	return c, nil
+}
+
+// partitionConn is a connection of a partitionDialer.
+type partitionConn struct {
+	net.Conn
+	dialer *partitionDialer
+
+	once   sync.Once
+	closed chan struct{}
+}
+
+// hold waits for the traffic to go through, it fails once the connection is
+// closed.
+func (c *partitionConn) hold() error {
+	for {
+		mode, delay, changed := c.dialer.state()
+		switch mode {
+		case partitionPass:
+			return nil
+		case partitionDelay:
+			select {
+			case <-time.After(delay):
+				return nil
+			case <-c.closed:
+				return net.ErrClosed
+			}
+		case partitionReset:
+			return &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+		}
+		select {
+		case <-changed:
+		case <-c.closed:
+			return net.ErrClosed
+		}
+	}
+}
+
+func (c *partitionConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		// The bytes arrived, the partition decides when they are seen.
+		if holdErr := c.hold(); holdErr != nil {
+			return 0, holdErr
+		}
+	}
+	return n, err
+}
+
+func (c *partitionConn) Write(b []byte) (int, error) {
+	if err := c.hold(); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
+
+// reset closes the connection with a TCP reset when it can.
+func (c *partitionConn) reset() {
+	if tcp, ok := c.Conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	c.Close()
+}
+
+func (c *partitionConn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+		c.dialer.mu.Lock()
+		delete(c.dialer.conns, c)
+		c.dialer.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+// partitionStep is one change of a partition schedule.
+type partitionStep struct {
+	at    time.Duration
+	mode  partitionMode
+	delay time.Duration
+}
+
+// parsePartitionSchedule reads steps like "blackhole@1s,heal@5s",
+// "delay=300ms@0s" or "reset@2s", at is counted from the start.
+func parsePartitionSchedule(s string) ([]partitionStep, error) {
+	var steps []partitionStep
+	for _, part := range strings.Split(s, ",") {
+		what, at, ok := strings.Cut(strings.TrimSpace(part), "@")
+		if !ok {
+			return nil, fmt.Errorf("partition step %q: want mode@time", part)
+		}
+		var step partitionStep
+		var err error
+		if step.at, err = time.ParseDuration(at); err != nil {
+			return nil, fmt.Errorf("partition step %q: %w", part, err)
+		}
+		name, delay, hasDelay := strings.Cut(what, "=")
+		if step.mode, ok = partitionModes[name]; !ok {
+			return nil, fmt.Errorf("partition step %q: unknown mode %q", part, name)
+		}
+		if hasDelay != (step.mode == partitionDelay) {
+			return nil, fmt.Errorf("partition step %q: only delay takes a duration, as delay=300ms", part)
+		}
+		if hasDelay {
+			if step.delay, err = time.ParseDuration(delay); err != nil {
+				return nil, fmt.Errorf("partition step %q: %w", part, err)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// run applies the steps at their time, printing each change to w.
+func (p *partitionDialer) run(steps []partitionStep, w io.Writer) {
+	start := time.Now()
+	for _, step := range steps {
+		time.Sleep(time.Until(start.Add(step.at)))
+		if step.mode == partitionDelay {
+			fmt.Fprintf(w, "partition: delay of %s after %s\n", step.delay, step.at)
+		} else {
+			fmt.Fprintf(w, "partition: %s after %s\n", step.mode, step.at)
+		}
+		p.Set(step.mode, step.delay)
+	}
+}
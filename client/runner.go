@@ -0,0 +1,116 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Call is one unit of work executed by a Runner.
+type Call func(ctx context.Context) (interface{}, error)
+
+// Result is the outcome of one call.
+type Result struct {
+	Name    string
+	Tags    []string
+	Value   interface{}
+	Err     error
+	Elapsed time.Duration
+}
+
+type runnerCall struct {
+	tags   []string
+	cancel context.CancelFunc
+}
+
+// Runner executes calls concurrently. Every call gets its own child context,
+// so cancelling one call, or one tag, leaves the others running. Runner is
+// safe for use by multiple goroutines.
+type Runner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	active  map[*runnerCall]struct{}
+	results []Result
+}
+
+func newRunner(ctx context.Context) *Runner {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Runner{
+		ctx:    ctx,
+		cancel: cancel,
+		active: make(map[*runnerCall]struct{}),
+	}
+}
+
+// Go starts call in its own goroutine.
+func (r *Runner) Go(name string, tags []string, call Call) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	rc := &runnerCall{tags: tags, cancel: cancel}
+
+	r.mu.Lock()
+	r.active[rc] = struct{}{}
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+
+		start := time.Now()
+		value, err := call(ctx)
+
+		r.mu.Lock()
+		delete(r.active, rc)
+		r.results = append(r.results, Result{
+			Name:    name,
+			Tags:    tags,
+			Value:   value,
+			Err:     err,
+			Elapsed: time.Since(start),
+		})
+		r.mu.Unlock()
+	}()
+}
+
+// CancelTag cancels the running calls carrying tag and returns how many it
+// cancelled.
+func (r *Runner) CancelTag(tag string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for rc := range r.active {
+		for _, t := range rc.tags {
+			if t == tag {
+				rc.cancel()
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// CancelAll cancels every running call and every call started afterwards.
+func (r *Runner) CancelAll() {
+	r.cancel()
+}
+
+// Wait blocks until all calls have returned and gives their results in
+// completion order. It releases the context of the Runner, a call started
+// afterwards runs cancelled.
+func (r *Runner) Wait() []Result {
+	r.wg.Wait()
+	r.cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]Result, len(r.results))
+	copy(results, r.results)
+	return results
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestRunner(t *testing.T) {
+	calls := []struct {
+		name string
+		tags []string
+	}{
+		{name: "List#0", tags: []string{"list"}},
+		{name: "List#1", tags: []string{"list", "slow"}},
+		{name: "Stats#0", tags: []string{"stats"}},
+		{name: "Stats#1", tags: []string{"stats", "slow"}},
+	}
+
+	tests := []struct {
+		name string
+		// cancel returns how many calls it cancelled.
+		cancel        func(r *Runner, parent context.CancelFunc) int
+		wantN         int
+		wantCancelled []string
+	}{
+		{
+			name:   "nothing cancelled",
+			cancel: func(r *Runner, parent context.CancelFunc) int { return 0 },
+		},
+		{
+			name:          "cancel a tag",
+			cancel:        func(r *Runner, parent context.CancelFunc) int { return r.CancelTag("slow") },
+			wantN:         2,
+			wantCancelled: []string{"List#1", "Stats#1"},
+		},
+		{
+			name:          "cancel another tag",
+			cancel:        func(r *Runner, parent context.CancelFunc) int { return r.CancelTag("stats") },
+			wantN:         2,
+			wantCancelled: []string{"Stats#0", "Stats#1"},
+		},
+		{
+			name:   "cancel an unknown tag",
+			cancel: func(r *Runner, parent context.CancelFunc) int { return r.CancelTag("export") },
+		},
+		{
+			name: "cancel all",
+			cancel: func(r *Runner, parent context.CancelFunc) int {
+				r.CancelAll()
+				return len(calls)
+			},
+			wantN:         len(calls),
+			wantCancelled: []string{"List#0", "List#1", "Stats#0", "Stats#1"},
+		},
+		{
+			name: "cancel the parent",
+			cancel: func(r *Runner, parent context.CancelFunc) int {
+				parent()
+				return len(calls)
+			},
+			wantN:         len(calls),
+			wantCancelled: []string{"List#0", "List#1", "Stats#0", "Stats#1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent, cancelParent := context.WithCancel(context.Background())
+			defer cancelParent()
+			r := newRunner(parent)
+
+			// Every call runs until it is cancelled or released, so the
+			// cancel finds them all running.
+			var started sync.WaitGroup
+			release := make(chan struct{})
+			for _, c := range calls {
+				name := c.name
+				started.Add(1)
+				r.Go(name, c.tags, func(ctx context.Context) (interface{}, error) {
+					started.Done()
+					select {
+					case <-ctx.Done():
+					case <-release:
+					}
+					if err := ctx.Err(); err != nil {
+						return nil, err
+					}
+					return name, nil
+				})
+			}
+			started.Wait()
+
+			if n := tt.cancel(r, cancelParent); n != tt.wantN {
+				t.Fatalf("cancelled %d calls, want %d", n, tt.wantN)
+			}
+			close(release)
+			results := r.Wait()
+
+			cancelled := make(map[string]bool)
+			for _, name := range tt.wantCancelled {
+				cancelled[name] = true
+			}
+			if len(results) != len(calls) {
+				t.Fatalf("got %d results, want %d", len(results), len(calls))
+			}
+			for _, res := range results {
+				if cancelled[res.Name] {
+					if res.Err != context.Canceled {
+						t.Errorf("%s: got %v, %v, want cancelled", res.Name, res.Value, res.Err)
+					}
+					continue
+				}
+				if res.Err != nil || res.Value != res.Name {
+					t.Errorf("%s: got %v, %v, want success", res.Name, res.Value, res.Err)
+				}
+			}
+
+			if r.ctx.Err() == nil {
+				t.Error("the context of the Runner is still live after Wait")
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go-cancel/clock"
+
+	"golang.org/x/net/context"
+)
+
+// slowStart paces a resumed stream. A stream that was cut off is likely to
+// hit the same limit again if it picks up at full speed, so the client waits
+// before reading each message, initial at first and half as long after
+// every message, until the wait drops below a millisecond.
+type slowStart struct {
+	initial time.Duration
+
+	mu      sync.Mutex
+	resumes int
+	paced   int
+	waited  time.Duration
+}
+
+// pacer returns the pacing of one resumed stream, timed by clk.
+func (s *slowStart) pacer(clk clock.Clock) *pacer {
+	if s == nil || s.initial <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.resumes++
+	s.mu.Unlock()
+	return &pacer{owner: s, clock: clk, gap: s.initial}
+}
+
+// String reports what slow-start did so far.
+func (s *slowStart) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("slow-start: %d resumed streams, %d messages paced, %s waited", s.resumes, s.paced, s.waited.Round(time.Millisecond))
+}
+
+type pacer struct {
+	owner *slowStart
+	clock clock.Clock
+	gap   time.Duration
+}
+
+// wait blocks before the next read, it returns early when ctx ends.
+func (p *pacer) wait(ctx context.Context) {
+	if p == nil || p.gap < time.Millisecond {
+		return
+	}
+
+	start := p.clock.Now()
+	fired, stop := p.clock.NewTimer(p.gap)
+	select {
+	case <-fired:
+	case <-ctx.Done():
+		stop()
+	}
+
+	p.owner.mu.Lock()
+	p.owner.paced++
+	p.owner.waited += p.clock.Now().Sub(start)
+	p.owner.mu.Unlock()
+
+	p.gap /= 2
+}
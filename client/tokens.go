@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// tokenStore persists resume tokens in a JSON file, keyed by stream name.
+type tokenStore struct {
+	path string
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func loadTokenStore(path string) (*tokenStore, error) {
+	s := &tokenStore{path: path, tokens: make(map[string]string)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.tokens); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *tokenStore) Get(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[name]
+}
+
+// Set saves token for name, an empty token removes it.
+func (s *tokenStore) Set(name, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token == "" {
+		delete(s.tokens, name)
+	} else {
+		s.tokens[name] = token
+	}
+
+	data, err := json.Marshal(s.tokens)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, data, 0600)
+}
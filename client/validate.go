@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"go-cancel/pb/cities"
+)
+
+// cityValidator checks the cities one call receives. A message cut short or
+// mixed up by the server, for example while it is being cancelled in the
+// middle of encoding, shows up as an empty field or an id going backwards.
+// Fields the client does not know about mean the server runs a newer schema.
+type cityValidator struct {
+	call      string
+	w         io.Writer
+	last      string
+	anomalies int
+}
+
+func newCityValidator(call string, w io.Writer) *cityValidator {
+	return &cityValidator{call: call, w: w}
+}
+
+// Check prints the anomalies of city to w and returns how many it found.
+func (v *cityValidator) Check(city *cities.City) int {
+	var found []string
+
+	if city.GetId() == "" {
+		found = append(found, "empty id")
+	}
+	if city.GetName() == "" {
+		found = append(found, "empty name")
+	}
+	if v.last != "" && city.GetId() != "" && !idAfter(city.GetId(), v.last) {
+		found = append(found, fmt.Sprintf("id %s does not follow %s", city.GetId(), v.last))
+	}
+	if unknown := city.ProtoReflect().GetUnknown(); len(unknown) > 0 {
+		found = append(found, fmt.Sprintf("%d bytes of unknown fields, the server schema has drifted", len(unknown)))
+	}
+
+	if city.GetId() != "" {
+		v.last = city.GetId()
+	}
+	for _, a := range found {
+		fmt.Fprintf(v.w, "anomaly in %s: %s\n", v.call, a)
+	}
+	v.anomalies += len(found)
+	return len(found)
+}
+
+// idAfter compares numeric ids as numbers, generated ids like ULIDs sort in
+// creation order as strings.
+func idAfter(id, last string) bool {
+	n, errN := strconv.ParseUint(id, 10, 64)
+	l, errL := strconv.ParseUint(last, 10, 64)
+	if errN == nil && errL == nil {
+		return n > l
+	}
+	return id > last
+}
@@ -0,0 +1,22 @@
+//go:build !minimal
+
+package main
+
+import (
+	"net"
+
+	"golang.org/x/net/context"
+	"nhooyr.io/websocket"
+)
+
+// dialWebSocket tunnels the gRPC connection through the /grpc-ws endpoint of
+// the REST server at addr. Closing the connection closes the socket, which
+// the server sees as the end of every stream on it.
+func dialWebSocket(ctx context.Context, addr string) (net.Conn, error) {
+	c, _, err := websocket.Dial(ctx, "ws://"+addr+"/grpc-ws", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return websocket.NetConn(context.Background(), c, websocket.MessageBinary), nil
+}
@@ -0,0 +1,15 @@
+//go:build minimal
+
+package main
+
+import (
+	"errors"
+	"net"
+
+	"golang.org/x/net/context"
+)
+
+// dialWebSocket is not part of the minimal build.
+func dialWebSocket(ctx context.Context, addr string) (net.Conn, error) {
+	return nil, errors.New("the WebSocket tunnel needs the full build")
+}
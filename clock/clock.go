@@ -0,0 +1,27 @@
+// Package clock is where code that waits takes the time from. The client
+// times its drain timeout, fallback window, cache TTL and slow-start pacing
+// with a Clock, so tests run them on a clocktest.Manual instead of waiting
+// for real time to pass.
+package clock
+
+import "time"
+
+// Clock tells the time and starts timers.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a channel receiving the time once d has passed, stop
+	// releases the timer early and reports whether it had not fired yet.
+	NewTimer(d time.Duration) (c <-chan time.Time, stop func() bool)
+}
+
+// System is the Clock of the time package.
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+func (System) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	t := time.NewTimer(d)
+	return t.C, t.Stop
+}
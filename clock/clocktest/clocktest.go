@@ -0,0 +1,80 @@
+// Package clocktest provides a clock.Clock that tests move by hand.
+package clocktest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manual only moves when Advance is called, timers fire as it passes them.
+// Waiters tells how many timers are pending, so a test can wait for the
+// code under test to block on one before advancing.
+type Manual struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+type timer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewManual returns a Manual clock showing start.
+func NewManual(start time.Time) *Manual {
+	return &Manual{now: start}
+}
+
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *Manual) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := &timer{at: m.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- m.now
+		return t.c, func() bool { return false }
+	}
+	m.timers = append(m.timers, t)
+	return t.c, func() bool { return m.remove(t) }
+}
+
+func (m *Manual) remove(t *timer) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, pending := range m.timers {
+		if pending == t {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance moves the clock forward by d, firing the timers due by then in
+// the order they are due.
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+	sort.SliceStable(m.timers, func(i, j int) bool { return m.timers[i].at.Before(m.timers[j].at) })
+	for len(m.timers) > 0 && !m.timers[0].at.After(m.now) {
+		t := m.timers[0]
+		m.timers = m.timers[1:]
+		t.c <- t.at
+	}
+}
+
+// Waiters returns the number of timers that have not fired yet.
+func (m *Manual) Waiters() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.timers)
+}
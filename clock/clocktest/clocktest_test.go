@@ -0,0 +1,39 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManual(t *testing.T) {
+	start := time.Unix(0, 0)
+	m := NewManual(start)
+	late, _ := m.NewTimer(2 * time.Second)
+	early, _ := m.NewTimer(time.Second)
+	_, stop := m.NewTimer(time.Second)
+	if !stop() || m.Waiters() != 2 {
+		t.Fatalf("stop a pending timer: %d timers left, want 2", m.Waiters())
+	}
+
+	m.Advance(time.Second - time.Nanosecond)
+	select {
+	case <-early:
+		t.Fatal("timer fired before it was due")
+	default:
+	}
+	m.Advance(time.Nanosecond)
+	if at := <-early; !at.Equal(start.Add(time.Second)) {
+		t.Fatalf("timer fired at %s, want %s", at, start.Add(time.Second))
+	}
+	if m.Waiters() != 1 {
+		t.Fatalf("%d timers pending, want 1", m.Waiters())
+	}
+
+	m.Advance(time.Hour)
+	if at := <-late; !at.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("timer fired at %s, want %s", at, start.Add(2*time.Second))
+	}
+	if now := m.Now(); !now.Equal(start.Add(time.Hour + time.Second)) {
+		t.Fatalf("Now() = %s", now)
+	}
+}
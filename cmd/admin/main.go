@@ -0,0 +1,121 @@
+// Command admin drives a running server through its AdminService, so demos
+// can be scripted. The server must run with ADMIN_TOKEN set.
+//
+//	go run ./cmd/admin -token secret maintenance on "deploying"
+//	go run ./cmd/admin -token secret maintenance off
+//	go run ./cmd/admin -token secret cancel <request-id>
+//	go run ./cmd/admin -token secret latency 250ms
+//	go run ./cmd/admin -token secret static-snapshot on
+//	go run ./cmd/admin -token secret reload
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9099", "gRPC address of the server")
+	token := flag.String("token", os.Getenv("ADMIN_TOKEN"), "admin token, defaults to $ADMIN_TOKEN")
+	timeout := flag.Duration("timeout", 5*time.Second, "deadline of the call")
+	flag.Parse()
+
+	if err := run(*addr, *token, *timeout, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "admin:", err)
+		os.Exit(1)
+	}
+}
+
+func run(addr, token string, timeout time.Duration, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: admin [flags] maintenance [on|off] [reason] | cancel <request-id> | latency [duration] | static-snapshot [on|off] | reload")
+	}
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := cities.NewAdminServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "maintenance":
+		var m *cities.Maintenance
+		if len(args) == 0 {
+			m, err = client.GetMaintenance(ctx, &cities.EmptyMessage{})
+		} else {
+			m, err = client.SetMaintenance(ctx, &cities.Maintenance{Enabled: args[0] == "on", Reason: strings.Join(args[1:], " ")})
+		}
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Printf("maintenance enabled=%t reason=%q\n", m.GetEnabled(), m.GetReason())
+
+	case "cancel":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: admin cancel <request-id>")
+		}
+		res, err := client.CancelCall(ctx, &cities.CancelCallRequest{RequestId: args[0]})
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Printf("cancelled %d calls\n", res.GetCancelled())
+
+	case "latency":
+		var l *cities.Latency
+		if len(args) == 0 {
+			l, err = client.GetLatency(ctx, &cities.EmptyMessage{})
+		} else {
+			d, perr := time.ParseDuration(args[0])
+			if perr != nil {
+				return perr
+			}
+			l, err = client.SetLatency(ctx, &cities.Latency{ExtraMs: d.Milliseconds()})
+		}
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Printf("extra latency %s\n", time.Duration(l.GetExtraMs())*time.Millisecond)
+
+	case "static-snapshot":
+		var snap *cities.StaticSnapshot
+		if len(args) == 0 {
+			snap, err = client.GetStaticSnapshot(ctx, &cities.EmptyMessage{})
+		} else {
+			snap, err = client.SetStaticSnapshot(ctx, &cities.StaticSnapshot{Enabled: args[0] == "on"})
+		}
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Printf("static snapshot enabled=%t\n", snap.GetEnabled())
+
+	case "reload":
+		if _, err := client.ReloadConfig(ctx, &cities.EmptyMessage{}); err != nil {
+			return describe(err)
+		}
+		fmt.Println("config reloaded")
+
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	return nil
+}
+
+func describe(err error) error {
+	st := status.Convert(err)
+	return fmt.Errorf("%s: %s", st.Code(), st.Message())
+}
@@ -0,0 +1,193 @@
+// Command conformance checks that a CitiesService implementation reports
+// cancellation the way this repository expects. It only talks gRPC, so the
+// implementation under test can be written in any language.
+//
+//	go run ./cmd/conformance -addr localhost:9099
+//	go run ./cmd/conformance -addr localhost:9099 -shutdown "kill -TERM 1234"
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type check struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:9099", "address of the CitiesService under test")
+	shutdown := flag.String("shutdown", "", "shell command making the server shut down, enables the shutdown check")
+	flag.Parse()
+
+	conn, err := grpc.Dial(*addr, grpc.WithInsecure())
+	if err != nil {
+		fmt.Printf("did not connect: %s\n", err)
+		os.Exit(2)
+	}
+	defer conn.Close()
+	client := cities.NewCitiesServiceClient(conn)
+	printServerInfo(client)
+
+	checks := []check{
+		{"cancel mid-stream returns Canceled", func(ctx context.Context) error { return cancelMidStream(ctx, client) }},
+		{"expired deadline before first byte returns DeadlineExceeded", func(ctx context.Context) error { return expiredDeadline(ctx, client) }},
+		{"short deadline on a stream returns DeadlineExceeded", func(ctx context.Context) error { return shortStreamDeadline(ctx, client) }},
+		{"cancel during dial returns promptly", cancelDuringDial},
+		{"server still serves after cancellations", func(ctx context.Context) error { return stillServing(ctx, client) }},
+	}
+	if *shutdown != "" {
+		checks = append(checks, check{"shutdown ends open streams with Unavailable or EOF", func(ctx context.Context) error {
+			return shutdownEndsStreams(ctx, client, *shutdown)
+		}})
+	}
+
+	failed := 0
+	for _, c := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := c.run(ctx)
+		cancel()
+
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL  %s: %s\n", c.name, err)
+			continue
+		}
+		fmt.Printf("PASS  %s\n", c.name)
+	}
+
+	fmt.Printf("%d/%d checks passed\n", len(checks)-failed, len(checks))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printServerInfo records which build and configuration the results below
+// belong to. Implementations without GetServerInfo are still checked.
+func printServerInfo(client cities.CitiesServiceClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	info, err := client.GetServerInfo(ctx, &cities.EmptyMessage{})
+	if err != nil {
+		fmt.Printf("server info unavailable: %s\n", status.Convert(err).Message())
+		return
+	}
+	fmt.Printf("server %s commit %s config %s features %v\n", info.GetVersion(), info.GetCommit(), info.GetConfigDigest(), info.GetFeatures())
+}
+
+func expectCode(err error, want codes.Code) error {
+	if got := status.Code(err); got != want {
+		return fmt.Errorf("got %s (%v), want %s", got, err, want)
+	}
+	return nil
+}
+
+func cancelMidStream(ctx context.Context, client cities.CitiesServiceClient) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := client.ListStream(ctx, &cities.ListStreamRequest{})
+	if err != nil {
+		return err
+	}
+	if _, err := stream.Recv(); err != nil {
+		return fmt.Errorf("first message: %w", err)
+	}
+
+	cancel()
+	for {
+		if _, err = stream.Recv(); err != nil {
+			return expectCode(err, codes.Canceled)
+		}
+	}
+}
+
+func expiredDeadline(ctx context.Context, client cities.CitiesServiceClient) error {
+	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err := client.List(ctx, &cities.EmptyMessage{})
+	return expectCode(err, codes.DeadlineExceeded)
+}
+
+func shortStreamDeadline(ctx context.Context, client cities.CitiesServiceClient) error {
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+
+	stream, err := client.ListStream(ctx, &cities.ListStreamRequest{})
+	if err != nil {
+		return expectCode(err, codes.DeadlineExceeded)
+	}
+	for {
+		if _, err = stream.Recv(); err != nil {
+			return expectCode(err, codes.DeadlineExceeded)
+		}
+	}
+}
+
+// cancelDuringDial dials an address that never answers and cancels the
+// blocking dial, which must give up right away.
+func cancelDuringDial(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	conn, err := grpc.DialContext(ctx, "10.255.255.1:9099", grpc.WithInsecure(), grpc.WithBlock())
+	if err == nil {
+		conn.Close()
+		return errors.New("dial to a blackhole address succeeded")
+	}
+	if !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("got %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		return fmt.Errorf("dial returned %s after cancel", elapsed)
+	}
+	return nil
+}
+
+func stillServing(ctx context.Context, client cities.CitiesServiceClient) error {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	_, err := client.List(ctx, &cities.EmptyMessage{})
+	return err
+}
+
+func shutdownEndsStreams(ctx context.Context, client cities.CitiesServiceClient, command string) error {
+	stream, err := client.ListStream(ctx, &cities.ListStreamRequest{})
+	if err != nil {
+		return err
+	}
+	if _, err := stream.Recv(); err != nil {
+		return fmt.Errorf("first message: %w", err)
+	}
+
+	if out, err := exec.Command("sh", "-c", command).CombinedOutput(); err != nil {
+		return fmt.Errorf("shutdown command: %v: %s", err, out)
+	}
+
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return expectCode(err, codes.Unavailable)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Command multizone launches a server instance in each of several zones and
+// calls them through a zone-aware picker, which prefers the local zone and
+// fails over to the others when it is slow or down, cancelling the attempts
+// that lost.
+//
+// It runs three rounds: all zones healthy, the local zone slowed down
+// through the admin API, and the local zone stopped.
+//
+//	go run ./cmd/multizone
+//	go run ./cmd/multizone -zones eu,us,ap -local us -failover 200ms
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// instance is one server process.
+type instance struct {
+	zone string
+	addr string
+	cmd  *exec.Cmd
+	conn *grpc.ClientConn
+}
+
+func main() {
+	server := flag.String("server", "", "server binary to run, empty builds the package given by -pkg")
+	pkg := flag.String("pkg", ".", "package of the server, built when -server is empty")
+	zones := flag.String("zones", "local,remote-1,remote-2", "comma separated zones, one server each")
+	local := flag.String("local", "local", "zone of the client")
+	failover := flag.Duration("failover", 300*time.Millisecond, "how long the picker waits for a zone before trying the next one too")
+	slow := flag.Duration("slow", 50*time.Millisecond, "latency added to every step of the local zone in the second round")
+	timeout := flag.Duration("timeout", 5*time.Second, "deadline of each call")
+	flag.Parse()
+
+	if err := run(*server, *pkg, strings.Split(*zones, ","), *local, *failover, *slow, *timeout); err != nil {
+		fmt.Fprintln(os.Stderr, "multizone:", err)
+		os.Exit(1)
+	}
+}
+
+func run(server, pkg string, zones []string, local string, failover, slow, timeout time.Duration) error {
+	if server == "" {
+		dir, err := os.MkdirTemp("", "multizone")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		server = filepath.Join(dir, "server")
+		if out, err := exec.Command("go", "build", "-o", server, pkg).CombinedOutput(); err != nil {
+			return fmt.Errorf("build: %v: %s", err, out)
+		}
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	adminToken := hex.EncodeToString(token)
+
+	var instances []*instance
+	defer func() {
+		for _, in := range instances {
+			in.conn.Close()
+			in.cmd.Process.Kill()
+			in.cmd.Wait()
+		}
+	}()
+	for _, zone := range zones {
+		in, err := start(server, zone, adminToken)
+		if err != nil {
+			return fmt.Errorf("zone %s: %w", zone, err)
+		}
+		instances = append(instances, in)
+	}
+
+	// The picker learns the zones from the servers themselves.
+	picker := &zonePicker{local: local, failover: failover}
+	for _, in := range instances {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		info, err := cities.NewCitiesServiceClient(in.conn).GetServerInfo(ctx, &cities.EmptyMessage{})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("server info of %s: %w", in.addr, err)
+		}
+		zone := info.GetConfig()["zone"]
+		fmt.Printf("zone %s on %s\n", zone, in.addr)
+		picker.endpoints = append(picker.endpoints, endpoint{zone: zone, addr: in.addr, client: cities.NewCitiesServiceClient(in.conn)})
+	}
+
+	call := func(round string) {
+		fmt.Printf("\n%s\n", round)
+		ctx, cancel := context.WithTimeout(metadata.AppendToOutgoingContext(context.Background(), "simulator", "fast"), timeout)
+		defer cancel()
+		list, attempts, err := picker.List(ctx)
+		for _, a := range attempts {
+			outcome := "answered first"
+			switch {
+			case a.err != nil:
+				outcome = status.Code(a.err).String() + ": " + status.Convert(a.err).Message()
+			case !a.won:
+				outcome = "answered too late"
+			}
+			fmt.Printf("  %-10s started at %-6s ended after %-8s %s\n", a.zone, a.started.Round(time.Millisecond), a.took.Round(time.Millisecond), outcome)
+		}
+		if err != nil {
+			fmt.Printf("  call failed: %s\n", err)
+			return
+		}
+		fmt.Printf("  got %d cities\n", len(list.GetCity()))
+	}
+
+	call("round 1: every zone healthy")
+
+	localInstance := instances[0]
+	for _, in := range instances {
+		if in.zone == local {
+			localInstance = in
+		}
+	}
+	if err := setLatency(localInstance.conn, adminToken, slow, timeout); err != nil {
+		return err
+	}
+	call(fmt.Sprintf("round 2: zone %s slowed down by %s per step", local, slow))
+
+	localInstance.cmd.Process.Kill()
+	localInstance.cmd.Wait()
+	call(fmt.Sprintf("round 3: zone %s stopped", local))
+	return nil
+}
+
+// start runs a server for zone on free ports and connects to it.
+func start(server, zone, adminToken string) (*instance, error) {
+	grpcAddr, err := freeAddr()
+	if err != nil {
+		return nil, err
+	}
+	restAddr, err := freeAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(server)
+	cmd.Env = append(os.Environ(), "ZONE="+zone, "GRPC_LISTEN="+grpcAddr, "REST_LISTEN="+restAddr, "ADMIN_TOKEN="+adminToken)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, grpcAddr, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("server did not come up on %s: %w", grpcAddr, err)
+	}
+	return &instance{zone: zone, addr: grpcAddr, cmd: cmd, conn: conn}, nil
+}
+
+func setLatency(conn *grpc.ClientConn, token string, latency, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	_, err := cities.NewAdminServiceClient(conn).SetLatency(ctx, &cities.Latency{ExtraMs: latency.Milliseconds()})
+	return err
+}
+
+// freeAddr returns a local address nothing listens on.
+func freeAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
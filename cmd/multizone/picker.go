@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc/status"
+)
+
+// endpoint is a server and the zone it said it runs in.
+type endpoint struct {
+	zone   string
+	addr   string
+	client cities.CitiesServiceClient
+}
+
+// zonePicker sends a call to the local zone first. When the local server
+// does not answer within failover, or fails, the call goes to the next zone
+// too, and so on. The first answer wins, the attempts still running are
+// cancelled.
+type zonePicker struct {
+	local     string
+	endpoints []endpoint
+	failover  time.Duration
+}
+
+// attempt is how one endpoint did on a call.
+type attempt struct {
+	zone    string
+	started time.Duration
+	took    time.Duration
+	err     error
+	won     bool
+}
+
+// order returns the endpoints of the local zone first, the others in the
+// order given.
+func (p *zonePicker) order() []endpoint {
+	ordered := make([]endpoint, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		if e.zone == p.local {
+			ordered = append(ordered, e)
+		}
+	}
+	for _, e := range p.endpoints {
+		if e.zone != p.local {
+			ordered = append(ordered, e)
+		}
+	}
+	return ordered
+}
+
+// List returns the cities of the first zone answering and how every
+// attempt went. It returns once all the attempts are over, so the losers
+// show whether their cancellation came through.
+func (p *zonePicker) List(ctx context.Context) (*cities.Cities, []attempt, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		i    int
+		list *cities.Cities
+		err  error
+	}
+	ordered := p.order()
+	results := make(chan result, len(ordered))
+	attempts := make([]attempt, 0, len(ordered))
+	start := time.Now()
+
+	launch := func() {
+		i := len(attempts)
+		attempts = append(attempts, attempt{zone: ordered[i].zone, started: time.Since(start)})
+		go func() {
+			list, err := ordered[i].client.List(ctx, &cities.EmptyMessage{})
+			results <- result{i, list, err}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(p.failover)
+	defer timer.Stop()
+
+	var (
+		winner  *cities.Cities
+		lastErr error
+	)
+	for pending := 1; pending > 0; {
+		select {
+		case r := <-results:
+			pending--
+			a := &attempts[r.i]
+			a.took, a.err = time.Since(start)-a.started, r.err
+			switch {
+			case r.err == nil && winner == nil:
+				a.won, winner = true, r.list
+				cancel()
+			case r.err != nil && winner == nil:
+				lastErr = r.err
+				if len(attempts) < len(ordered) {
+					launch()
+					pending++
+					timer.Reset(p.failover)
+				}
+			}
+		case <-timer.C:
+			if winner == nil && len(attempts) < len(ordered) {
+				launch()
+				pending++
+				timer.Reset(p.failover)
+			}
+		}
+	}
+
+	if winner == nil {
+		if lastErr == nil {
+			lastErr = errors.New("no endpoint")
+		}
+		return nil, attempts, status.Convert(lastErr).Err()
+	}
+	return winner, attempts, nil
+}
@@ -0,0 +1,83 @@
+// Command protoc-gen-timeouts is a protoc plugin turning the (timeouts.max)
+// method option into a MethodTimeouts map next to the generated messages, so
+// the server's timeout interceptor reads its policy from the API definition.
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-cancel/pb/timeouts"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func main() {
+	protogen.Options{}.Run(func(gen *protogen.Plugin) error {
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			if err := generateFile(gen, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type methodTimeout struct {
+	method  string
+	timeout time.Duration
+	raw     string
+}
+
+func generateFile(gen *protogen.Plugin, f *protogen.File) error {
+	var list []methodTimeout
+	for _, service := range f.Services {
+		for _, method := range service.Methods {
+			opts, ok := method.Desc.Options().(*descriptorpb.MethodOptions)
+			if !ok || opts == nil {
+				continue
+			}
+
+			raw := proto.GetExtension(opts, timeouts.E_Max).(string)
+			if raw == "" {
+				continue
+			}
+
+			timeout, err := time.ParseDuration(raw)
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("%s: invalid (timeouts.max) %q", method.Desc.FullName(), raw)
+			}
+
+			list = append(list, methodTimeout{
+				method:  fmt.Sprintf("/%s/%s", service.Desc.FullName(), method.Desc.Name()),
+				timeout: timeout,
+				raw:     raw,
+			})
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	g := gen.NewGeneratedFile(f.GeneratedFilenamePrefix+"_timeouts.pb.go", f.GoImportPath)
+	g.P("// Code generated by protoc-gen-timeouts. DO NOT EDIT.")
+	g.P("// source: ", f.Desc.Path())
+	g.P()
+	g.P("package ", f.GoPackageName)
+	g.P()
+	g.P("// MethodTimeouts maps the full name of every method setting (timeouts.max)")
+	g.P("// to that timeout.")
+	g.P("var MethodTimeouts = map[string]", g.QualifiedGoIdent(protogen.GoIdent{GoName: "Duration", GoImportPath: "time"}), "{")
+	for _, m := range list {
+		g.P(strconv.Quote(m.method), ": ", int64(m.timeout), ", // ", m.raw)
+	}
+	g.P("}")
+
+	return nil
+}
@@ -0,0 +1,208 @@
+// Command proxy serves the CitiesService over plain HTTP. Clients send their
+// budget in the Grpc-Timeout header, in the gRPC wire format ("500m" is
+// 500ms, "5S" five seconds). The proxy turns it into a deadline the moment
+// the request arrives, so whatever time the hop spends comes off the budget
+// and the backend only gets what is left. grpc-go then sends the remaining
+// time in its own grpc-timeout header.
+//
+// -naive forwards the budget the way a careless proxy does: the original
+// value is applied again when the backend call starts, so the backend keeps
+// working after the client has given up. -demo runs the same request through
+// both and shows the difference.
+//
+//	go run ./cmd/proxy -addr :8199 -backend localhost:9099
+//	curl -H 'Grpc-Timeout: 2S' -d '{"name":"Bandung"}' localhost:8199/cities
+//	go run ./cmd/proxy -demo -hop-delay 400ms
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/deadline"
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := flag.String("addr", ":8199", "address the proxy listens on")
+	backend := flag.String("backend", "localhost:9099", "address of the CitiesService")
+	hopDelay := flag.Duration("hop-delay", 0, "time the proxy spends on each request before calling the backend, e.g. for authentication")
+	naive := flag.Bool("naive", false, "restart the client's budget when calling the backend instead of forwarding what is left")
+	demo := flag.Bool("demo", false, "send one request through a correct and a naive proxy and compare them")
+	flag.Parse()
+
+	conn, err := grpc.Dial(*backend, grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("did not connect: %s", err)
+	}
+	defer conn.Close()
+	client := cities.NewCitiesServiceClient(conn)
+
+	if *demo {
+		if !runDemo(client, *hopDelay) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	p := &proxy{client: client, hopDelay: *hopDelay, naive: *naive}
+	log.Printf("proxy listening on %s, backend %s", *addr, *backend)
+	log.Fatal(http.ListenAndServe(*addr, p.handler()))
+}
+
+type proxy struct {
+	client   cities.CitiesServiceClient
+	hopDelay time.Duration
+	naive    bool
+}
+
+func (p *proxy) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cities", p.cities)
+	return mux
+}
+
+// cities lists the cities on GET and creates one on POST.
+func (p *proxy) cities(w http.ResponseWriter, r *http.Request) {
+	budget, timed, err := ctxmeta.ParseTimeout(r.Header.Get(ctxmeta.TimeoutHeader))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	cancel := context.CancelFunc(func() {})
+	if timed && !p.naive {
+		ctx, cancel = context.WithTimeout(ctx, budget)
+	}
+	defer cancel()
+
+	if err := hop(ctx, p.hopDelay); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if timed && p.naive {
+		// The mistake: the budget starts over, the time spent above is
+		// forgotten.
+		ctx, cancel = context.WithTimeout(r.Context(), budget)
+		defer cancel()
+	}
+	if remaining, ok := deadline.RemainingBudget(ctx); ok {
+		w.Header().Set("X-Forwarded-Timeout", remaining.Round(time.Millisecond).String())
+	}
+
+	var resp interface{}
+	switch r.Method {
+	case http.MethodGet:
+		resp, err = p.client.List(ctx, &cities.EmptyMessage{})
+	case http.MethodPost:
+		var in cities.CreateCityRequest
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, err = p.client.Create(ctx, &in)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.Println("error writing result", err)
+	}
+}
+
+// hop stands for the work the proxy does itself before the backend call.
+func hop(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return grpcerr.FromContext(ctx)
+	case <-t.C:
+		return nil
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	grpcerr.WriteHTTP(w, err)
+}
+
+// runDemo sends a request with a one second budget through a correct and a
+// naive proxy. The correct one must forward the budget minus the hop, the
+// naive one forwards the full budget, so the backend would still be working
+// when the client has already timed out.
+func runDemo(client cities.CitiesServiceClient, hopDelay time.Duration) bool {
+	if hopDelay == 0 {
+		hopDelay = 400 * time.Millisecond
+	}
+	const budget = time.Second
+
+	ok := true
+	for _, naive := range []bool{false, true} {
+		srv := httptest.NewServer((&proxy{client: client, hopDelay: hopDelay, naive: naive}).handler())
+
+		forwarded, err := demoRequest(srv.URL, budget)
+		srv.Close()
+		if err != nil {
+			fmt.Printf("naive=%t: %s\n", naive, err)
+			ok = false
+			continue
+		}
+
+		overrun := forwarded > budget-hopDelay+50*time.Millisecond
+		fmt.Printf("naive=%t: client budget %s, hop %s, forwarded %s, backend may outlive the client: %t\n",
+			naive, budget, hopDelay, forwarded, overrun)
+		if overrun != naive {
+			ok = false
+		}
+	}
+	return ok
+}
+
+func demoRequest(url string, budget time.Duration) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodGet, url+"/cities", nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(ctxmeta.TimeoutHeader, ctxmeta.FormatTimeout(budget))
+
+	// The backend call fails with DeadlineExceeded, the forwarded header is
+	// still there.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	forwarded := resp.Header.Get("X-Forwarded-Timeout")
+	if forwarded == "" {
+		return 0, errors.New("proxy did not report the forwarded timeout")
+	}
+	return time.ParseDuration(forwarded)
+}
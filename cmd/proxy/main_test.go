@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/grpcerr"
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// backendCall is a List call the backend received: the budget it got and
+// when its context ended.
+type backendCall struct {
+	budget time.Duration
+	ended  chan time.Time
+}
+
+// backend holds every List call until its context ends.
+type backend struct {
+	cities.UnimplementedCitiesServiceServer
+	calls chan backendCall
+}
+
+func (b *backend) List(ctx context.Context, in *cities.EmptyMessage) (*cities.Cities, error) {
+	call := backendCall{ended: make(chan time.Time, 1)}
+	if dl, ok := ctx.Deadline(); ok {
+		call.budget = time.Until(dl)
+	}
+	b.calls <- call
+	<-ctx.Done()
+	call.ended <- time.Now()
+	return nil, grpcerr.FromContext(ctx)
+}
+
+// startBackend serves b in process and returns a client of it.
+func startBackend(t *testing.T, b *backend) cities.CitiesServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	cities.RegisterCitiesServiceServer(srv, b)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return cities.NewCitiesServiceClient(conn)
+}
+
+// TestProxyBudget sends a request with a one second budget through a proxy
+// spending 300ms on the hop: the backend gets what is left and stops with
+// the client, unless the proxy is naive and starts the budget over.
+func TestProxyBudget(t *testing.T) {
+	const (
+		budget   = time.Second
+		hopDelay = 300 * time.Millisecond
+		slack    = 100 * time.Millisecond
+	)
+
+	tests := []struct {
+		name  string
+		naive bool
+	}{
+		{name: "remaining budget forwarded"},
+		{name: "naive proxy restarts the budget", naive: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &backend{calls: make(chan backendCall, 1)}
+			p := &proxy{client: startBackend(t, b), hopDelay: hopDelay, naive: tt.naive}
+			srv := httptest.NewServer(p.handler())
+			defer srv.Close()
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/cities", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(ctxmeta.TimeoutHeader, ctxmeta.FormatTimeout(budget))
+			clientDeadline := time.Now().Add(budget)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET /cities: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusGatewayTimeout {
+				t.Errorf("GET /cities: %s, want the backend out of time", resp.Status)
+			}
+
+			call := <-b.calls
+			outlived := (<-call.ended).Sub(clientDeadline)
+			if tt.naive {
+				if call.budget < budget-slack || outlived < hopDelay-slack {
+					t.Fatalf("naive proxy forwarded %s and the backend outlived the client by %s, want the full budget and about the hop", call.budget, outlived)
+				}
+				return
+			}
+			if call.budget > budget-hopDelay+slack || outlived > slack {
+				t.Fatalf("proxy forwarded %s and the backend outlived the client by %s, want at most %s and no overrun", call.budget, outlived, budget-hopDelay)
+			}
+		})
+	}
+}
+
+// TestProxyDisconnect disconnects from the proxy while the backend works:
+// the backend call is cancelled with the request.
+func TestProxyDisconnect(t *testing.T) {
+	for _, naive := range []bool{false, true} {
+		b := &backend{calls: make(chan backendCall, 1)}
+		p := &proxy{client: startBackend(t, b), naive: naive}
+		srv := httptest.NewServer(p.handler())
+
+		ctx, disconnect := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/cities", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan struct{})
+		go func() {
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+			close(done)
+		}()
+
+		call := <-b.calls
+		disconnected := time.Now()
+		disconnect()
+		select {
+		case ended := <-call.ended:
+			if took := ended.Sub(disconnected); took > 250*time.Millisecond {
+				t.Errorf("naive=%t: backend ran on %s after the disconnect", naive, took)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("naive=%t: backend call not cancelled by the disconnect", naive)
+		}
+		<-done
+		srv.Close()
+	}
+}
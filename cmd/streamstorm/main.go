@@ -0,0 +1,189 @@
+// Command streamstorm opens thousands of ListStream calls on a running
+// server, cancels them all at once and reports how long the cancellation
+// storm took to settle next to what the GC did meanwhile, read from
+// /debug/vars. Run it against servers started with different GC_PERCENT,
+// GC_MEMORY_LIMIT and GC_BALLAST to compare them.
+//
+//	GC_BALLAST=1GiB go run .
+//	go run ./cmd/streamstorm -streams 5000 -hold 5s
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go-cancel/pb/cities"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type memStats struct {
+	NumGC        uint32
+	PauseTotalNs uint64
+	PauseNs      [256]uint64
+	HeapAlloc    uint64
+	HeapSys      uint64
+}
+
+type vars struct {
+	ActiveStreams int64    `json:"active_streams"`
+	Goroutines    int      `json:"goroutines"`
+	MemStats      memStats `json:"memstats"`
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:9099", "gRPC address of the server")
+	rest := flag.String("rest", "http://localhost:8099", "base URL of the REST server, for /debug/vars")
+	streams := flag.Int("streams", 2000, "number of concurrent streams")
+	conns := flag.Int("conns", 4, "connections the streams are spread over")
+	simulator := flag.String("simulator", "huge-dataset", "simulator preset of the streams, it should outlast -hold")
+	hold := flag.Duration("hold", 3*time.Second, "how long the streams run before they are all cancelled")
+	flag.Parse()
+
+	if err := run(*addr, *rest, *streams, *conns, *simulator, *hold); err != nil {
+		fmt.Fprintln(os.Stderr, "streamstorm:", err)
+		os.Exit(1)
+	}
+}
+
+func run(addr, rest string, n, nconns int, simulator string, hold time.Duration) error {
+	clients := make([]cities.CitiesServiceClient, nconns)
+	for i := range clients {
+		conn, err := grpc.Dial(addr, grpc.WithInsecure())
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		clients[i] = cities.NewCitiesServiceClient(conn)
+	}
+
+	before, err := readVars(rest)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), "simulator", simulator))
+	defer cancel()
+
+	var (
+		ready    sync.WaitGroup
+		done     sync.WaitGroup
+		mu       sync.Mutex
+		stops    []time.Duration
+		failures int
+	)
+	var cancelledAt time.Time
+	ready.Add(n)
+	done.Add(n)
+	for i := 0; i < n; i++ {
+		go func(client cities.CitiesServiceClient) {
+			defer done.Done()
+			stream, err := client.ListStream(ctx, &cities.ListStreamRequest{})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			ready.Done()
+			if err != nil {
+				mu.Lock()
+				failures++
+				mu.Unlock()
+				return
+			}
+			for {
+				if _, err := stream.Recv(); err != nil {
+					break
+				}
+			}
+			mu.Lock()
+			if !cancelledAt.IsZero() {
+				stops = append(stops, time.Since(cancelledAt))
+			}
+			mu.Unlock()
+		}(clients[i%nconns])
+	}
+
+	start := time.Now()
+	ready.Wait()
+	fmt.Printf("%d streams open in %s, %d failed\n", n-failures, time.Since(start).Round(time.Millisecond), failures)
+	time.Sleep(hold)
+
+	mid, err := readVars(rest)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	cancelledAt = time.Now()
+	mu.Unlock()
+	cancel()
+	done.Wait()
+	clientDone := time.Since(cancelledAt)
+
+	// The server is done once its stream count is back where it was.
+	var after vars
+	for {
+		if after, err = readVars(rest); err != nil {
+			return err
+		}
+		if after.ActiveStreams <= before.ActiveStreams {
+			break
+		}
+		if time.Since(cancelledAt) > time.Minute {
+			return errors.New("the server still has the streams a minute after the cancellation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	serverDone := time.Since(cancelledAt)
+
+	sort.Slice(stops, func(i, j int) bool { return stops[i] < stops[j] })
+	fmt.Printf("cancelled %d streams: client done in %s (p50 %s, p99 %s), server done in %s\n",
+		len(stops), clientDone.Round(time.Millisecond), percentile(stops, 0.5), percentile(stops, 0.99), serverDone.Round(time.Millisecond))
+	fmt.Printf("while running: %s\n", gcDelta(before.MemStats, mid.MemStats))
+	fmt.Printf("during the storm: %s\n", gcDelta(mid.MemStats, after.MemStats))
+	fmt.Printf("heap %d MiB in use of %d MiB, %d goroutines left (%d before)\n",
+		after.MemStats.HeapAlloc>>20, after.MemStats.HeapSys>>20, after.Goroutines, before.Goroutines)
+	return nil
+}
+
+// gcDelta describes the collections between two snapshots.
+func gcDelta(from, to memStats) string {
+	n := to.NumGC - from.NumGC
+	var longest uint64
+	for i := uint32(0); i < n && i < uint32(len(to.PauseNs)); i++ {
+		if p := to.PauseNs[(to.NumGC-i+255)%256]; p > longest {
+			longest = p
+		}
+	}
+	return fmt.Sprintf("%d GCs, %s paused, longest pause %s", n,
+		time.Duration(to.PauseTotalNs-from.PauseTotalNs), time.Duration(longest))
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1))].Round(time.Millisecond)
+}
+
+func readVars(rest string) (vars, error) {
+	var v vars
+
+	resp, err := http.Get(rest + "/debug/vars")
+	if err != nil {
+		return v, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return v, fmt.Errorf("debug vars: %s", resp.Status)
+	}
+	return v, json.NewDecoder(resp.Body).Decode(&v)
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// Client holds the settings the client connects with, DefaultClient the
+// defaults.
+type Client struct {
+	Target   string        `config:"target" env:"CITIES_TARGET" flag:"target" usage:"gRPC address of the server, or unix:path of its unix socket"`
+	RESTURL  string        `config:"rest_url" env:"CITIES_REST_URL" flag:"rest" usage:"REST gateway used by List when gRPC is unavailable, empty disables the fallback"`
+	Timeout  time.Duration `config:"timeout" env:"CITIES_TIMEOUT" flag:"timeout" usage:"deadline of the calls"`
+	Drain    time.Duration `config:"drain" env:"CITIES_DRAIN" flag:"drain" usage:"how long Close waits for streams to reach a message boundary"`
+	Fallback time.Duration `config:"fallback" env:"CITIES_FALLBACK" flag:"fallback" usage:"how quickly the gRPC call must fail with Unavailable to fall back to REST"`
+
+	Keepalive              time.Duration `config:"keepalive" env:"CITIES_KEEPALIVE" flag:"keepalive" usage:"ping the server after that long without activity, so an idle stream keeps its NAT mapping and a dead server is found (grpc-go raises it to at least 10s); 0 disables keepalives"`
+	KeepaliveTimeout       time.Duration `config:"keepalive_timeout" env:"CITIES_KEEPALIVE_TIMEOUT" flag:"keepalive-timeout" usage:"fail the connection when a ping is not answered within that long, 0 for as long as keepalive"`
+	KeepaliveWithoutStream bool          `config:"keepalive_without_stream" env:"CITIES_KEEPALIVE_WITHOUT_STREAM" usage:"ping while no call is in flight too, the server must permit it"`
+
+	TLS           bool   `config:"tls" env:"CITIES_TLS" flag:"tls" usage:"connect over TLS, trusting the system roots without tls_ca"`
+	TLSCA         string `config:"tls_ca" env:"CITIES_TLS_CA" flag:"tls-ca" usage:"PEM CA bundle the server certificate must chain to, implies tls"`
+	TLSCert       string `config:"tls_cert" env:"CITIES_TLS_CERT" flag:"tls-cert" usage:"PEM client certificate for servers asking for one (mutual TLS), with tls_key; implies tls"`
+	TLSKey        string `config:"tls_key" env:"CITIES_TLS_KEY" flag:"tls-key" usage:"PEM private key of tls_cert"`
+	TLSServerName string `config:"tls_server_name" env:"CITIES_TLS_SERVER_NAME" flag:"tls-server-name" usage:"name the server certificate must have, defaults to the host of target"`
+}
+
+// TLSEnabled reports whether the client connects over TLS.
+func (c *Client) TLSEnabled() bool {
+	return c.TLS || c.TLSCA != "" || c.TLSCert != ""
+}
+
+// DefaultClient returns the defaults of the client settings.
+func DefaultClient() Client {
+	return Client{
+		Target:   "localhost:9099",
+		RESTURL:  "http://localhost:8099",
+		Timeout:  3 * time.Second,
+		Drain:    2 * time.Second,
+		Fallback: time.Second,
+
+		KeepaliveWithoutStream: true,
+	}
+}
+
+// Validate reports the settings the client cannot run with.
+func (c *Client) Validate() error {
+	switch {
+	case c.Target == "":
+		return errors.New("target is empty")
+	case c.Timeout <= 0:
+		return errors.New("timeout must be positive")
+	case c.Drain < 0:
+		return errors.New("drain is negative")
+	case c.Keepalive < 0 || c.KeepaliveTimeout < 0:
+		return errors.New("keepalive is negative")
+	case (c.TLSCert == "") != (c.TLSKey == ""):
+		return errors.New("tls_cert and tls_key go together")
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+// Package config loads the settings of the server and the client. Every
+// setting has a default, and can be set from a JSON or YAML file, from the
+// environment and from a command line flag, each overriding the one before.
+//
+// The settings are the fields of a struct, described by tags:
+//
+//	GRPCPort int `config:"grpc_port" env:"GRPC_PORT" flag:"grpc-port" usage:"..."`
+//
+// config is the key in the file and in Values, env lists the environment
+// variables, the first one set wins, and flag names the flag. A field
+// tagged secret is only reported as set or not by Values.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Loader fills a settings struct.
+type Loader struct {
+	cfg     reflect.Value
+	fields  []field
+	fs      *flag.FlagSet
+	file    *string
+	fileEnv string
+	flags   map[string]string
+}
+
+type field struct {
+	value  reflect.Value
+	key    string
+	env    []string
+	flag   string
+	secret bool
+}
+
+// Bind registers the flags of cfg, a pointer to a settings struct holding
+// the defaults, and a -config flag on fs, the environment variable fileEnv
+// naming the file when the flag is not given. Load fills cfg once fs is
+// parsed.
+func Bind(fs *flag.FlagSet, fileEnv string, cfg interface{}) *Loader {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		panic("config: Bind needs a pointer to a struct")
+	}
+	l := &Loader{cfg: v.Elem(), fs: fs, fileEnv: fileEnv, flags: make(map[string]string)}
+	l.file = fs.String("config", "", "JSON or YAML file with the settings, defaults to $"+fileEnv)
+
+	t := l.cfg.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := sf.Tag.Get("config")
+		if key == "" {
+			continue
+		}
+		f := field{value: l.cfg.Field(i), key: key, flag: sf.Tag.Get("flag"), secret: sf.Tag.Get("secret") != ""}
+		if env := sf.Tag.Get("env"); env != "" {
+			f.env = strings.Split(env, ",")
+		}
+		if f.flag != "" {
+			name := f.flag
+			usage := sf.Tag.Get("usage")
+			if len(f.env) > 0 {
+				usage += " ($" + f.env[0] + ")"
+			}
+			fs.Func(name, fmt.Sprintf("%s (default %s)", usage, format(f.value)), func(s string) error {
+				if err := set(reflect.New(f.value.Type()).Elem(), s); err != nil {
+					return err
+				}
+				l.flags[name] = s
+				return nil
+			})
+		}
+		l.fields = append(l.fields, f)
+	}
+	return l
+}
+
+// Load applies the file, the environment and the flags, then validates the
+// settings when they have a Validate() error method.
+func (l *Loader) Load() error {
+	path := *l.file
+	if path == "" {
+		path = os.Getenv(l.fileEnv)
+	}
+	if path != "" {
+		if err := l.loadFile(path); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+
+	for _, f := range l.fields {
+		for _, name := range f.env {
+			s, ok := os.LookupEnv(name)
+			if !ok || s == "" {
+				continue
+			}
+			if err := set(f.value, s); err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			break
+		}
+	}
+
+	for _, f := range l.fields {
+		if s, ok := l.flags[f.flag]; ok {
+			if err := set(f.value, s); err != nil {
+				return fmt.Errorf("invalid -%s: %w", f.flag, err)
+			}
+		}
+	}
+
+	if v, ok := l.cfg.Addr().Interface().(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func (l *Loader) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("unknown format %q, want .json, .yaml or .yml", ext)
+	}
+	if err != nil {
+		return err
+	}
+
+	for key, raw := range values {
+		f, ok := l.field(key)
+		if !ok {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+		if err := set(f.value, fileValue(raw)); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// fileValue is the text of a value decoded from a file. An array becomes
+// the comma separated text a List reads, fmt.Sprint would make [a b] of it.
+func fileValue(raw interface{}) string {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return fmt.Sprint(raw)
+	}
+	items := make([]string, len(list))
+	for i, item := range list {
+		items[i] = fmt.Sprint(item)
+	}
+	return strings.Join(items, ",")
+}
+
+func (l *Loader) field(key string) (field, bool) {
+	for _, f := range l.fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// Values returns every setting by key, as text. Secrets are "set" or empty.
+func (l *Loader) Values() map[string]string {
+	values := make(map[string]string, len(l.fields))
+	for _, f := range l.fields {
+		s := format(f.value)
+		if f.secret && s != "" {
+			s = "set"
+		}
+		values[f.key] = s
+	}
+	return values
+}
+
+func set(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(interface{ UnmarshalText([]byte) error }); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return errors.New("unsupported setting type " + v.Type().String())
+	}
+	return nil
+}
+
+func format(v reflect.Value) string {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
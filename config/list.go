@@ -0,0 +1,23 @@
+package config
+
+import "strings"
+
+// List is a comma separated list of values, e.g. a,b,c, or an array in a
+// config file.
+type List []string
+
+// UnmarshalText splits text on commas, dropping the empty values.
+func (l *List) UnmarshalText(text []byte) error {
+	*l = nil
+	for _, v := range strings.Split(string(text), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// String joins the values with commas.
+func (l List) String() string {
+	return strings.Join(l, ",")
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Server holds the settings of the server, DefaultServer the defaults.
+type Server struct {
+	GRPCPort   int    `config:"grpc_port" env:"GRPC_PORT" flag:"grpc-port" usage:"port of the gRPC server"`
+	RESTPort   int    `config:"rest_port" env:"REST_PORT" flag:"rest-port" usage:"port of the REST server"`
+	GRPCListen string `config:"grpc_listen" env:"GRPC_LISTEN" flag:"grpc-listen" usage:"comma separated gRPC listen addresses, e.g. 0.0.0.0:9099,[::]:9099 or unix:/tmp/cities.sock; overrides -grpc-port"`
+	RESTListen string `config:"rest_listen" env:"REST_LISTEN" flag:"rest-listen" usage:"comma separated REST listen addresses; overrides -rest-port"`
+
+	StreamInterval time.Duration `config:"stream_interval" env:"STREAM_INTERVAL" flag:"stream-interval" usage:"time the default simulator takes per streamed city"`
+	ListSize       int           `config:"list_size" env:"LIST_SIZE" flag:"list-size" usage:"number of cities the default simulator lists"`
+	MaxStreams     uint32        `config:"max_streams" env:"MAX_STREAMS" flag:"max-streams" usage:"concurrent streams allowed on each gRPC connection, 0 for no limit"`
+	StreamLimit    int           `config:"stream_limit" env:"STREAM_LIMIT" flag:"stream-limit" usage:"concurrent streams allowed on the whole server, the streams above it fail with ResourceExhausted; 0 for no limit"`
+	NameLocale     string        `config:"name_locale" env:"NAME_LOCALE" flag:"name-locale" usage:"country of the generated city names when a call sends no locale: id, de, fi, jp or us; empty for random letters"`
+	SoftDeadline   float64       `config:"soft_deadline" env:"SOFT_DEADLINE" flag:"soft-deadline" usage:"share of its deadline a List or ListStream call may use before it is warned: List skips the simulated work left and is no longer coalesced, ListStream flushes its batch; 0 disables it"`
+	StaticSnapshot bool          `config:"static_snapshot" env:"STATIC_SNAPSHOT" flag:"static-snapshot" usage:"serve List and ListStream from cities built once, without simulated work, to benchmark the call overhead alone; the admin API toggles it"`
+
+	KeepaliveTime                time.Duration `config:"keepalive_time" env:"KEEPALIVE_TIME" flag:"keepalive-time" usage:"ping a client after that long without activity, so idle streams keep their NAT mapping and dead clients are found; 0 keeps the gRPC default of 2h"`
+	KeepaliveTimeout             time.Duration `config:"keepalive_timeout" env:"KEEPALIVE_TIMEOUT" usage:"close a connection whose ping is not answered within that long, 0 keeps the gRPC default of 20s"`
+	KeepaliveMinTime             time.Duration `config:"keepalive_min_time" env:"KEEPALIVE_MIN_TIME" usage:"shortest interval allowed between the pings of a client, one pinging more often is disconnected; 0 keeps the gRPC default of 5m"`
+	KeepalivePermitWithoutStream bool          `config:"keepalive_permit_without_stream" env:"KEEPALIVE_PERMIT_WITHOUT_STREAM" usage:"let clients ping while they have no call in flight"`
+	MaxConnectionIdle            time.Duration `config:"max_connection_idle" env:"MAX_CONNECTION_IDLE" usage:"close a connection without calls for that long, 0 for never"`
+	MaxConnectionAge             time.Duration `config:"max_connection_age" env:"MAX_CONNECTION_AGE" usage:"ask a client to reconnect after that long, to rebalance; 0 for never"`
+	MaxConnectionAgeGrace        time.Duration `config:"max_connection_age_grace" env:"MAX_CONNECTION_AGE_GRACE" usage:"how long the calls of a connection past max_connection_age may run on, 0 for no limit"`
+
+	ShutdownTimeout          time.Duration `config:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT,SHUTDOWN_DRAIN_TIMEOUT" flag:"shutdown-timeout" usage:"how long the calls in flight may run on after SIGINT or SIGTERM before they are cancelled"`
+	DefaultDeadline          time.Duration `config:"default_deadline" env:"DEFAULT_DEADLINE" flag:"default-deadline" usage:"deadline of the gRPC calls sent without one, watches and the cities streams excepted; 0 leaves them unbounded"`
+	HandlerCeiling           time.Duration `config:"handler_ceiling" env:"HANDLER_CEILING" usage:"longest a CitiesService call may run whatever its deadline, watches excepted; the watchdog cancels it and dumps the goroutines; 0 disables it"`
+	WatchdogDumpDir          string        `config:"watchdog_dump_dir" env:"WATCHDOG_DUMP_DIR" usage:"directory the watchdog writes its goroutine dumps to, empty for the temporary directory"`
+	RESTWriteTimeout         time.Duration `config:"rest_write_timeout" env:"REST_WRITE_TIMEOUT" usage:"write timeout of the ndjson, Server-Sent Events and WebSocket streams"`
+	RESTReadHeaderTimeout    time.Duration `config:"rest_read_header_timeout" env:"REST_READ_HEADER_TIMEOUT" usage:"how long a REST client may take to send the request headers"`
+	RESTReadTimeout          time.Duration `config:"rest_read_timeout" env:"REST_READ_TIMEOUT" usage:"how long a REST client may take to send the whole request, 0 for no limit"`
+	RESTResponseTimeout      time.Duration `config:"rest_response_timeout" env:"REST_RESPONSE_TIMEOUT" usage:"how long a REST response may take once the request headers are read, the ndjson stream extends it on every write; 0 for no limit"`
+	RESTIdleTimeout          time.Duration `config:"rest_idle_timeout" env:"REST_IDLE_TIMEOUT" usage:"how long an idle keep-alive REST connection stays open"`
+	RESTMaxHeaderBytes       Size          `config:"rest_max_header_bytes" env:"REST_MAX_HEADER_BYTES" usage:"largest REST request headers accepted, e.g. 64KiB"`
+	CompressionSkipThreshold time.Duration `config:"compression_skip_threshold" env:"COMPRESSION_SKIP_THRESHOLD" usage:"stream messages are no longer compressed with less than that left before the deadline, 0 disables it"`
+	CancelPropagationAlert   time.Duration `config:"cancel_propagation_alert" env:"CANCEL_PROPAGATION_ALERT" usage:"logs the work still running that long after a disconnect"`
+	RESTMaxInflight          int           `config:"rest_max_inflight" env:"REST_MAX_INFLIGHT" usage:"REST requests in flight beyond which new ones are shed"`
+	RESTMaxLatency           time.Duration `config:"rest_max_latency" env:"REST_MAX_LATENCY" usage:"REST latency beyond which new requests are shed"`
+	PrimeCache               bool          `config:"prime_cache" env:"PRIME_CACHE" usage:"fill the List cache at startup"`
+	PrimeTimeout             time.Duration `config:"prime_timeout" env:"PRIME_TIMEOUT" usage:"how long priming the cache may take"`
+	StrictCancellation       string        `config:"strict_cancellation" env:"STRICT_CANCELLATION" flag:"strict-cancellation" usage:"off, log or fail: what to do with a gRPC handler still running strict_cancellation_grace after its context ended; fail fails the call and the server"`
+	StrictCancellationGrace  time.Duration `config:"strict_cancellation_grace" env:"STRICT_CANCELLATION_GRACE" usage:"how long a gRPC handler may run on after its context ended in strict mode"`
+
+	NodeID                 int64   `config:"node_id" env:"NODE_ID" usage:"node number in the generated ids"`
+	IDGenerator            string  `config:"id_generator" env:"ID_GENERATOR" usage:"ulid, snowflake or uuid"`
+	RepositoryFixtures     string  `config:"repository_fixtures" env:"REPOSITORY_FIXTURES" usage:"JSON file the repository starts from"`
+	RepositoryConflictRate float64 `config:"repository_conflict_rate" env:"REPOSITORY_CONFLICT_RATE" usage:"share of the repository transactions failing with a serialization error"`
+
+	RepositoryLockSlots int           `config:"repository_lock_slots" env:"REPOSITORY_LOCK_SLOTS" usage:"transactions holding a simulated lock at once, 0 disables the contention simulator"`
+	RepositoryLockQueue int           `config:"repository_lock_queue" env:"REPOSITORY_LOCK_QUEUE" usage:"transactions waiting for a lock before more are refused, 0 lets the line grow"`
+	RepositoryWriteHold time.Duration `config:"repository_write_hold" env:"REPOSITORY_WRITE_HOLD" usage:"how long a write transaction holds its lock"`
+	RepositoryReadHold  time.Duration `config:"repository_read_hold" env:"REPOSITORY_READ_HOLD" usage:"how long a read transaction holds its lock"`
+
+	GRPCWebsocket  bool   `config:"grpc_websocket" env:"GRPC_WEBSOCKET" usage:"serve gRPC through a WebSocket on the REST server"`
+	GRPCReflection bool   `config:"grpc_reflection" env:"GRPC_REFLECTION" flag:"grpc-reflection" usage:"register the gRPC reflection service for grpcurl and evans, keep it off in production"`
+	AdminToken     string `config:"admin_token" env:"ADMIN_TOKEN" secret:"true" usage:"registers the AdminService and the /admin REST routes, their calls must send it as a bearer token"`
+	AuditLog       string `config:"audit_log" env:"AUDIT_LOG" usage:"file the audit log is appended to, empty disables it"`
+	Zone           string `config:"zone" env:"ZONE" usage:"zone the server runs in"`
+
+	TLSCert       string `config:"tls_cert" env:"TLS_CERT" flag:"tls-cert" usage:"PEM certificate the gRPC server presents, with tls_key; empty serves plaintext"`
+	TLSKey        string `config:"tls_key" env:"TLS_KEY" flag:"tls-key" usage:"PEM private key of tls_cert"`
+	TLSClientCA   string `config:"tls_client_ca" env:"TLS_CLIENT_CA" flag:"tls-client-ca" usage:"PEM CA bundle the gRPC clients must present a certificate of (mutual TLS), empty does not ask for one"`
+	TLSSelfSigned bool   `config:"tls_self_signed" env:"TLS_SELF_SIGNED" usage:"serve gRPC over TLS with a certificate generated at startup when tls_cert is empty, for development only"`
+
+	RESTTLS            bool   `config:"rest_tls" env:"REST_TLS" usage:"serve the REST API over HTTPS with the certificate of gRPC, tls_cert or tls_self_signed, and its client CA"`
+	RESTRedirectListen string `config:"rest_redirect_listen" env:"REST_REDIRECT_LISTEN" usage:"comma separated plaintext addresses redirecting to the HTTPS REST API, e.g. :8080; empty disables them"`
+
+	SinglePort bool `config:"single_port" env:"SINGLE_PORT" usage:"serve gRPC and the REST API on the REST addresses, told apart by the first bytes of each connection; grpc_listen is then unused"`
+
+	AuthSecret        string        `config:"auth_secret" env:"AUTH_SECRET,RBAC_SECRET" secret:"true" usage:"HMAC key of the HS256 tokens every call must send, empty disables authentication"`
+	AuthPublicMethods List          `config:"auth_public_methods" env:"AUTH_PUBLIC_METHODS" usage:"comma separated methods called without a token"`
+	RBACPolicy        string        `config:"rbac_policy" env:"RBAC_POLICY" usage:"JSON file mapping methods to permissions and roles to permissions, empty disables the checks"`
+	RBACTimeout       time.Duration `config:"rbac_timeout" env:"RBAC_TIMEOUT" usage:"longest a permission check may take, never more than a tenth of the call deadline"`
+
+	RateLimit       float64 `config:"rate_limit" env:"RATE_LIMIT" usage:"gRPC calls per second allowed to each client, 0 disables the limit"`
+	RateLimitBurst  int     `config:"rate_limit_burst" env:"RATE_LIMIT_BURST" usage:"gRPC calls a client may make at once above the rate"`
+	RateLimitByPeer bool    `config:"rate_limit_by_peer" env:"RATE_LIMIT_BY_PEER" usage:"key the rate limit by peer address even for authenticated callers"`
+
+	GCPercent     int  `config:"gc_percent" env:"GC_PERCENT" usage:"GC target percentage, 0 keeps GOGC"`
+	GCMemoryLimit Size `config:"gc_memory_limit" env:"GC_MEMORY_LIMIT" usage:"soft memory limit, e.g. 512MiB, 0 keeps GOMEMLIMIT"`
+	GCBallast     Size `config:"gc_ballast" env:"GC_BALLAST" usage:"size of the heap ballast, 0 disables it"`
+}
+
+// DefaultServer returns the defaults of the server settings.
+func DefaultServer() Server {
+	return Server{
+		GRPCPort:                 9099,
+		RESTPort:                 8099,
+		StreamInterval:           time.Second,
+		ListSize:                 49,
+		StreamLimit:              100,
+		ShutdownTimeout:          10 * time.Second,
+		DefaultDeadline:          30 * time.Second,
+		HandlerCeiling:           10 * time.Minute,
+		RBACTimeout:              50 * time.Millisecond,
+		RepositoryWriteHold:      200 * time.Millisecond,
+		RateLimitBurst:           20,
+		RepositoryReadHold:       20 * time.Millisecond,
+		RESTWriteTimeout:         5 * time.Second,
+		RESTReadHeaderTimeout:    5 * time.Second,
+		RESTReadTimeout:          30 * time.Second,
+		RESTResponseTimeout:      time.Minute,
+		RESTIdleTimeout:          2 * time.Minute,
+		RESTMaxHeaderBytes:       1 << 20,
+		CompressionSkipThreshold: 100 * time.Millisecond,
+		CancelPropagationAlert:   250 * time.Millisecond,
+		RESTMaxInflight:          32,
+		RESTMaxLatency:           8 * time.Second,
+		PrimeTimeout:             10 * time.Second,
+		StrictCancellation:       "off",
+		StrictCancellationGrace:  100 * time.Millisecond,
+		IDGenerator:              "ulid",
+		AuthPublicMethods: List{
+			"/grpc.health.v1.Health/Check",
+			"/grpc.health.v1.Health/Watch",
+			"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+		},
+		KeepaliveTime:                time.Minute,
+		KeepaliveTimeout:             20 * time.Second,
+		KeepaliveMinTime:             10 * time.Second,
+		KeepalivePermitWithoutStream: true,
+	}
+}
+
+// Validate reports every setting out of range at once.
+func (s *Server) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(s.GRPCPort > 0 && s.GRPCPort < 1<<16, "grpc_port %d out of range", s.GRPCPort)
+	check(s.RESTPort > 0 && s.RESTPort < 1<<16, "rest_port %d out of range", s.RESTPort)
+	check(s.GRPCListen != "" || s.GRPCPort != s.RESTPort, "grpc_port and rest_port are both %d", s.GRPCPort)
+	check(s.StreamInterval >= 0, "stream_interval %s is negative", s.StreamInterval)
+	check(s.ListSize > 0, "list_size %d must be positive", s.ListSize)
+	check(s.SoftDeadline >= 0 && s.SoftDeadline < 1, "soft_deadline %v must be at least 0 and below 1", s.SoftDeadline)
+	check(s.StreamLimit >= 0, "stream_limit %d is negative", s.StreamLimit)
+	check(s.KeepaliveTime >= 0, "keepalive_time %s is negative", s.KeepaliveTime)
+	check(s.KeepaliveTimeout >= 0, "keepalive_timeout %s is negative", s.KeepaliveTimeout)
+	check(s.KeepaliveMinTime >= 0, "keepalive_min_time %s is negative", s.KeepaliveMinTime)
+	check(s.MaxConnectionIdle >= 0, "max_connection_idle %s is negative", s.MaxConnectionIdle)
+	check(s.MaxConnectionAge >= 0, "max_connection_age %s is negative", s.MaxConnectionAge)
+	check(s.MaxConnectionAgeGrace >= 0, "max_connection_age_grace %s is negative", s.MaxConnectionAgeGrace)
+	check(s.ShutdownTimeout > 0, "shutdown_timeout %s must be positive", s.ShutdownTimeout)
+	check(s.DefaultDeadline >= 0, "default_deadline %s is negative", s.DefaultDeadline)
+	check(s.HandlerCeiling >= 0, "handler_ceiling %s is negative", s.HandlerCeiling)
+	check(s.RESTWriteTimeout > 0, "rest_write_timeout %s must be positive", s.RESTWriteTimeout)
+	check(s.RESTReadHeaderTimeout > 0, "rest_read_header_timeout %s must be positive", s.RESTReadHeaderTimeout)
+	check(s.RESTReadTimeout >= 0, "rest_read_timeout %s is negative", s.RESTReadTimeout)
+	check(s.RESTResponseTimeout >= 0, "rest_response_timeout %s is negative", s.RESTResponseTimeout)
+	check(s.RESTIdleTimeout > 0, "rest_idle_timeout %s must be positive", s.RESTIdleTimeout)
+	check(s.RESTMaxHeaderBytes >= 4<<10, "rest_max_header_bytes %s must be at least 4KiB", s.RESTMaxHeaderBytes)
+	check(s.CompressionSkipThreshold >= 0, "compression_skip_threshold %s is negative", s.CompressionSkipThreshold)
+	check(s.RESTMaxInflight > 0, "rest_max_inflight %d must be positive", s.RESTMaxInflight)
+	check(s.RESTMaxLatency > 0, "rest_max_latency %s must be positive", s.RESTMaxLatency)
+	check(s.PrimeTimeout > 0, "prime_timeout %s must be positive", s.PrimeTimeout)
+	check(s.StrictCancellation == "off" || s.StrictCancellation == "log" || s.StrictCancellation == "fail", "strict_cancellation %q must be off, log or fail", s.StrictCancellation)
+	check(s.StrictCancellationGrace > 0, "strict_cancellation_grace %s must be positive", s.StrictCancellationGrace)
+	check((s.TLSCert == "") == (s.TLSKey == ""), "tls_cert and tls_key go together")
+	check(s.TLSClientCA == "" || s.TLSCert != "" || s.TLSSelfSigned, "tls_client_ca needs tls_cert or tls_self_signed")
+	check(!s.RESTTLS || s.TLSCert != "" || s.TLSSelfSigned, "rest_tls needs tls_cert or tls_self_signed")
+	check(s.RESTRedirectListen == "" || s.RESTTLS, "rest_redirect_listen needs rest_tls")
+	check(!s.SinglePort || (s.TLSCert == "" && !s.TLSSelfSigned), "single_port serves plaintext only, without tls_cert or tls_self_signed")
+	check(s.RBACPolicy == "" || s.AuthSecret != "", "rbac_policy needs auth_secret, the roles come from the tokens")
+	check(s.RBACTimeout > 0, "rbac_timeout %s must be positive", s.RBACTimeout)
+	check(s.RateLimit >= 0, "rate_limit %v is negative", s.RateLimit)
+	check(s.RateLimit == 0 || s.RateLimitBurst >= 1, "rate_limit_burst %d must be at least 1", s.RateLimitBurst)
+	check(s.RepositoryConflictRate >= 0 && s.RepositoryConflictRate <= 1, "repository_conflict_rate %v must be between 0 and 1", s.RepositoryConflictRate)
+	check(s.RepositoryLockSlots >= 0, "repository_lock_slots %d is negative", s.RepositoryLockSlots)
+	check(s.RepositoryLockQueue >= 0, "repository_lock_queue %d is negative", s.RepositoryLockQueue)
+	check(s.RepositoryWriteHold >= 0, "repository_write_hold %s is negative", s.RepositoryWriteHold)
+	check(s.RepositoryReadHold >= 0, "repository_read_hold %s is negative", s.RepositoryReadHold)
+	return errors.Join(errs...)
+}
+
+// GRPCDefaultListen is where gRPC listens without GRPCListen.
+func (s *Server) GRPCDefaultListen() string {
+	return ":" + strconv.Itoa(s.GRPCPort)
+}
+
+// RESTDefaultListen is where REST listens without RESTListen.
+func (s *Server) RESTDefaultListen() string {
+	return ":" + strconv.Itoa(s.RESTPort)
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Size is a number of bytes, written with an optional unit as 512MiB or 1GB.
+type Size int64
+
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+	{"B", 1},
+}
+
+// UnmarshalText reads a size like 512MiB.
+func (s *Size) UnmarshalText(text []byte) error {
+	str, factor := string(text), int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(str, u.suffix) {
+			str, factor = strings.TrimSuffix(str, u.suffix), u.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(str), 10, 64)
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return fmt.Errorf("negative size %d", n)
+	}
+	*s = Size(n * factor)
+	return nil
+}
+
+// String returns the size in bytes.
+func (s Size) String() string {
+	return strconv.FormatInt(int64(s), 10)
+}
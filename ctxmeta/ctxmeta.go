@@ -0,0 +1,263 @@
+// Package ctxmeta carries the request id, the user id, the locale and the
+// deadline budget of a call in its context, and copies them from and to
+// gRPC metadata and HTTP headers, so the gRPC interceptors and the REST
+// handlers propagate them the same way.
+//
+// The budget is the deadline of the context itself. gRPC sends it on its
+// own, over HTTP it travels in the Grpc-Timeout header.
+package ctxmeta
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-cancel/deadline"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// The gRPC metadata keys.
+const (
+	RequestIDKey = "request-id"
+	UserIDKey    = "user-id"
+	LocaleKey    = "locale"
+)
+
+// The HTTP headers.
+const (
+	RequestIDHeader = "X-Request-ID"
+	UserIDHeader    = "X-User-ID"
+	LocaleHeader    = "Accept-Language"
+	TimeoutHeader   = "Grpc-Timeout"
+)
+
+type key int
+
+const (
+	requestIDKey key = iota
+	userIDKey
+	localeKey
+)
+
+// WithRequestID returns a copy of ctx carrying the request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request id of ctx, empty without one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// maxRequestIDLength bounds the request ids taken from clients, they end up
+// in every log line of the call.
+const maxRequestIDLength = 64
+
+// EnsureRequestID makes up the request id of ctx when it has none, or an
+// invalid one, and returns it.
+func EnsureRequestID(ctx context.Context) (context.Context, string) {
+	id := RequestID(ctx)
+	if !validRequestID(id) {
+		id = newRequestID()
+		ctx = WithRequestID(ctx, id)
+	}
+	return ctx, id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts the printable ASCII ids a log line can carry as is.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithUserID returns a copy of ctx carrying the id of the caller.
+func WithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey, id)
+}
+
+// UserID returns the id of the caller, empty without one.
+func UserID(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey).(string)
+	return id
+}
+
+// WithLocale returns a copy of ctx carrying the locale, e.g. en-US.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// Locale returns the locale of ctx, empty without one.
+func Locale(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey).(string)
+	return locale
+}
+
+// Budget returns the time left before the deadline of ctx, ok is false
+// without one.
+func Budget(ctx context.Context) (remaining time.Duration, ok bool) {
+	return deadline.RemainingBudget(ctx)
+}
+
+// WithBudget returns a copy of ctx ending after budget at the latest.
+func WithBudget(ctx context.Context, budget time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, budget)
+}
+
+// FromIncoming copies the values of the incoming gRPC metadata of ctx into
+// ctx. A value already in ctx is kept.
+func FromIncoming(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	return fill(ctx, first(RequestIDKey), first(UserIDKey), first(LocaleKey))
+}
+
+// ToOutgoing appends the values of ctx to its outgoing gRPC metadata.
+func ToOutgoing(ctx context.Context) context.Context {
+	var kv []string
+	for _, p := range []struct{ key, value string }{
+		{RequestIDKey, RequestID(ctx)},
+		{UserIDKey, UserID(ctx)},
+		{LocaleKey, Locale(ctx)},
+	} {
+		if p.value != "" {
+			kv = append(kv, p.key, p.value)
+		}
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// FromHTTP copies the values of the request headers into ctx, the budget of
+// the Grpc-Timeout header too, so a Grpc-Timeout of zero gives a ctx that
+// has already expired. cancel releases the budget.
+func FromHTTP(ctx context.Context, h http.Header) (_ context.Context, cancel context.CancelFunc, err error) {
+	locale := h.Get(LocaleHeader)
+	// Only the preferred language of Accept-Language.
+	if i := strings.IndexAny(locale, ",;"); i >= 0 {
+		locale = locale[:i]
+	}
+	ctx = fill(ctx, h.Get(RequestIDHeader), h.Get(UserIDHeader), strings.TrimSpace(locale))
+
+	budget, ok, err := ParseTimeout(h.Get(TimeoutHeader))
+	if err != nil {
+		return ctx, func() {}, err
+	}
+	if ok {
+		ctx, cancel = WithBudget(ctx, budget)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
+
+// ToHTTP sets the request headers of the values of ctx, the time it has
+// left as Grpc-Timeout.
+func ToHTTP(ctx context.Context, h http.Header) {
+	for _, p := range []struct{ header, value string }{
+		{RequestIDHeader, RequestID(ctx)},
+		{UserIDHeader, UserID(ctx)},
+		{LocaleHeader, Locale(ctx)},
+	} {
+		if p.value != "" {
+			h.Set(p.header, p.value)
+		}
+	}
+	if remaining, ok := Budget(ctx); ok {
+		h.Set(TimeoutHeader, FormatTimeout(remaining))
+	}
+}
+
+func fill(ctx context.Context, requestID, userID, locale string) context.Context {
+	if requestID != "" && RequestID(ctx) == "" {
+		ctx = WithRequestID(ctx, requestID)
+	}
+	if userID != "" && UserID(ctx) == "" {
+		ctx = WithUserID(ctx, userID)
+	}
+	if locale != "" && Locale(ctx) == "" {
+		ctx = WithLocale(ctx, locale)
+	}
+	return ctx
+}
+
+// maxTimeout is the longest timeout ParseTimeout returns, eight digits of
+// hours do not fit in a time.Duration.
+const maxTimeout = time.Duration(math.MaxInt64)
+
+// ParseTimeout reads a timeout in the format of the grpc-timeout header: at
+// most eight digits followed by a unit. ok is false for an empty value,
+// which means no timeout, while a zero timeout has already run out. A
+// timeout above maxTimeout is clamped to it.
+func ParseTimeout(v string) (d time.Duration, ok bool, err error) {
+	if v == "" {
+		return 0, false, nil
+	}
+	if len(v) < 2 || len(v) > 9 {
+		return 0, false, fmt.Errorf("invalid Grpc-Timeout %q", v)
+	}
+
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[v[len(v)-1]]
+	if !ok {
+		return 0, false, fmt.Errorf("invalid Grpc-Timeout unit in %q", v)
+	}
+
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("invalid Grpc-Timeout %q", v)
+	}
+	if n > int64(maxTimeout/unit) {
+		return maxTimeout, true, nil
+	}
+	return time.Duration(n) * unit, true, nil
+}
+
+// FormatTimeout writes d in the format of the grpc-timeout header, in
+// milliseconds rounded up, so a spent budget is sent as 1m rather than as
+// none. Budgets too long for eight digits go in seconds.
+func FormatTimeout(d time.Duration) string {
+	ms := int64((d + time.Millisecond - 1) / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	if ms > 99999999 {
+		return strconv.FormatInt(ms/1000, 10) + "S"
+	}
+	return strconv.FormatInt(ms, 10) + "m"
+}
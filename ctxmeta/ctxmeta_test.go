@@ -0,0 +1,49 @@
+package ctxmeta
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		v       string
+		want    time.Duration
+		wantOK  bool
+		wantErr bool
+	}{
+		{v: ""},
+		{v: "0m", wantOK: true},
+		{v: "1500m", want: 1500 * time.Millisecond, wantOK: true},
+		{v: "3S", want: 3 * time.Second, wantOK: true},
+		{v: "99999999n", want: 99999999, wantOK: true},
+		{v: "99999999H", want: maxTimeout, wantOK: true},
+		{v: "2562048H", want: maxTimeout, wantOK: true},
+		{v: "2562047H", want: 2562047 * time.Hour, wantOK: true},
+		{v: "m", wantErr: true},
+		{v: "123456789m", wantErr: true},
+		{v: "10x", wantErr: true},
+		{v: "-1m", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := ParseTimeout(tt.v)
+		if (err != nil) != tt.wantErr || got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseTimeout(%q): got %s, %v, %v, want %s, %v, error %v", tt.v, got, ok, err, tt.want, tt.wantOK, tt.wantErr)
+		}
+	}
+}
+
+// TestFromHTTPZeroTimeout sends a Grpc-Timeout of zero: the budget has
+// already run out, it is not taken as no timeout.
+func TestFromHTTPZeroTimeout(t *testing.T) {
+	ctx, cancel, err := FromHTTP(context.Background(), http.Header{TimeoutHeader: {"0m"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	if _, ok := ctx.Deadline(); !ok || ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("ctx has a deadline %v, err %v, want an expired one", ok, ctx.Err())
+	}
+}
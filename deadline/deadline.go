@@ -0,0 +1,51 @@
+// Package deadline computes how much time a context has left without being
+// fooled by the wall clock.
+//
+// Go measures durations between times that carry a monotonic clock reading
+// on that clock, so time.Now().Add(d) deadlines, the ones context.WithTimeout
+// and gRPC create, are immune to NTP steps and manual clock changes. A
+// deadline built from a wall clock value, such as time.Unix or a parsed
+// timestamp, has no monotonic reading: the time left jumps with the clock.
+// Normalize pins such a deadline to the monotonic clock once.
+package deadline
+
+import (
+	"context"
+	"time"
+)
+
+// RemainingBudget returns the time left before the deadline of ctx, never
+// below zero. ok is false when ctx has no deadline.
+func RemainingBudget(ctx context.Context) (remaining time.Duration, ok bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+
+	remaining = time.Until(deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// Monotonic reports whether t carries a monotonic clock reading. Round(0)
+// strips the reading, so it only changes t when there is one.
+func Monotonic(t time.Time) bool {
+	return t != t.Round(0)
+}
+
+// Normalize returns ctx unchanged unless its deadline lacks a monotonic
+// reading. Then the deadline is replaced by one the same distance away on
+// the monotonic clock, so later clock jumps no longer move it. The returned
+// cancel must be called like the one of context.WithDeadline.
+func Normalize(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || Monotonic(deadline) {
+		return ctx, func() {}
+	}
+
+	// The parent keeps its wall clock deadline, a clock jumping forward
+	// still ends it early. The child ends at the pinned time at the latest.
+	return context.WithTimeout(ctx, time.Until(deadline))
+}
@@ -0,0 +1,58 @@
+package deadline
+
+import (
+	"context"
+	"time"
+)
+
+// Soft is a warning deadline ahead of the hard one: it passes once fraction
+// of the budget ctx had when it was created is consumed, while the call
+// still has time to log it, switch to a cheaper code path or flush what it
+// has. The hard deadline still cancels ctx.
+type Soft struct {
+	done  chan struct{}
+	timer *time.Timer
+}
+
+// NewSoft returns the soft deadline of ctx at fraction of its remaining
+// budget, calling fn, when not nil, on its own goroutine when it passes. A
+// context without a deadline, or a fraction outside (0, 1), gets a soft
+// deadline that never passes. Stop must be called once the work is done.
+func NewSoft(ctx context.Context, fraction float64, fn func()) *Soft {
+	s := &Soft{done: make(chan struct{})}
+	remaining, ok := RemainingBudget(ctx)
+	if !ok || fraction <= 0 || fraction >= 1 {
+		return s
+	}
+
+	s.timer = time.AfterFunc(time.Duration(float64(remaining)*fraction), func() {
+		close(s.done)
+		if fn != nil {
+			fn()
+		}
+	})
+	return s
+}
+
+// Done is closed when the soft deadline passes.
+func (s *Soft) Done() <-chan struct{} {
+	return s.done
+}
+
+// Passed reports whether the soft deadline passed.
+func (s *Soft) Passed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop keeps the soft deadline from passing, and its callback from running,
+// if it has not yet.
+func (s *Soft) Stop() {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
@@ -0,0 +1,127 @@
+package grpcerr
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Retryable reports whether the same call may succeed later as it is: the
+// server was unavailable, overloaded or lost a conflict. A call that ran out
+// of time is not, it needs a new deadline first.
+func Retryable(err error) bool {
+	switch status.Code(FromError(err)) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// CallerFault reports whether err is down to the call itself, so repeating
+// it fails again and nothing is wrong with the server: a bad or forbidden
+// request, or one the caller cancelled.
+func CallerFault(err error) bool {
+	switch status.Code(FromError(err)) {
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition,
+		codes.OutOfRange, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+// RetryDelay returns the delay of the RetryInfo detail of a retryable err,
+// ok is false without one.
+func RetryDelay(err error) (delay time.Duration, ok bool) {
+	if !Retryable(err) {
+		return 0, false
+	}
+	for _, d := range status.Convert(FromError(err)).Details() {
+		if info, isInfo := d.(*errdetails.RetryInfo); isInfo && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// RetryError returns an error with code and msg asking the client to come
+// back after delay, as a RetryInfo that RetryDelay reads back and WriteHTTP
+// turns into Retry-After. The servers build their retry errors with it.
+func RetryError(code codes.Code, msg string, delay time.Duration) error {
+	st, err := status.New(code, msg).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(delay),
+	})
+	if err != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
+
+// Unreachable reports whether err says the server could not be reached at
+// all: Unavailable without a RetryInfo. A server that is up but sheds the
+// call says when to come back, another route to it would be shed too.
+func Unreachable(err error) bool {
+	if status.Code(FromError(err)) != codes.Unavailable {
+		return false
+	}
+	_, wait := RetryDelay(err)
+	return !wait
+}
+
+// WriteHTTP answers a REST request with err, its message as the body. A
+// retryable error with a RetryInfo also gets a Retry-After header, in whole
+// seconds rounded up.
+func WriteHTTP(w http.ResponseWriter, err error) {
+	st := status.Convert(FromError(err))
+	setRetryAfter(w, err)
+	http.Error(w, st.Message(), HTTPStatus(st.Code()))
+}
+
+// HTTPError is the JSON body of the errors of the REST API. Code is the name
+// of the gRPC code, DeadlineExceeded for example, the HTTP status only says
+// 504.
+type HTTPError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Err returns the status error e carries, so a REST client gets back the
+// code of the call. A code e does not name is Unknown.
+func (e HTTPError) Err() error {
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if c.String() == e.Code {
+			return status.Error(c, e.Message)
+		}
+	}
+	return status.Error(codes.Unknown, e.Message)
+}
+
+// WriteJSON answers a REST request with err like WriteHTTP, the body an
+// HTTPError carrying requestID.
+func WriteJSON(w http.ResponseWriter, err error, requestID string) {
+	st := status.Convert(FromError(err))
+	setRetryAfter(w, err)
+	body, _ := json.Marshal(HTTPError{Code: st.Code().String(), Message: st.Message(), RequestID: requestID})
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(HTTPStatus(st.Code()))
+	w.Write(append(body, '\n'))
+}
+
+// setRetryAfter sets the Retry-After header of a retryable err with a
+// RetryInfo, in whole seconds rounded up.
+func setRetryAfter(w http.ResponseWriter, err error) {
+	if delay, ok := RetryDelay(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int((delay+time.Second-1)/time.Second)))
+	}
+}
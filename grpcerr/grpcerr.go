@@ -0,0 +1,115 @@
+// Package grpcerr turns context and Go errors into gRPC status errors, and
+// status codes into HTTP status codes, so the gRPC handlers and the REST
+// gateway report a cancelled call the same way.
+package grpcerr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StatusClientClosedRequest is the non-standard code nginx logs when the
+// client went away before the response, there is no net/http constant for it.
+const StatusClientClosedRequest = 499
+
+// FromContext returns the status error matching why ctx ended, or nil while
+// ctx is still running. A cause set with context.WithCancelCause, or one of
+// its relatives, is kept in the message, a cause that is a status error
+// itself is returned as is.
+func FromContext(ctx context.Context) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+
+	cause := context.Cause(ctx)
+	if cause != nil && cause != err {
+		if st, ok := fromStatus(cause); ok {
+			return st.Err()
+		}
+	}
+
+	var msg string
+	switch err {
+	case context.Canceled:
+		msg = "request is canceled"
+	case context.DeadlineExceeded:
+		msg = "deadline is exceeded"
+	}
+	if cause != nil && cause != err {
+		msg += ": " + cause.Error()
+	}
+
+	return status.Error(codeOf(err), msg)
+}
+
+// FromError converts err to a status error. Status errors, even wrapped
+// ones, keep their code, context errors become Canceled or DeadlineExceeded
+// and anything else is Unknown.
+func FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if st, ok := fromStatus(err); ok {
+		return st.Err()
+	}
+
+	return status.Error(codeOf(err), err.Error())
+}
+
+func fromStatus(err error) (*status.Status, bool) {
+	var se interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &se) {
+		return nil, false
+	}
+	return se.GRPCStatus(), true
+}
+
+func codeOf(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	default:
+		return codes.Unknown
+	}
+}
+
+// HTTPStatus returns the HTTP status code the REST gateway answers with for
+// a gRPC code. It follows the mapping of grpc-gateway.
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return StatusClientClosedRequest
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
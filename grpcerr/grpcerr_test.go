@@ -0,0 +1,277 @@
+package grpcerr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFromContext(t *testing.T) {
+	errShutdown := errors.New("server shutting down")
+
+	tests := []struct {
+		name     string
+		ctx      func(t *testing.T) context.Context
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{
+			name:     "running",
+			ctx:      func(*testing.T) context.Context { return context.Background() },
+			wantCode: codes.OK,
+		},
+		{
+			name: "canceled",
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			wantCode: codes.Canceled,
+			wantMsg:  "request is canceled",
+		},
+		{
+			name: "deadline exceeded",
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+				t.Cleanup(cancel)
+				return ctx
+			},
+			wantCode: codes.DeadlineExceeded,
+			wantMsg:  "deadline is exceeded",
+		},
+		{
+			name: "custom cause",
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithCancelCause(context.Background())
+				cancel(errShutdown)
+				return ctx
+			},
+			wantCode: codes.Canceled,
+			wantMsg:  "request is canceled: server shutting down",
+		},
+		{
+			name: "wrapped custom cause",
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithCancelCause(context.Background())
+				cancel(fmt.Errorf("drain: %w", errShutdown))
+				return ctx
+			},
+			wantCode: codes.Canceled,
+			wantMsg:  "request is canceled: drain: server shutting down",
+		},
+		{
+			name: "status cause",
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithCancelCause(context.Background())
+				cancel(status.Error(codes.Unavailable, "maintenance"))
+				return ctx
+			},
+			wantCode: codes.Unavailable,
+			wantMsg:  "maintenance",
+		},
+		{
+			name: "wrapped status cause",
+			ctx: func(t *testing.T) context.Context {
+				ctx, cancel := context.WithCancelCause(context.Background())
+				cancel(fmt.Errorf("admin: %w", status.Error(codes.Aborted, "call cancelled by an operator")))
+				return ctx
+			},
+			wantCode: codes.Aborted,
+			wantMsg:  "call cancelled by an operator",
+		},
+		{
+			name: "cause of the parent",
+			ctx: func(t *testing.T) context.Context {
+				parent, cancel := context.WithCancelCause(context.Background())
+				ctx, stop := context.WithTimeout(parent, time.Hour)
+				t.Cleanup(stop)
+				cancel(errShutdown)
+				return ctx
+			},
+			wantCode: codes.Canceled,
+			wantMsg:  "request is canceled: server shutting down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromContext(tt.ctx(t))
+			if tt.wantCode == codes.OK {
+				if err != nil {
+					t.Fatalf("got %v, want nil", err)
+				}
+				return
+			}
+			st, ok := status.FromError(err)
+			if !ok || st.Code() != tt.wantCode || st.Message() != tt.wantMsg {
+				t.Fatalf("got %v, want %s %q", err, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{name: "nil", err: nil, wantCode: codes.OK},
+		{name: "canceled", err: context.Canceled, wantCode: codes.Canceled, wantMsg: "context canceled"},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, wantCode: codes.DeadlineExceeded, wantMsg: "context deadline exceeded"},
+		{
+			name:     "wrapped canceled",
+			err:      fmt.Errorf("read cities: %w", context.Canceled),
+			wantCode: codes.Canceled,
+			wantMsg:  "read cities: context canceled",
+		},
+		{
+			name:     "wrapped deadline exceeded",
+			err:      fmt.Errorf("step 3: %w", fmt.Errorf("query: %w", context.DeadlineExceeded)),
+			wantCode: codes.DeadlineExceeded,
+			wantMsg:  "step 3: query: context deadline exceeded",
+		},
+		{
+			name:     "status",
+			err:      status.Error(codes.NotFound, "no such city"),
+			wantCode: codes.NotFound,
+			wantMsg:  "no such city",
+		},
+		{
+			name:     "wrapped status",
+			err:      fmt.Errorf("lookup: %w", status.Error(codes.ResourceExhausted, "slow down")),
+			wantCode: codes.ResourceExhausted,
+			wantMsg:  "slow down",
+		},
+		{name: "other", err: errors.New("disk full"), wantCode: codes.Unknown, wantMsg: "disk full"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromError(tt.err)
+			if tt.wantCode == codes.OK {
+				if err != nil {
+					t.Fatalf("got %v, want nil", err)
+				}
+				return
+			}
+			st, ok := status.FromError(err)
+			if !ok || st.Code() != tt.wantCode || st.Message() != tt.wantMsg {
+				t.Fatalf("got %v, want %s %q", err, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.OK, http.StatusOK},
+		{codes.Canceled, StatusClientClosedRequest},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.Internal, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := HTTPStatus(tt.code); got != tt.want {
+			t.Errorf("HTTPStatus(%s) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPErrorErr(t *testing.T) {
+	for c := codes.OK + 1; c <= codes.Unauthenticated; c++ {
+		rec := httptest.NewRecorder()
+		WriteJSON(rec, status.Error(c, "boom"), "req-1")
+		var body HTTPError
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v", c, err)
+		}
+		if st := status.Convert(body.Err()); st.Code() != c || st.Message() != "boom" {
+			t.Errorf("%s: got back %v", c, body.Err())
+		}
+	}
+	if got := status.Code(HTTPError{Code: "Gone", Message: "boom"}.Err()); got != codes.Unknown {
+		t.Errorf("unknown code: got %s, want Unknown", got)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		code        codes.Code
+		retryable   bool
+		callerFault bool
+	}{
+		{code: codes.OK},
+		{code: codes.Canceled, callerFault: true},
+		{code: codes.Unknown},
+		{code: codes.InvalidArgument, callerFault: true},
+		{code: codes.DeadlineExceeded},
+		{code: codes.NotFound, callerFault: true},
+		{code: codes.AlreadyExists, callerFault: true},
+		{code: codes.PermissionDenied, callerFault: true},
+		{code: codes.ResourceExhausted, retryable: true},
+		{code: codes.FailedPrecondition, callerFault: true},
+		{code: codes.Aborted, retryable: true},
+		{code: codes.OutOfRange, callerFault: true},
+		{code: codes.Unimplemented, callerFault: true},
+		{code: codes.Internal},
+		{code: codes.Unavailable, retryable: true},
+		{code: codes.DataLoss},
+		{code: codes.Unauthenticated, callerFault: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			plain := status.Error(tt.code, "boom")
+			if got := Retryable(plain); got != tt.retryable {
+				t.Errorf("Retryable: got %v, want %v", got, tt.retryable)
+			}
+			if got := CallerFault(plain); got != tt.callerFault {
+				t.Errorf("CallerFault: got %v, want %v", got, tt.callerFault)
+			}
+			if got := Unreachable(plain); got != (tt.code == codes.Unavailable) {
+				t.Errorf("Unreachable: got %v", got)
+			}
+			if delay, ok := RetryDelay(plain); ok {
+				t.Errorf("RetryDelay without a RetryInfo: got %s", delay)
+			}
+
+			if tt.code == codes.OK {
+				return
+			}
+			// A RetryInfo only counts on a retryable error.
+			withInfo := RetryError(tt.code, "boom", 1500*time.Millisecond)
+			if st := status.Convert(withInfo); st.Code() != tt.code || st.Message() != "boom" {
+				t.Fatalf("RetryError: got %v", withInfo)
+			}
+			delay, ok := RetryDelay(withInfo)
+			if ok != tt.retryable || (ok && delay != 1500*time.Millisecond) {
+				t.Errorf("RetryDelay: got %s, %v", delay, ok)
+			}
+			if Unreachable(withInfo) {
+				t.Errorf("Unreachable with a RetryInfo: got true")
+			}
+
+			rec := httptest.NewRecorder()
+			WriteJSON(rec, withInfo, "req-1")
+			want := ""
+			if tt.retryable {
+				want = "2"
+			}
+			if got := rec.Header().Get("Retry-After"); got != want {
+				t.Errorf("Retry-After: got %q, want %q", got, want)
+			}
+		})
+	}
+}
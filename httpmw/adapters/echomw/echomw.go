@@ -0,0 +1,50 @@
+// Package echomw runs the httpmw middleware in Echo.
+package echomw
+
+import (
+	"errors"
+	"time"
+
+	"go-cancel/httpmw"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Defaults are RequestID, Timeout and Logging of httpmw, in that order, as
+// Echo middleware, Errors last.
+func Defaults(timeout time.Duration, logf func(format string, v ...interface{})) []echo.MiddlewareFunc {
+	return []echo.MiddlewareFunc{
+		echo.WrapMiddleware(httpmw.RequestID),
+		echo.WrapMiddleware(httpmw.Timeout(timeout)),
+		echo.WrapMiddleware(httpmw.Logging(logf)),
+		Errors,
+	}
+}
+
+// Errors hands the error of the handler to the HTTPErrorHandler of Echo
+// right away, while the request context still runs and before Logging logs
+// the status, where Echo would only do it once every middleware returned.
+func Errors(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+		return nil
+	}
+}
+
+// ErrorHandler is an echo.HTTPErrorHandler answering with the status of
+// httpmw.WriteError, 504 or 499 once the request context ended. The
+// echo.HTTPErrors of Echo itself, a 404 of the router for example, keep
+// their code.
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) && c.Request().Context().Err() == nil {
+		c.Echo().DefaultHTTPErrorHandler(err, c)
+		return
+	}
+	httpmw.WriteError(c.Response(), c.Request(), err)
+}
@@ -0,0 +1,72 @@
+// Package ginmw runs the httpmw middleware in Gin.
+package ginmw
+
+import (
+	"net/http"
+	"time"
+
+	"go-cancel/httpmw"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Wrap turns mw into a Gin middleware. The request, and the writer, mw hands
+// on replace those of the gin.Context for the rest of the chain; a request
+// mw answers itself aborts it.
+func Wrap(mw httpmw.Middleware) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		next := false
+		mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next = true
+			orig := c.Writer
+			if w != http.ResponseWriter(orig) {
+				c.Writer = &responseWriter{ResponseWriter: orig, w: w}
+			}
+			c.Request = r
+			c.Next()
+			c.Writer = orig
+		})).ServeHTTP(c.Writer, c.Request)
+		if !next {
+			c.Abort()
+		}
+	}
+}
+
+// Defaults are RequestID, Timeout and Logging of httpmw, in that order, as
+// Gin middleware, the errors set with c.Error last.
+func Defaults(timeout time.Duration, logf func(format string, v ...interface{})) []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		Wrap(httpmw.RequestID),
+		Wrap(httpmw.Timeout(timeout)),
+		Wrap(httpmw.Logging(logf)),
+		Errors,
+	}
+}
+
+// Errors answers a request whose handlers set an error with c.Error, and
+// wrote nothing, with the last of them, see httpmw.WriteError.
+func Errors(c *gin.Context) {
+	c.Next()
+	if err := c.Errors.Last(); err != nil && !c.Writer.Written() {
+		httpmw.WriteError(c.Writer, c.Request, err.Err)
+	}
+}
+
+// responseWriter sends the body through the writer of a middleware, a
+// status recorder for example, and the rest to Gin's.
+type responseWriter struct {
+	gin.ResponseWriter
+	w http.ResponseWriter
+}
+
+func (r *responseWriter) WriteHeader(code int) {
+	r.w.WriteHeader(code)
+}
+
+func (r *responseWriter) Write(b []byte) (int, error) {
+	return r.w.Write(b)
+}
+
+func (r *responseWriter) WriteString(s string) (int, error) {
+	return r.w.Write([]byte(s))
+}
@@ -0,0 +1,164 @@
+// Package httpmw is the request id, timeout, logging and error mapping of
+// the gRPC interceptors as net/http middleware, so a REST layer moved to a
+// router keeps the cancellation semantics of the gRPC side: the same
+// request ids and Grpc-Timeout budget, the same deadlines, the same log
+// lines and the same status codes for a call that ran out of time or whose
+// client went away.
+//
+// A Middleware is the func(http.Handler) http.Handler chi and net/http take
+// as is. Echo takes it through echo.WrapMiddleware, Gin through the ginmw
+// package of the adapters module next to this one.
+package httpmw
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go-cancel/ctxmeta"
+	"go-cancel/deadline"
+	"go-cancel/grpcerr"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Middleware wraps a handler.
+type Middleware = func(http.Handler) http.Handler
+
+// Chain applies mws to h, the first one outermost.
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// RequestID copies the request id, user id, locale and Grpc-Timeout budget
+// of the request headers into its context, makes up the request id when
+// the client sent none, and echoes it in the X-Request-ID response header.
+// An invalid Grpc-Timeout is answered with 400. It goes first.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel, err := ctxmeta.FromHTTP(r.Context(), r.Header)
+		defer cancel()
+		ctx, id := ctxmeta.EnsureRequestID(ctx)
+		w.Header().Set(ctxmeta.RequestIDHeader, id)
+		if err != nil {
+			grpcerr.WriteHTTP(w, status.Error(codes.InvalidArgument, err.Error()))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Timeout bounds every request by timeout, a client budget that is already
+// shorter wins.
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Logging logs every request once it returned with logf, log.Printf when
+// nil: its request id and client address, the deadline it had, the status
+// code and how long it took. A request whose context ended is logged with
+// why, whatever its handler answered.
+func Logging(logf func(format string, v ...interface{})) Middleware {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			start, budget := time.Now(), deadlineLeft(ctx)
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			elapsed := time.Since(start)
+
+			ended := ""
+			if ctx.Err() != nil {
+				ended = fmt.Sprintf(", context ended: %v", context.Cause(ctx))
+			}
+			id := ctxmeta.RequestID(ctx)
+			if id == "" {
+				id = "-"
+			}
+			logf("request-id %s %s %s from %s deadline %s: %d took %s%s", id, r.Method, r.URL.Path, r.RemoteAddr, budget, rec.code(), elapsed, ended)
+		})
+	}
+}
+
+// deadlineLeft is the time left before the deadline of ctx, as logged.
+func deadlineLeft(ctx context.Context) string {
+	remaining, ok := deadline.RemainingBudget(ctx)
+	if !ok {
+		return "none"
+	}
+	return remaining.Round(time.Millisecond).String()
+}
+
+// HandlerFunc is a handler returning its error, as a gRPC handler does,
+// for ServeHTTP to answer with WriteError.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (h HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// WriteError answers r with the HTTP status of err, see grpcerr.WriteHTTP.
+// Once the context of r ended, the answer is why, 504 or 499, as gRPC
+// reports DEADLINE_EXCEEDED or CANCELLED whatever the handler returned.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if ctxErr := grpcerr.FromContext(r.Context()); ctxErr != nil {
+		err = ctxErr
+	}
+	grpcerr.WriteHTTP(w, err)
+}
+
+// statusRecorder keeps the status code of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses streaming.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) code() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
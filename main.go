@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go-cancel/app"
+	"go-cancel/config"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		os.Exit(app.Selftest(os.Args[2:]))
+	}
+
+	cfg := config.DefaultServer()
+	settings := config.Bind(flag.CommandLine, "CONFIG_FILE", &cfg)
+	flag.Parse()
+	if err := settings.Load(); err != nil {
+		log.Printf("error: %s", err)
+		os.Exit(2)
+	}
+
+	// root is the context of everything the server does, handlers included.
+	root, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	a := app.New(cfg, settings.Values())
+	err := a.Start(root)
+	if err == nil {
+		select {
+		case sig := <-sigs:
+			log.Printf("received %s, draining the calls in flight for up to %s", sig, cfg.ShutdownTimeout)
+			err = a.Stop(context.Background())
+		case <-a.Done():
+			err = a.Wait()
+		}
+	}
+	if err != nil {
+		log.Printf("error: shutting down: %s", err)
+		os.Exit(1)
+	}
+}
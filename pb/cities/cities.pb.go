@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.25.0
+// 	protoc-gen-go v1.26.0
 // 	protoc        v3.13.0
 // source: cities.proto
 
@@ -8,7 +8,8 @@ package cities
 
 import (
 	context "context"
-	proto "github.com/golang/protobuf/proto"
+	_ "go-cancel/pb/timeouts"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -25,17 +26,126 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// This is a compile-time assertion that a sufficiently up-to-date version
-// of the legacy proto package is being used.
-const _ = proto.ProtoPackageIsVersion4
+type OverflowPolicy int32
+
+const (
+	// DROP_OLDEST discards the oldest buffered event to make room.
+	OverflowPolicy_DROP_OLDEST OverflowPolicy = 0
+	// DROP_NEWEST discards the event that did not fit.
+	OverflowPolicy_DROP_NEWEST OverflowPolicy = 1
+	// CANCEL_SUBSCRIBER ends the watch with RESOURCE_EXHAUSTED.
+	OverflowPolicy_CANCEL_SUBSCRIBER OverflowPolicy = 2
+)
+
+// Enum value maps for OverflowPolicy.
+var (
+	OverflowPolicy_name = map[int32]string{
+		0: "DROP_OLDEST",
+		1: "DROP_NEWEST",
+		2: "CANCEL_SUBSCRIBER",
+	}
+	OverflowPolicy_value = map[string]int32{
+		"DROP_OLDEST":       0,
+		"DROP_NEWEST":       1,
+		"CANCEL_SUBSCRIBER": 2,
+	}
+)
+
+func (x OverflowPolicy) Enum() *OverflowPolicy {
+	p := new(OverflowPolicy)
+	*p = x
+	return p
+}
+
+func (x OverflowPolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OverflowPolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_cities_proto_enumTypes[0].Descriptor()
+}
+
+func (OverflowPolicy) Type() protoreflect.EnumType {
+	return &file_cities_proto_enumTypes[0]
+}
+
+func (x OverflowPolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OverflowPolicy.Descriptor instead.
+func (OverflowPolicy) EnumDescriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{0}
+}
+
+// WatchStart says what a watch sends before the new cities, a client
+// watching again after a cancelled watch picks one.
+type WatchStart int32
+
+const (
+	// DELTAS_ONLY sends the cities created from now on.
+	WatchStart_DELTAS_ONLY WatchStart = 0
+	// RESUME replays the deltas after resume_after first. A snapshot takes
+	// their place when the server no longer holds all of them.
+	WatchStart_RESUME WatchStart = 1
+	// RESYNC sends a snapshot of every city first.
+	WatchStart_RESYNC WatchStart = 2
+)
+
+// Enum value maps for WatchStart.
+var (
+	WatchStart_name = map[int32]string{
+		0: "DELTAS_ONLY",
+		1: "RESUME",
+		2: "RESYNC",
+	}
+	WatchStart_value = map[string]int32{
+		"DELTAS_ONLY": 0,
+		"RESUME":      1,
+		"RESYNC":      2,
+	}
+)
+
+func (x WatchStart) Enum() *WatchStart {
+	p := new(WatchStart)
+	*p = x
+	return p
+}
+
+func (x WatchStart) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WatchStart) Descriptor() protoreflect.EnumDescriptor {
+	return file_cities_proto_enumTypes[1].Descriptor()
+}
+
+func (WatchStart) Type() protoreflect.EnumType {
+	return &file_cities_proto_enumTypes[1]
+}
+
+func (x WatchStart) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WatchStart.Descriptor instead.
+func (WatchStart) EnumDescriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{1}
+}
 
 type City struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id   uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Id   string `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
 	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// detail is only set by calls that ask for enrichment.
+	Detail *CityDetail `protobuf:"bytes,4,opt,name=detail,proto3" json:"detail,omitempty"`
+	// sealed replaces every other field when the client asked for encryption
+	// with the city-key metadata. It is the City encrypted with the key of the
+	// request, see package citycrypt.
+	Sealed []byte `protobuf:"bytes,5,opt,name=sealed,proto3" json:"sealed,omitempty"`
 }
 
 func (x *City) Reset() {
@@ -70,11 +180,11 @@ func (*City) Descriptor() ([]byte, []int) {
 	return file_cities_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *City) GetId() uint32 {
+func (x *City) GetId() string {
 	if x != nil {
 		return x.Id
 	}
-	return 0
+	return ""
 }
 
 func (x *City) GetName() string {
@@ -84,6 +194,75 @@ func (x *City) GetName() string {
 	return ""
 }
 
+func (x *City) GetDetail() *CityDetail {
+	if x != nil {
+		return x.Detail
+	}
+	return nil
+}
+
+func (x *City) GetSealed() []byte {
+	if x != nil {
+		return x.Sealed
+	}
+	return nil
+}
+
+type CityDetail struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Region     string `protobuf:"bytes,1,opt,name=region,proto3" json:"region,omitempty"`
+	Population uint32 `protobuf:"varint,2,opt,name=population,proto3" json:"population,omitempty"`
+}
+
+func (x *CityDetail) Reset() {
+	*x = CityDetail{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CityDetail) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CityDetail) ProtoMessage() {}
+
+func (x *CityDetail) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CityDetail.ProtoReflect.Descriptor instead.
+func (*CityDetail) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CityDetail) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *CityDetail) GetPopulation() uint32 {
+	if x != nil {
+		return x.Population
+	}
+	return 0
+}
+
 type EmptyMessage struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -93,7 +272,7 @@ type EmptyMessage struct {
 func (x *EmptyMessage) Reset() {
 	*x = EmptyMessage{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_cities_proto_msgTypes[1]
+		mi := &file_cities_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -106,7 +285,7 @@ func (x *EmptyMessage) String() string {
 func (*EmptyMessage) ProtoMessage() {}
 
 func (x *EmptyMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_cities_proto_msgTypes[1]
+	mi := &file_cities_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -119,7 +298,7 @@ func (x *EmptyMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmptyMessage.ProtoReflect.Descriptor instead.
 func (*EmptyMessage) Descriptor() ([]byte, []int) {
-	return file_cities_proto_rawDescGZIP(), []int{1}
+	return file_cities_proto_rawDescGZIP(), []int{2}
 }
 
 type Cities struct {
@@ -133,7 +312,7 @@ type Cities struct {
 func (x *Cities) Reset() {
 	*x = Cities{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_cities_proto_msgTypes[2]
+		mi := &file_cities_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -146,7 +325,7 @@ func (x *Cities) String() string {
 func (*Cities) ProtoMessage() {}
 
 func (x *Cities) ProtoReflect() protoreflect.Message {
-	mi := &file_cities_proto_msgTypes[2]
+	mi := &file_cities_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -159,7 +338,7 @@ func (x *Cities) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Cities.ProtoReflect.Descriptor instead.
 func (*Cities) Descriptor() ([]byte, []int) {
-	return file_cities_proto_rawDescGZIP(), []int{2}
+	return file_cities_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *Cities) GetCity() []*City {
@@ -169,31 +348,36 @@ func (x *Cities) GetCity() []*City {
 	return nil
 }
 
-type CityStream struct {
+type ListPageRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	City *City `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+	// page_size is the most cities the page holds. Zero asks for the server
+	// default of 20, the server caps it at 100.
+	PageSize uint32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// page_token is the next_page_token of the previous page, empty for the
+	// first page.
+	PageToken string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
 }
 
-func (x *CityStream) Reset() {
-	*x = CityStream{}
+func (x *ListPageRequest) Reset() {
+	*x = ListPageRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_cities_proto_msgTypes[3]
+		mi := &file_cities_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *CityStream) String() string {
+func (x *ListPageRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CityStream) ProtoMessage() {}
+func (*ListPageRequest) ProtoMessage() {}
 
-func (x *CityStream) ProtoReflect() protoreflect.Message {
-	mi := &file_cities_proto_msgTypes[3]
+func (x *ListPageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -204,292 +388,2703 @@ func (x *CityStream) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CityStream.ProtoReflect.Descriptor instead.
-func (*CityStream) Descriptor() ([]byte, []int) {
-	return file_cities_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use ListPageRequest.ProtoReflect.Descriptor instead.
+func (*ListPageRequest) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *CityStream) GetCity() *City {
+func (x *ListPageRequest) GetPageSize() uint32 {
 	if x != nil {
-		return x.City
+		return x.PageSize
 	}
-	return nil
+	return 0
 }
 
-var File_cities_proto protoreflect.FileDescriptor
-
-var file_cities_proto_rawDesc = []byte{
-	0x0a, 0x0c, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06,
-	0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x22, 0x2a, 0x0a, 0x04, 0x43, 0x69, 0x74, 0x79, 0x12, 0x0e,
-	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12,
-	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
-	0x6d, 0x65, 0x22, 0x0e, 0x0a, 0x0c, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61,
-	0x67, 0x65, 0x22, 0x2a, 0x0a, 0x06, 0x43, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x04,
-	0x63, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63, 0x69, 0x74,
-	0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x52, 0x04, 0x63, 0x69, 0x74, 0x79, 0x22, 0x2e,
-	0x0a, 0x0a, 0x43, 0x69, 0x74, 0x79, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x20, 0x0a, 0x04,
-	0x63, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63, 0x69, 0x74,
-	0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x52, 0x04, 0x63, 0x69, 0x74, 0x79, 0x32, 0x7b,
-	0x0a, 0x0d, 0x43, 0x69, 0x74, 0x69, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
-	0x3a, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x14, 0x2e,
-	0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x4d, 0x65, 0x73, 0x73,
-	0x61, 0x67, 0x65, 0x1a, 0x12, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74,
-	0x79, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x22, 0x00, 0x30, 0x01, 0x12, 0x2e, 0x0a, 0x04, 0x4c,
-	0x69, 0x73, 0x74, 0x12, 0x14, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45, 0x6d, 0x70,
-	0x74, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x0e, 0x2e, 0x63, 0x69, 0x74, 0x69,
-	0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x69, 0x65, 0x73, 0x22, 0x00, 0x42, 0x12, 0x5a, 0x10, 0x70,
-	0x62, 0x2f, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x3b, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x62,
-	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *ListPageRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
 }
 
-var (
-	file_cities_proto_rawDescOnce sync.Once
-	file_cities_proto_rawDescData = file_cities_proto_rawDesc
-)
+type CityPage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-func file_cities_proto_rawDescGZIP() []byte {
-	file_cities_proto_rawDescOnce.Do(func() {
-		file_cities_proto_rawDescData = protoimpl.X.CompressGZIP(file_cities_proto_rawDescData)
-	})
-	return file_cities_proto_rawDescData
+	City []*City `protobuf:"bytes,1,rep,name=city,proto3" json:"city,omitempty"`
+	// next_page_token asks for the page after this one, it is empty on the
+	// last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
 }
 
-var file_cities_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
-var file_cities_proto_goTypes = []interface{}{
-	(*City)(nil),         // 0: cities.City
-	(*EmptyMessage)(nil), // 1: cities.EmptyMessage
-	(*Cities)(nil),       // 2: cities.Cities
-	(*CityStream)(nil),   // 3: cities.CityStream
+func (x *CityPage) Reset() {
+	*x = CityPage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
-var file_cities_proto_depIdxs = []int32{
-	0, // 0: cities.Cities.city:type_name -> cities.City
-	0, // 1: cities.CityStream.city:type_name -> cities.City
-	1, // 2: cities.CitiesService.ListStream:input_type -> cities.EmptyMessage
-	1, // 3: cities.CitiesService.List:input_type -> cities.EmptyMessage
-	3, // 4: cities.CitiesService.ListStream:output_type -> cities.CityStream
-	2, // 5: cities.CitiesService.List:output_type -> cities.Cities
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+
+func (x *CityPage) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func init() { file_cities_proto_init() }
-func file_cities_proto_init() {
-	if File_cities_proto != nil {
-		return
-	}
-	if !protoimpl.UnsafeEnabled {
-		file_cities_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*City); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_cities_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*EmptyMessage); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_cities_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Cities); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_cities_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CityStream); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+func (*CityPage) ProtoMessage() {}
+
+func (x *CityPage) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
-	type x struct{}
-	out := protoimpl.TypeBuilder{
-		File: protoimpl.DescBuilder{
-			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_cities_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   4,
-			NumExtensions: 0,
-			NumServices:   1,
-		},
-		GoTypes:           file_cities_proto_goTypes,
-		DependencyIndexes: file_cities_proto_depIdxs,
-		MessageInfos:      file_cities_proto_msgTypes,
-	}.Build()
-	File_cities_proto = out.File
-	file_cities_proto_rawDesc = nil
-	file_cities_proto_goTypes = nil
-	file_cities_proto_depIdxs = nil
+	return mi.MessageOf(x)
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConnInterface
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion6
+// Deprecated: Use CityPage.ProtoReflect.Descriptor instead.
+func (*CityPage) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{5}
+}
 
-// CitiesServiceClient is the client API for CitiesService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type CitiesServiceClient interface {
-	ListStream(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (CitiesService_ListStreamClient, error)
-	List(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*Cities, error)
+func (x *CityPage) GetCity() []*City {
+	if x != nil {
+		return x.City
+	}
+	return nil
 }
 
-type citiesServiceClient struct {
-	cc grpc.ClientConnInterface
+func (x *CityPage) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
 }
 
-func NewCitiesServiceClient(cc grpc.ClientConnInterface) CitiesServiceClient {
-	return &citiesServiceClient{cc}
+type ListStreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// resume_token continues an interrupted stream after the last message the
+	// client received. Leave it empty to start from the beginning.
+	ResumeToken string `protobuf:"bytes,1,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	// batch_size groups that many cities into each message. Zero or one sends
+	// one city per message in the city field.
+	BatchSize uint32 `protobuf:"varint,2,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
+	// enrich looks up the detail of every city before it is sent, up to
+	// enrich_parallelism lookups ahead of the stream. The default is 4, the
+	// maximum 16.
+	Enrich            bool   `protobuf:"varint,3,opt,name=enrich,proto3" json:"enrich,omitempty"`
+	EnrichParallelism uint32 `protobuf:"varint,4,opt,name=enrich_parallelism,json=enrichParallelism,proto3" json:"enrich_parallelism,omitempty"`
+	// allow_extension lets the server ask for more time with an
+	// ExtensionRequest. A client that does not set it never gets one.
+	AllowExtension bool `protobuf:"varint,5,opt,name=allow_extension,json=allowExtension,proto3" json:"allow_extension,omitempty"`
 }
 
-func (c *citiesServiceClient) ListStream(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (CitiesService_ListStreamClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_CitiesService_serviceDesc.Streams[0], "/cities.CitiesService/ListStream", opts...)
+func (x *ListStreamRequest) Reset() {
+	*x = ListStreamRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStreamRequest) ProtoMessage() {}
+
+func (x *ListStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStreamRequest.ProtoReflect.Descriptor instead.
+func (*ListStreamRequest) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListStreamRequest) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+func (x *ListStreamRequest) GetBatchSize() uint32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *ListStreamRequest) GetEnrich() bool {
+	if x != nil {
+		return x.Enrich
+	}
+	return false
+}
+
+func (x *ListStreamRequest) GetEnrichParallelism() uint32 {
+	if x != nil {
+		return x.EnrichParallelism
+	}
+	return 0
+}
+
+func (x *ListStreamRequest) GetAllowExtension() bool {
+	if x != nil {
+		return x.AllowExtension
+	}
+	return false
+}
+
+// ExtensionRequest asks the client for more time. The server sends it at most
+// once per call, when the deadline is about to end a stream that is making
+// progress. The client either ignores it and the call ends at its deadline,
+// or cancels the call and resumes from resume_token with a new deadline.
+type ExtensionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// progress is the share of the cities sent so far, from 0 to 1, resumed
+	// calls included.
+	Progress float64 `protobuf:"fixed64,1,opt,name=progress,proto3" json:"progress,omitempty"`
+	// requested_ms is how long the server expects the rest of the stream to
+	// take.
+	RequestedMs int64  `protobuf:"varint,2,opt,name=requested_ms,json=requestedMs,proto3" json:"requested_ms,omitempty"`
+	ResumeToken string `protobuf:"bytes,3,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+}
+
+func (x *ExtensionRequest) Reset() {
+	*x = ExtensionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExtensionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtensionRequest) ProtoMessage() {}
+
+func (x *ExtensionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtensionRequest.ProtoReflect.Descriptor instead.
+func (*ExtensionRequest) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ExtensionRequest) GetProgress() float64 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *ExtensionRequest) GetRequestedMs() int64 {
+	if x != nil {
+		return x.RequestedMs
+	}
+	return 0
+}
+
+func (x *ExtensionRequest) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+type CityStream struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// city is set when the stream sends one city per message.
+	City *City `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+	// resume_token marks the boundary right after this message.
+	ResumeToken string `protobuf:"bytes,2,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	// cities is set when the stream was opened with a batch_size above one.
+	Cities []*City `protobuf:"bytes,3,rep,name=cities,proto3" json:"cities,omitempty"`
+	// extension is only set on control messages, which carry no city.
+	Extension *ExtensionRequest `protobuf:"bytes,4,opt,name=extension,proto3" json:"extension,omitempty"`
+}
+
+func (x *CityStream) Reset() {
+	*x = CityStream{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CityStream) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CityStream) ProtoMessage() {}
+
+func (x *CityStream) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CityStream.ProtoReflect.Descriptor instead.
+func (*CityStream) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CityStream) GetCity() *City {
+	if x != nil {
+		return x.City
+	}
+	return nil
+}
+
+func (x *CityStream) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+func (x *CityStream) GetCities() []*City {
+	if x != nil {
+		return x.Cities
+	}
+	return nil
+}
+
+func (x *CityStream) GetExtension() *ExtensionRequest {
+	if x != nil {
+		return x.Extension
+	}
+	return nil
+}
+
+type CreateCityRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// validate_only runs every check of a real Create, under the same
+	// deadline, but does not store the city. The response has no id.
+	ValidateOnly bool `protobuf:"varint,2,opt,name=validate_only,json=validateOnly,proto3" json:"validate_only,omitempty"`
+}
+
+func (x *CreateCityRequest) Reset() {
+	*x = CreateCityRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateCityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCityRequest) ProtoMessage() {}
+
+func (x *CreateCityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCityRequest.ProtoReflect.Descriptor instead.
+func (*CreateCityRequest) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CreateCityRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateCityRequest) GetValidateOnly() bool {
+	if x != nil {
+		return x.ValidateOnly
+	}
+	return false
+}
+
+type TransformResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// city is the persisted city, it is empty when the message failed.
+	City *City `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+	// stage names the pipeline stage that failed.
+	Stage string `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`
+	// code is the google.rpc.Code of the failure.
+	Code    int32  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Message string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *TransformResult) Reset() {
+	*x = TransformResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TransformResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransformResult) ProtoMessage() {}
+
+func (x *TransformResult) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransformResult.ProtoReflect.Descriptor instead.
+func (*TransformResult) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TransformResult) GetCity() *City {
+	if x != nil {
+		return x.City
+	}
+	return nil
+}
+
+func (x *TransformResult) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *TransformResult) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *TransformResult) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// buffer is the number of events kept for a subscriber that reads slower
+	// than cities are created. Zero uses the server default.
+	Buffer   uint32         `protobuf:"varint,1,opt,name=buffer,proto3" json:"buffer,omitempty"`
+	Overflow OverflowPolicy `protobuf:"varint,2,opt,name=overflow,proto3,enum=cities.OverflowPolicy" json:"overflow,omitempty"`
+	Start    WatchStart     `protobuf:"varint,3,opt,name=start,proto3,enum=cities.WatchStart" json:"start,omitempty"`
+	// resume_after is the last sequence the client applied, for RESUME.
+	ResumeAfter uint64 `protobuf:"varint,4,opt,name=resume_after,json=resumeAfter,proto3" json:"resume_after,omitempty"`
+	// snapshot_interval_ms sends a snapshot every that many milliseconds
+	// between the deltas. Zero sends none.
+	SnapshotIntervalMs uint32 `protobuf:"varint,5,opt,name=snapshot_interval_ms,json=snapshotIntervalMs,proto3" json:"snapshot_interval_ms,omitempty"`
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchRequest) GetBuffer() uint32 {
+	if x != nil {
+		return x.Buffer
+	}
+	return 0
+}
+
+func (x *WatchRequest) GetOverflow() OverflowPolicy {
+	if x != nil {
+		return x.Overflow
+	}
+	return OverflowPolicy_DROP_OLDEST
+}
+
+func (x *WatchRequest) GetStart() WatchStart {
+	if x != nil {
+		return x.Start
+	}
+	return WatchStart_DELTAS_ONLY
+}
+
+func (x *WatchRequest) GetResumeAfter() uint64 {
+	if x != nil {
+		return x.ResumeAfter
+	}
+	return 0
+}
+
+func (x *WatchRequest) GetSnapshotIntervalMs() uint32 {
+	if x != nil {
+		return x.SnapshotIntervalMs
+	}
+	return 0
+}
+
+type CityEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// city is a delta, the city created at sequence. It only holds the id
+	// and the name.
+	City *City `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+	// dropped counts the events discarded for this subscriber since the
+	// previous event.
+	Dropped uint64 `protobuf:"varint,2,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	// sequence orders the deltas. A snapshot holds every delta up to its
+	// sequence, a delta following it may still repeat one of its cities:
+	// clients apply deltas by city id.
+	Sequence uint64 `protobuf:"varint,3,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// snapshot replaces city when the event is a snapshot.
+	Snapshot *CitySnapshot `protobuf:"bytes,4,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+}
+
+func (x *CityEvent) Reset() {
+	*x = CityEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CityEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CityEvent) ProtoMessage() {}
+
+func (x *CityEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CityEvent.ProtoReflect.Descriptor instead.
+func (*CityEvent) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CityEvent) GetCity() *City {
+	if x != nil {
+		return x.City
+	}
+	return nil
+}
+
+func (x *CityEvent) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *CityEvent) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *CityEvent) GetSnapshot() *CitySnapshot {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+type CitySnapshot struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	City []*City `protobuf:"bytes,1,rep,name=city,proto3" json:"city,omitempty"`
+}
+
+func (x *CitySnapshot) Reset() {
+	*x = CitySnapshot{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CitySnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CitySnapshot) ProtoMessage() {}
+
+func (x *CitySnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CitySnapshot.ProtoReflect.Descriptor instead.
+func (*CitySnapshot) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CitySnapshot) GetCity() []*City {
+	if x != nil {
+		return x.City
+	}
+	return nil
+}
+
+type StatsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// fresh waits for a recomputation instead of returning the cached value.
+	Fresh bool `protobuf:"varint,1,opt,name=fresh,proto3" json:"fresh,omitempty"`
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *StatsRequest) GetFresh() bool {
+	if x != nil {
+		return x.Fresh
+	}
+	return false
+}
+
+type CityStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Count             uint32  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	AverageNameLength float64 `protobuf:"fixed64,2,opt,name=average_name_length,json=averageNameLength,proto3" json:"average_name_length,omitempty"`
+	LongestName       string  `protobuf:"bytes,3,opt,name=longest_name,json=longestName,proto3" json:"longest_name,omitempty"`
+	// computed_at is in unix milliseconds.
+	ComputedAt int64 `protobuf:"varint,4,opt,name=computed_at,json=computedAt,proto3" json:"computed_at,omitempty"`
+	Stale      bool  `protobuf:"varint,5,opt,name=stale,proto3" json:"stale,omitempty"`
+}
+
+func (x *CityStats) Reset() {
+	*x = CityStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CityStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CityStats) ProtoMessage() {}
+
+func (x *CityStats) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CityStats.ProtoReflect.Descriptor instead.
+func (*CityStats) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CityStats) GetCount() uint32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *CityStats) GetAverageNameLength() float64 {
+	if x != nil {
+		return x.AverageNameLength
+	}
+	return 0
+}
+
+func (x *CityStats) GetLongestName() string {
+	if x != nil {
+		return x.LongestName
+	}
+	return ""
+}
+
+func (x *CityStats) GetComputedAt() int64 {
+	if x != nil {
+		return x.ComputedAt
+	}
+	return 0
+}
+
+func (x *CityStats) GetStale() bool {
+	if x != nil {
+		return x.Stale
+	}
+	return false
+}
+
+type ServerInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version string `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	// commit is the VCS revision the server was built from, with a "-dirty"
+	// suffix when the tree had local changes.
+	Commit string `protobuf:"bytes,2,opt,name=commit,proto3" json:"commit,omitempty"`
+	// started_at is in unix milliseconds.
+	StartedAt int64 `protobuf:"varint,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	UptimeMs  int64 `protobuf:"varint,4,opt,name=uptime_ms,json=uptimeMs,proto3" json:"uptime_ms,omitempty"`
+	// config holds the settings in effect, config_digest is a SHA-256 of them
+	// so runs can be grouped by configuration.
+	Config       map[string]string `protobuf:"bytes,5,rep,name=config,proto3" json:"config,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	ConfigDigest string            `protobuf:"bytes,6,opt,name=config_digest,json=configDigest,proto3" json:"config_digest,omitempty"`
+	// features lists the optional behaviors that are switched on.
+	Features []string `protobuf:"bytes,7,rep,name=features,proto3" json:"features,omitempty"`
+}
+
+func (x *ServerInfo) Reset() {
+	*x = ServerInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ServerInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerInfo) ProtoMessage() {}
+
+func (x *ServerInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerInfo.ProtoReflect.Descriptor instead.
+func (*ServerInfo) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ServerInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ServerInfo) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *ServerInfo) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *ServerInfo) GetUptimeMs() int64 {
+	if x != nil {
+		return x.UptimeMs
+	}
+	return 0
+}
+
+func (x *ServerInfo) GetConfig() map[string]string {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *ServerInfo) GetConfigDigest() string {
+	if x != nil {
+		return x.ConfigDigest
+	}
+	return ""
+}
+
+func (x *ServerInfo) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+type ExportRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// gzip compresses the export. It is one gzip stream across all chunks.
+	Gzip bool `protobuf:"varint,1,opt,name=gzip,proto3" json:"gzip,omitempty"`
+}
+
+func (x *ExportRequest) Reset() {
+	*x = ExportRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRequest) ProtoMessage() {}
+
+func (x *ExportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRequest.ProtoReflect.Descriptor instead.
+func (*ExportRequest) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ExportRequest) GetGzip() bool {
+	if x != nil {
+		return x.Gzip
+	}
+	return false
+}
+
+// ExportChunk carries part of the stored cities as newline delimited JSON.
+// A complete export ends with a {"complete":true,"count":N} line, an export
+// without it was cut short.
+type ExportChunk struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *ExportChunk) Reset() {
+	*x = ExportChunk{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExportChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportChunk) ProtoMessage() {}
+
+func (x *ExportChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportChunk.ProtoReflect.Descriptor instead.
+func (*ExportChunk) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ExportChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type Maintenance struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// enabled rejects every CitiesService call with UNAVAILABLE and reason.
+	Enabled bool   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Reason  string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *Maintenance) Reset() {
+	*x = Maintenance{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Maintenance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Maintenance) ProtoMessage() {}
+
+func (x *Maintenance) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Maintenance.ProtoReflect.Descriptor instead.
+func (*Maintenance) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Maintenance) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Maintenance) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type CancelCallRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// request_id is the request-id metadata of the calls to cancel.
+	RequestId string `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+}
+
+func (x *CancelCallRequest) Reset() {
+	*x = CancelCallRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancelCallRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelCallRequest) ProtoMessage() {}
+
+func (x *CancelCallRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelCallRequest.ProtoReflect.Descriptor instead.
+func (*CancelCallRequest) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CancelCallRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+type CancelCallResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// cancelled is the number of running calls that had the request id.
+	Cancelled uint32 `protobuf:"varint,1,opt,name=cancelled,proto3" json:"cancelled,omitempty"`
+}
+
+func (x *CancelCallResponse) Reset() {
+	*x = CancelCallResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancelCallResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelCallResponse) ProtoMessage() {}
+
+func (x *CancelCallResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelCallResponse.ProtoReflect.Descriptor instead.
+func (*CancelCallResponse) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CancelCallResponse) GetCancelled() uint32 {
+	if x != nil {
+		return x.Cancelled
+	}
+	return 0
+}
+
+type Latency struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// extra_ms is added to every simulated step.
+	ExtraMs int64 `protobuf:"varint,1,opt,name=extra_ms,json=extraMs,proto3" json:"extra_ms,omitempty"`
+}
+
+func (x *Latency) Reset() {
+	*x = Latency{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Latency) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Latency) ProtoMessage() {}
+
+func (x *Latency) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Latency.ProtoReflect.Descriptor instead.
+func (*Latency) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *Latency) GetExtraMs() int64 {
+	if x != nil {
+		return x.ExtraMs
+	}
+	return 0
+}
+
+type StaticSnapshot struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// enabled makes List and ListStream serve cities built once, with no
+	// simulated work, to measure the cost of the rest of a call.
+	Enabled bool `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+}
+
+func (x *StaticSnapshot) Reset() {
+	*x = StaticSnapshot{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cities_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StaticSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StaticSnapshot) ProtoMessage() {}
+
+func (x *StaticSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_cities_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StaticSnapshot.ProtoReflect.Descriptor instead.
+func (*StaticSnapshot) Descriptor() ([]byte, []int) {
+	return file_cities_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *StaticSnapshot) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+var File_cities_proto protoreflect.FileDescriptor
+
+var file_cities_proto_rawDesc = []byte{
+	0x0a, 0x0c, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06,
+	0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x61,
+	0x70, 0x69, 0x2f, 0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x17, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x73, 0x2f, 0x74,
+	0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x74, 0x0a,
+	0x04, 0x43, 0x69, 0x74, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x2a, 0x0a, 0x06, 0x64, 0x65, 0x74,
+	0x61, 0x69, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x63, 0x69, 0x74, 0x69,
+	0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x06, 0x64,
+	0x65, 0x74, 0x61, 0x69, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x65, 0x61, 0x6c, 0x65, 0x64, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x73, 0x65, 0x61, 0x6c, 0x65, 0x64, 0x4a, 0x04, 0x08,
+	0x01, 0x10, 0x02, 0x22, 0x44, 0x0a, 0x0a, 0x43, 0x69, 0x74, 0x79, 0x44, 0x65, 0x74, 0x61, 0x69,
+	0x6c, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x6f, 0x70,
+	0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x70,
+	0x6f, 0x70, 0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x0e, 0x0a, 0x0c, 0x45, 0x6d, 0x70,
+	0x74, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x2a, 0x0a, 0x06, 0x43, 0x69, 0x74,
+	0x69, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x04, 0x63, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x0c, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x52,
+	0x04, 0x63, 0x69, 0x74, 0x79, 0x22, 0x4d, 0x0a, 0x0f, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x67,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x61, 0x67, 0x65,
+	0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x70, 0x61, 0x67,
+	0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f,
+	0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x67, 0x65, 0x54,
+	0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x54, 0x0a, 0x08, 0x43, 0x69, 0x74, 0x79, 0x50, 0x61, 0x67, 0x65,
+	0x12, 0x20, 0x0a, 0x04, 0x63, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0c,
+	0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x52, 0x04, 0x63, 0x69,
+	0x74, 0x79, 0x12, 0x26, 0x0a, 0x0f, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x70, 0x61, 0x67, 0x65, 0x5f,
+	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6e, 0x65, 0x78,
+	0x74, 0x50, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0xc5, 0x01, 0x0a, 0x11, 0x4c,
+	0x69, 0x73, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f,
+	0x6b, 0x65, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x61, 0x74, 0x63, 0x68, 0x5f, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x62, 0x61, 0x74, 0x63, 0x68, 0x53, 0x69,
+	0x7a, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x6e, 0x72, 0x69, 0x63, 0x68, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x06, 0x65, 0x6e, 0x72, 0x69, 0x63, 0x68, 0x12, 0x2d, 0x0a, 0x12, 0x65, 0x6e,
+	0x72, 0x69, 0x63, 0x68, 0x5f, 0x70, 0x61, 0x72, 0x61, 0x6c, 0x6c, 0x65, 0x6c, 0x69, 0x73, 0x6d,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x11, 0x65, 0x6e, 0x72, 0x69, 0x63, 0x68, 0x50, 0x61,
+	0x72, 0x61, 0x6c, 0x6c, 0x65, 0x6c, 0x69, 0x73, 0x6d, 0x12, 0x27, 0x0a, 0x0f, 0x61, 0x6c, 0x6c,
+	0x6f, 0x77, 0x5f, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x0e, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x45, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69,
+	0x6f, 0x6e, 0x22, 0x74, 0x0a, 0x10, 0x45, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65,
+	0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65,
+	0x73, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x65, 0x64, 0x5f,
+	0x6d, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x65, 0x64, 0x4d, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x5f,
+	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x73,
+	0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0xaf, 0x01, 0x0a, 0x0a, 0x43, 0x69, 0x74,
+	0x79, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x20, 0x0a, 0x04, 0x63, 0x69, 0x74, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43,
+	0x69, 0x74, 0x79, 0x52, 0x04, 0x63, 0x69, 0x74, 0x79, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x73,
+	0x75, 0x6d, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x24, 0x0a, 0x06,
+	0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x52, 0x06, 0x63, 0x69, 0x74, 0x69,
+	0x65, 0x73, 0x12, 0x36, 0x0a, 0x09, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45,
+	0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52,
+	0x09, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x4c, 0x0a, 0x11, 0x43, 0x72,
+	0x65, 0x61, 0x74, 0x65, 0x43, 0x69, 0x74, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x5f,
+	0x6f, 0x6e, 0x6c, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x76, 0x61, 0x6c, 0x69,
+	0x64, 0x61, 0x74, 0x65, 0x4f, 0x6e, 0x6c, 0x79, 0x22, 0x77, 0x0a, 0x0f, 0x54, 0x72, 0x61, 0x6e,
+	0x73, 0x66, 0x6f, 0x72, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x20, 0x0a, 0x04, 0x63,
+	0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63, 0x69, 0x74, 0x69,
+	0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x52, 0x04, 0x63, 0x69, 0x74, 0x79, 0x12, 0x14, 0x0a,
+	0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x74,
+	0x61, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x22, 0xd9, 0x01, 0x0a, 0x0c, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x06, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x12, 0x32, 0x0a, 0x08, 0x6f, 0x76,
+	0x65, 0x72, 0x66, 0x6c, 0x6f, 0x77, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e, 0x63,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x4f, 0x76, 0x65, 0x72, 0x66, 0x6c, 0x6f, 0x77, 0x50, 0x6f,
+	0x6c, 0x69, 0x63, 0x79, 0x52, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x66, 0x6c, 0x6f, 0x77, 0x12, 0x28,
+	0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x12, 0x2e,
+	0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x53, 0x74, 0x61, 0x72,
+	0x74, 0x52, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x73, 0x75,
+	0x6d, 0x65, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b,
+	0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x41, 0x66, 0x74, 0x65, 0x72, 0x12, 0x30, 0x0a, 0x14, 0x73,
+	0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c,
+	0x5f, 0x6d, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x12, 0x73, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x4d, 0x73, 0x22, 0x95, 0x01,
+	0x0a, 0x09, 0x43, 0x69, 0x74, 0x79, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x20, 0x0a, 0x04, 0x63,
+	0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63, 0x69, 0x74, 0x69,
+	0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x52, 0x04, 0x63, 0x69, 0x74, 0x79, 0x12, 0x18, 0x0a,
+	0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07,
+	0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65,
+	0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65,
+	0x6e, 0x63, 0x65, 0x12, 0x30, 0x0a, 0x08, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43,
+	0x69, 0x74, 0x79, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x52, 0x08, 0x73, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x22, 0x30, 0x0a, 0x0c, 0x43, 0x69, 0x74, 0x79, 0x53, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x20, 0x0a, 0x04, 0x63, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74,
+	0x79, 0x52, 0x04, 0x63, 0x69, 0x74, 0x79, 0x22, 0x24, 0x0a, 0x0c, 0x53, 0x74, 0x61, 0x74, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x72, 0x65, 0x73, 0x68,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x66, 0x72, 0x65, 0x73, 0x68, 0x22, 0xab, 0x01,
+	0x0a, 0x09, 0x43, 0x69, 0x74, 0x79, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x63,
+	0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x12, 0x2e, 0x0a, 0x13, 0x61, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x6e, 0x61, 0x6d,
+	0x65, 0x5f, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x11,
+	0x61, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x4c, 0x65, 0x6e, 0x67, 0x74,
+	0x68, 0x12, 0x21, 0x0a, 0x0c, 0x6c, 0x6f, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x5f, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6c, 0x6f, 0x6e, 0x67, 0x65, 0x73, 0x74,
+	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x75, 0x74, 0x65, 0x64,
+	0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x63, 0x6f, 0x6d, 0x70, 0x75,
+	0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x6c, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x73, 0x74, 0x61, 0x6c, 0x65, 0x22, 0xae, 0x02, 0x0a, 0x0a,
+	0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x1d, 0x0a, 0x0a,
+	0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x75,
+	0x70, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x6d, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08,
+	0x75, 0x70, 0x74, 0x69, 0x6d, 0x65, 0x4d, 0x73, 0x12, 0x36, 0x0a, 0x06, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65,
+	0x73, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x2e, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x12, 0x23, 0x0a, 0x0d, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73,
+	0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x44,
+	0x69, 0x67, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x73, 0x1a, 0x39, 0x0a, 0x0b, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x23, 0x0a, 0x0d,
+	0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x67, 0x7a, 0x69, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x67, 0x7a, 0x69,
+	0x70, 0x22, 0x21, 0x0a, 0x0b, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x43, 0x68, 0x75, 0x6e, 0x6b,
+	0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04,
+	0x64, 0x61, 0x74, 0x61, 0x22, 0x3f, 0x0a, 0x0b, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61,
+	0x6e, 0x63, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x12, 0x16, 0x0a,
+	0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72,
+	0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x32, 0x0a, 0x11, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x43,
+	0x61, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x64, 0x22, 0x32, 0x0a, 0x12, 0x43, 0x61, 0x6e,
+	0x63, 0x65, 0x6c, 0x43, 0x61, 0x6c, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x1c, 0x0a, 0x09, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x09, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x65, 0x64, 0x22, 0x24, 0x0a,
+	0x07, 0x4c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x19, 0x0a, 0x08, 0x65, 0x78, 0x74, 0x72,
+	0x61, 0x5f, 0x6d, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x65, 0x78, 0x74, 0x72,
+	0x61, 0x4d, 0x73, 0x22, 0x2a, 0x0a, 0x0e, 0x53, 0x74, 0x61, 0x74, 0x69, 0x63, 0x53, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x2a,
+	0x49, 0x0a, 0x0e, 0x4f, 0x76, 0x65, 0x72, 0x66, 0x6c, 0x6f, 0x77, 0x50, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x12, 0x0f, 0x0a, 0x0b, 0x44, 0x52, 0x4f, 0x50, 0x5f, 0x4f, 0x4c, 0x44, 0x45, 0x53, 0x54,
+	0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x44, 0x52, 0x4f, 0x50, 0x5f, 0x4e, 0x45, 0x57, 0x45, 0x53,
+	0x54, 0x10, 0x01, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x41, 0x4e, 0x43, 0x45, 0x4c, 0x5f, 0x53, 0x55,
+	0x42, 0x53, 0x43, 0x52, 0x49, 0x42, 0x45, 0x52, 0x10, 0x02, 0x2a, 0x35, 0x0a, 0x0a, 0x57, 0x61,
+	0x74, 0x63, 0x68, 0x53, 0x74, 0x61, 0x72, 0x74, 0x12, 0x0f, 0x0a, 0x0b, 0x44, 0x45, 0x4c, 0x54,
+	0x41, 0x53, 0x5f, 0x4f, 0x4e, 0x4c, 0x59, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x52, 0x45, 0x53,
+	0x55, 0x4d, 0x45, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x52, 0x45, 0x53, 0x59, 0x4e, 0x43, 0x10,
+	0x02, 0x32, 0xfa, 0x04, 0x0a, 0x0d, 0x43, 0x69, 0x74, 0x69, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x12, 0x5f, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x12, 0x19, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x63,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x22, 0x20, 0x8a, 0xb5, 0x18, 0x03, 0x36, 0x30, 0x73, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x13, 0x12,
+	0x11, 0x2f, 0x76, 0x31, 0x2f, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2f, 0x73, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x30, 0x01, 0x12, 0x47, 0x0a, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x14, 0x2e, 0x63,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x1a, 0x0e, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x69,
+	0x65, 0x73, 0x22, 0x19, 0x8a, 0xb5, 0x18, 0x03, 0x31, 0x30, 0x73, 0x82, 0xd3, 0xe4, 0x93, 0x02,
+	0x0c, 0x12, 0x0a, 0x2f, 0x76, 0x31, 0x2f, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x3e, 0x0a,
+	0x08, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x67, 0x65, 0x12, 0x17, 0x2e, 0x63, 0x69, 0x74, 0x69,
+	0x65, 0x73, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x10, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79,
+	0x50, 0x61, 0x67, 0x65, 0x22, 0x07, 0x8a, 0xb5, 0x18, 0x03, 0x31, 0x30, 0x73, 0x12, 0x39, 0x0a,
+	0x06, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x12, 0x19, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73,
+	0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x69, 0x74, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79,
+	0x22, 0x06, 0x8a, 0xb5, 0x18, 0x02, 0x35, 0x73, 0x12, 0x39, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74,
+	0x73, 0x12, 0x14, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73,
+	0x2e, 0x43, 0x69, 0x74, 0x79, 0x53, 0x74, 0x61, 0x74, 0x73, 0x22, 0x07, 0x8a, 0xb5, 0x18, 0x03,
+	0x31, 0x35, 0x73, 0x12, 0x44, 0x0a, 0x0f, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x6f, 0x72, 0x6d,
+	0x43, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x0c, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e,
+	0x43, 0x69, 0x74, 0x79, 0x1a, 0x17, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x66, 0x6f, 0x72, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0x06, 0x8a,
+	0xb5, 0x18, 0x02, 0x35, 0x6d, 0x28, 0x01, 0x30, 0x01, 0x12, 0x3a, 0x0a, 0x0b, 0x57, 0x61, 0x74,
+	0x63, 0x68, 0x43, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x14, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65,
+	0x73, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11,
+	0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x69, 0x74, 0x79, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x22, 0x00, 0x30, 0x01, 0x12, 0x44, 0x0a, 0x0c, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x43,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x15, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45,
+	0x78, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x63,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x43, 0x68, 0x75, 0x6e,
+	0x6b, 0x22, 0x06, 0x8a, 0xb5, 0x18, 0x02, 0x35, 0x6d, 0x30, 0x01, 0x12, 0x41, 0x0a, 0x0d, 0x47,
+	0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x14, 0x2e, 0x63,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x1a, 0x12, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x53, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x06, 0x8a, 0xb5, 0x18, 0x02, 0x35, 0x73, 0x32, 0xb5,
+	0x04, 0x0a, 0x0c, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
+	0x43, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63,
+	0x65, 0x12, 0x14, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x13, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73,
+	0x2e, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x22, 0x06, 0x8a, 0xb5,
+	0x18, 0x02, 0x35, 0x73, 0x12, 0x42, 0x0a, 0x0e, 0x53, 0x65, 0x74, 0x4d, 0x61, 0x69, 0x6e, 0x74,
+	0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x13, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e,
+	0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x1a, 0x13, 0x2e, 0x63, 0x69,
+	0x74, 0x69, 0x65, 0x73, 0x2e, 0x4d, 0x61, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65,
+	0x22, 0x06, 0x8a, 0xb5, 0x18, 0x02, 0x35, 0x73, 0x12, 0x4b, 0x0a, 0x0a, 0x43, 0x61, 0x6e, 0x63,
+	0x65, 0x6c, 0x43, 0x61, 0x6c, 0x6c, 0x12, 0x19, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e,
+	0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x43, 0x61, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1a, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65,
+	0x6c, 0x43, 0x61, 0x6c, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x06, 0x8a,
+	0xb5, 0x18, 0x02, 0x35, 0x73, 0x12, 0x3b, 0x0a, 0x0a, 0x47, 0x65, 0x74, 0x4c, 0x61, 0x74, 0x65,
+	0x6e, 0x63, 0x79, 0x12, 0x14, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45, 0x6d, 0x70,
+	0x74, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x0f, 0x2e, 0x63, 0x69, 0x74, 0x69,
+	0x65, 0x73, 0x2e, 0x4c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x22, 0x06, 0x8a, 0xb5, 0x18, 0x02,
+	0x35, 0x73, 0x12, 0x36, 0x0a, 0x0a, 0x53, 0x65, 0x74, 0x4c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79,
+	0x12, 0x0f, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x4c, 0x61, 0x74, 0x65, 0x6e, 0x63,
+	0x79, 0x1a, 0x0f, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x4c, 0x61, 0x74, 0x65, 0x6e,
+	0x63, 0x79, 0x22, 0x06, 0x8a, 0xb5, 0x18, 0x02, 0x35, 0x73, 0x12, 0x49, 0x0a, 0x11, 0x47, 0x65,
+	0x74, 0x53, 0x74, 0x61, 0x74, 0x69, 0x63, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x12,
+	0x14, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x4d, 0x65,
+	0x73, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x16, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x53,
+	0x74, 0x61, 0x74, 0x69, 0x63, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x22, 0x06, 0x8a,
+	0xb5, 0x18, 0x02, 0x35, 0x73, 0x12, 0x4b, 0x0a, 0x11, 0x53, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74,
+	0x69, 0x63, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x16, 0x2e, 0x63, 0x69, 0x74,
+	0x69, 0x65, 0x73, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x69, 0x63, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68,
+	0x6f, 0x74, 0x1a, 0x16, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x69, 0x63, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x22, 0x06, 0x8a, 0xb5, 0x18, 0x02,
+	0x35, 0x73, 0x12, 0x42, 0x0a, 0x0c, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x12, 0x14, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x14, 0x2e, 0x63, 0x69, 0x74, 0x69, 0x65,
+	0x73, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x06,
+	0x8a, 0xb5, 0x18, 0x02, 0x35, 0x73, 0x42, 0x1c, 0x5a, 0x1a, 0x67, 0x6f, 0x2d, 0x63, 0x61, 0x6e,
+	0x63, 0x65, 0x6c, 0x2f, 0x70, 0x62, 0x2f, 0x63, 0x69, 0x74, 0x69, 0x65, 0x73, 0x3b, 0x63, 0x69,
+	0x74, 0x69, 0x65, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_cities_proto_rawDescOnce sync.Once
+	file_cities_proto_rawDescData = file_cities_proto_rawDesc
+)
+
+func file_cities_proto_rawDescGZIP() []byte {
+	file_cities_proto_rawDescOnce.Do(func() {
+		file_cities_proto_rawDescData = protoimpl.X.CompressGZIP(file_cities_proto_rawDescData)
+	})
+	return file_cities_proto_rawDescData
+}
+
+var file_cities_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_cities_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_cities_proto_goTypes = []interface{}{
+	(OverflowPolicy)(0),        // 0: cities.OverflowPolicy
+	(WatchStart)(0),            // 1: cities.WatchStart
+	(*City)(nil),               // 2: cities.City
+	(*CityDetail)(nil),         // 3: cities.CityDetail
+	(*EmptyMessage)(nil),       // 4: cities.EmptyMessage
+	(*Cities)(nil),             // 5: cities.Cities
+	(*ListPageRequest)(nil),    // 6: cities.ListPageRequest
+	(*CityPage)(nil),           // 7: cities.CityPage
+	(*ListStreamRequest)(nil),  // 8: cities.ListStreamRequest
+	(*ExtensionRequest)(nil),   // 9: cities.ExtensionRequest
+	(*CityStream)(nil),         // 10: cities.CityStream
+	(*CreateCityRequest)(nil),  // 11: cities.CreateCityRequest
+	(*TransformResult)(nil),    // 12: cities.TransformResult
+	(*WatchRequest)(nil),       // 13: cities.WatchRequest
+	(*CityEvent)(nil),          // 14: cities.CityEvent
+	(*CitySnapshot)(nil),       // 15: cities.CitySnapshot
+	(*StatsRequest)(nil),       // 16: cities.StatsRequest
+	(*CityStats)(nil),          // 17: cities.CityStats
+	(*ServerInfo)(nil),         // 18: cities.ServerInfo
+	(*ExportRequest)(nil),      // 19: cities.ExportRequest
+	(*ExportChunk)(nil),        // 20: cities.ExportChunk
+	(*Maintenance)(nil),        // 21: cities.Maintenance
+	(*CancelCallRequest)(nil),  // 22: cities.CancelCallRequest
+	(*CancelCallResponse)(nil), // 23: cities.CancelCallResponse
+	(*Latency)(nil),            // 24: cities.Latency
+	(*StaticSnapshot)(nil),     // 25: cities.StaticSnapshot
+	nil,                        // 26: cities.ServerInfo.ConfigEntry
+}
+var file_cities_proto_depIdxs = []int32{
+	3,  // 0: cities.City.detail:type_name -> cities.CityDetail
+	2,  // 1: cities.Cities.city:type_name -> cities.City
+	2,  // 2: cities.CityPage.city:type_name -> cities.City
+	2,  // 3: cities.CityStream.city:type_name -> cities.City
+	2,  // 4: cities.CityStream.cities:type_name -> cities.City
+	9,  // 5: cities.CityStream.extension:type_name -> cities.ExtensionRequest
+	2,  // 6: cities.TransformResult.city:type_name -> cities.City
+	0,  // 7: cities.WatchRequest.overflow:type_name -> cities.OverflowPolicy
+	1,  // 8: cities.WatchRequest.start:type_name -> cities.WatchStart
+	2,  // 9: cities.CityEvent.city:type_name -> cities.City
+	15, // 10: cities.CityEvent.snapshot:type_name -> cities.CitySnapshot
+	2,  // 11: cities.CitySnapshot.city:type_name -> cities.City
+	26, // 12: cities.ServerInfo.config:type_name -> cities.ServerInfo.ConfigEntry
+	8,  // 13: cities.CitiesService.ListStream:input_type -> cities.ListStreamRequest
+	4,  // 14: cities.CitiesService.List:input_type -> cities.EmptyMessage
+	6,  // 15: cities.CitiesService.ListPage:input_type -> cities.ListPageRequest
+	11, // 16: cities.CitiesService.Create:input_type -> cities.CreateCityRequest
+	16, // 17: cities.CitiesService.Stats:input_type -> cities.StatsRequest
+	2,  // 18: cities.CitiesService.TransformCities:input_type -> cities.City
+	13, // 19: cities.CitiesService.WatchCities:input_type -> cities.WatchRequest
+	19, // 20: cities.CitiesService.ExportCities:input_type -> cities.ExportRequest
+	4,  // 21: cities.CitiesService.GetServerInfo:input_type -> cities.EmptyMessage
+	4,  // 22: cities.AdminService.GetMaintenance:input_type -> cities.EmptyMessage
+	21, // 23: cities.AdminService.SetMaintenance:input_type -> cities.Maintenance
+	22, // 24: cities.AdminService.CancelCall:input_type -> cities.CancelCallRequest
+	4,  // 25: cities.AdminService.GetLatency:input_type -> cities.EmptyMessage
+	24, // 26: cities.AdminService.SetLatency:input_type -> cities.Latency
+	4,  // 27: cities.AdminService.GetStaticSnapshot:input_type -> cities.EmptyMessage
+	25, // 28: cities.AdminService.SetStaticSnapshot:input_type -> cities.StaticSnapshot
+	4,  // 29: cities.AdminService.ReloadConfig:input_type -> cities.EmptyMessage
+	10, // 30: cities.CitiesService.ListStream:output_type -> cities.CityStream
+	5,  // 31: cities.CitiesService.List:output_type -> cities.Cities
+	7,  // 32: cities.CitiesService.ListPage:output_type -> cities.CityPage
+	2,  // 33: cities.CitiesService.Create:output_type -> cities.City
+	17, // 34: cities.CitiesService.Stats:output_type -> cities.CityStats
+	12, // 35: cities.CitiesService.TransformCities:output_type -> cities.TransformResult
+	14, // 36: cities.CitiesService.WatchCities:output_type -> cities.CityEvent
+	20, // 37: cities.CitiesService.ExportCities:output_type -> cities.ExportChunk
+	18, // 38: cities.CitiesService.GetServerInfo:output_type -> cities.ServerInfo
+	21, // 39: cities.AdminService.GetMaintenance:output_type -> cities.Maintenance
+	21, // 40: cities.AdminService.SetMaintenance:output_type -> cities.Maintenance
+	23, // 41: cities.AdminService.CancelCall:output_type -> cities.CancelCallResponse
+	24, // 42: cities.AdminService.GetLatency:output_type -> cities.Latency
+	24, // 43: cities.AdminService.SetLatency:output_type -> cities.Latency
+	25, // 44: cities.AdminService.GetStaticSnapshot:output_type -> cities.StaticSnapshot
+	25, // 45: cities.AdminService.SetStaticSnapshot:output_type -> cities.StaticSnapshot
+	4,  // 46: cities.AdminService.ReloadConfig:output_type -> cities.EmptyMessage
+	30, // [30:47] is the sub-list for method output_type
+	13, // [13:30] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_cities_proto_init() }
+func file_cities_proto_init() {
+	if File_cities_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_cities_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*City); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CityDetail); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EmptyMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Cities); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListPageRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CityPage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListStreamRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExtensionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CityStream); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateCityRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TransformResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CityEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CitySnapshot); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CityStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ServerInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExportRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExportChunk); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Maintenance); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CancelCallRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CancelCallResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Latency); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cities_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StaticSnapshot); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_cities_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   25,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_cities_proto_goTypes,
+		DependencyIndexes: file_cities_proto_depIdxs,
+		EnumInfos:         file_cities_proto_enumTypes,
+		MessageInfos:      file_cities_proto_msgTypes,
+	}.Build()
+	File_cities_proto = out.File
+	file_cities_proto_rawDesc = nil
+	file_cities_proto_goTypes = nil
+	file_cities_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// CitiesServiceClient is the client API for CitiesService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type CitiesServiceClient interface {
+	// The REST routes of the grpc-gateway take the fields of the request as
+	// query parameters, e.g. /v1/cities/stream?batch_size=10.
+	ListStream(ctx context.Context, in *ListStreamRequest, opts ...grpc.CallOption) (CitiesService_ListStreamClient, error)
+	List(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*Cities, error)
+	// ListPage is List one page at a time, a page takes longer the more
+	// cities it holds.
+	ListPage(ctx context.Context, in *ListPageRequest, opts ...grpc.CallOption) (*CityPage, error)
+	Create(ctx context.Context, in *CreateCityRequest, opts ...grpc.CallOption) (*City, error)
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*CityStats, error)
+	TransformCities(ctx context.Context, opts ...grpc.CallOption) (CitiesService_TransformCitiesClient, error)
+	WatchCities(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CitiesService_WatchCitiesClient, error)
+	ExportCities(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (CitiesService_ExportCitiesClient, error)
+	GetServerInfo(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*ServerInfo, error)
+}
+
+type citiesServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCitiesServiceClient(cc grpc.ClientConnInterface) CitiesServiceClient {
+	return &citiesServiceClient{cc}
+}
+
+func (c *citiesServiceClient) ListStream(ctx context.Context, in *ListStreamRequest, opts ...grpc.CallOption) (CitiesService_ListStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CitiesService_serviceDesc.Streams[0], "/cities.CitiesService/ListStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &citiesServiceListStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CitiesService_ListStreamClient interface {
+	Recv() (*CityStream, error)
+	grpc.ClientStream
+}
+
+type citiesServiceListStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *citiesServiceListStreamClient) Recv() (*CityStream, error) {
+	m := new(CityStream)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *citiesServiceClient) List(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*Cities, error) {
+	out := new(Cities)
+	err := c.cc.Invoke(ctx, "/cities.CitiesService/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *citiesServiceClient) ListPage(ctx context.Context, in *ListPageRequest, opts ...grpc.CallOption) (*CityPage, error) {
+	out := new(CityPage)
+	err := c.cc.Invoke(ctx, "/cities.CitiesService/ListPage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *citiesServiceClient) Create(ctx context.Context, in *CreateCityRequest, opts ...grpc.CallOption) (*City, error) {
+	out := new(City)
+	err := c.cc.Invoke(ctx, "/cities.CitiesService/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *citiesServiceClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*CityStats, error) {
+	out := new(CityStats)
+	err := c.cc.Invoke(ctx, "/cities.CitiesService/Stats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *citiesServiceClient) TransformCities(ctx context.Context, opts ...grpc.CallOption) (CitiesService_TransformCitiesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CitiesService_serviceDesc.Streams[1], "/cities.CitiesService/TransformCities", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &citiesServiceTransformCitiesClient{stream}
+	return x, nil
+}
+
+type CitiesService_TransformCitiesClient interface {
+	Send(*City) error
+	Recv() (*TransformResult, error)
+	grpc.ClientStream
+}
+
+type citiesServiceTransformCitiesClient struct {
+	grpc.ClientStream
+}
+
+func (x *citiesServiceTransformCitiesClient) Send(m *City) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *citiesServiceTransformCitiesClient) Recv() (*TransformResult, error) {
+	m := new(TransformResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *citiesServiceClient) WatchCities(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CitiesService_WatchCitiesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CitiesService_serviceDesc.Streams[2], "/cities.CitiesService/WatchCities", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &citiesServiceWatchCitiesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CitiesService_WatchCitiesClient interface {
+	Recv() (*CityEvent, error)
+	grpc.ClientStream
+}
+
+type citiesServiceWatchCitiesClient struct {
+	grpc.ClientStream
+}
+
+func (x *citiesServiceWatchCitiesClient) Recv() (*CityEvent, error) {
+	m := new(CityEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *citiesServiceClient) ExportCities(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (CitiesService_ExportCitiesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CitiesService_serviceDesc.Streams[3], "/cities.CitiesService/ExportCities", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &citiesServiceExportCitiesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CitiesService_ExportCitiesClient interface {
+	Recv() (*ExportChunk, error)
+	grpc.ClientStream
+}
+
+type citiesServiceExportCitiesClient struct {
+	grpc.ClientStream
+}
+
+func (x *citiesServiceExportCitiesClient) Recv() (*ExportChunk, error) {
+	m := new(ExportChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *citiesServiceClient) GetServerInfo(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, "/cities.CitiesService/GetServerInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CitiesServiceServer is the server API for CitiesService service.
+type CitiesServiceServer interface {
+	// The REST routes of the grpc-gateway take the fields of the request as
+	// query parameters, e.g. /v1/cities/stream?batch_size=10.
+	ListStream(*ListStreamRequest, CitiesService_ListStreamServer) error
+	List(context.Context, *EmptyMessage) (*Cities, error)
+	// ListPage is List one page at a time, a page takes longer the more
+	// cities it holds.
+	ListPage(context.Context, *ListPageRequest) (*CityPage, error)
+	Create(context.Context, *CreateCityRequest) (*City, error)
+	Stats(context.Context, *StatsRequest) (*CityStats, error)
+	TransformCities(CitiesService_TransformCitiesServer) error
+	WatchCities(*WatchRequest, CitiesService_WatchCitiesServer) error
+	ExportCities(*ExportRequest, CitiesService_ExportCitiesServer) error
+	GetServerInfo(context.Context, *EmptyMessage) (*ServerInfo, error)
+}
+
+// UnimplementedCitiesServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedCitiesServiceServer struct {
+}
+
+func (*UnimplementedCitiesServiceServer) ListStream(*ListStreamRequest, CitiesService_ListStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListStream not implemented")
+}
+func (*UnimplementedCitiesServiceServer) List(context.Context, *EmptyMessage) (*Cities, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (*UnimplementedCitiesServiceServer) ListPage(context.Context, *ListPageRequest) (*CityPage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPage not implemented")
+}
+func (*UnimplementedCitiesServiceServer) Create(context.Context, *CreateCityRequest) (*City, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (*UnimplementedCitiesServiceServer) Stats(context.Context, *StatsRequest) (*CityStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (*UnimplementedCitiesServiceServer) TransformCities(CitiesService_TransformCitiesServer) error {
+	return status.Errorf(codes.Unimplemented, "method TransformCities not implemented")
+}
+func (*UnimplementedCitiesServiceServer) WatchCities(*WatchRequest, CitiesService_WatchCitiesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCities not implemented")
+}
+func (*UnimplementedCitiesServiceServer) ExportCities(*ExportRequest, CitiesService_ExportCitiesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportCities not implemented")
+}
+func (*UnimplementedCitiesServiceServer) GetServerInfo(context.Context, *EmptyMessage) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerInfo not implemented")
+}
+
+func RegisterCitiesServiceServer(s *grpc.Server, srv CitiesServiceServer) {
+	s.RegisterService(&_CitiesService_serviceDesc, srv)
+}
+
+func _CitiesService_ListStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CitiesServiceServer).ListStream(m, &citiesServiceListStreamServer{stream})
+}
+
+type CitiesService_ListStreamServer interface {
+	Send(*CityStream) error
+	grpc.ServerStream
+}
+
+type citiesServiceListStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *citiesServiceListStreamServer) Send(m *CityStream) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CitiesService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CitiesServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.CitiesService/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CitiesServiceServer).List(ctx, req.(*EmptyMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CitiesService_ListPage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CitiesServiceServer).ListPage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.CitiesService/ListPage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CitiesServiceServer).ListPage(ctx, req.(*ListPageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CitiesService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CitiesServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.CitiesService/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CitiesServiceServer).Create(ctx, req.(*CreateCityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CitiesService_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CitiesServiceServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.CitiesService/Stats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CitiesServiceServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CitiesService_TransformCities_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CitiesServiceServer).TransformCities(&citiesServiceTransformCitiesServer{stream})
+}
+
+type CitiesService_TransformCitiesServer interface {
+	Send(*TransformResult) error
+	Recv() (*City, error)
+	grpc.ServerStream
+}
+
+type citiesServiceTransformCitiesServer struct {
+	grpc.ServerStream
+}
+
+func (x *citiesServiceTransformCitiesServer) Send(m *TransformResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *citiesServiceTransformCitiesServer) Recv() (*City, error) {
+	m := new(City)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _CitiesService_WatchCities_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CitiesServiceServer).WatchCities(m, &citiesServiceWatchCitiesServer{stream})
+}
+
+type CitiesService_WatchCitiesServer interface {
+	Send(*CityEvent) error
+	grpc.ServerStream
+}
+
+type citiesServiceWatchCitiesServer struct {
+	grpc.ServerStream
+}
+
+func (x *citiesServiceWatchCitiesServer) Send(m *CityEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CitiesService_ExportCities_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CitiesServiceServer).ExportCities(m, &citiesServiceExportCitiesServer{stream})
+}
+
+type CitiesService_ExportCitiesServer interface {
+	Send(*ExportChunk) error
+	grpc.ServerStream
+}
+
+type citiesServiceExportCitiesServer struct {
+	grpc.ServerStream
+}
+
+func (x *citiesServiceExportCitiesServer) Send(m *ExportChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CitiesService_GetServerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CitiesServiceServer).GetServerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.CitiesService/GetServerInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CitiesServiceServer).GetServerInfo(ctx, req.(*EmptyMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _CitiesService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cities.CitiesService",
+	HandlerType: (*CitiesServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _CitiesService_List_Handler,
+		},
+		{
+			MethodName: "ListPage",
+			Handler:    _CitiesService_ListPage_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _CitiesService_Create_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _CitiesService_Stats_Handler,
+		},
+		{
+			MethodName: "GetServerInfo",
+			Handler:    _CitiesService_GetServerInfo_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListStream",
+			Handler:       _CitiesService_ListStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "TransformCities",
+			Handler:       _CitiesService_TransformCities_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchCities",
+			Handler:       _CitiesService_WatchCities_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportCities",
+			Handler:       _CitiesService_ExportCities_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cities.proto",
+}
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AdminServiceClient interface {
+	GetMaintenance(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*Maintenance, error)
+	SetMaintenance(ctx context.Context, in *Maintenance, opts ...grpc.CallOption) (*Maintenance, error)
+	CancelCall(ctx context.Context, in *CancelCallRequest, opts ...grpc.CallOption) (*CancelCallResponse, error)
+	GetLatency(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*Latency, error)
+	SetLatency(ctx context.Context, in *Latency, opts ...grpc.CallOption) (*Latency, error)
+	GetStaticSnapshot(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*StaticSnapshot, error)
+	SetStaticSnapshot(ctx context.Context, in *StaticSnapshot, opts ...grpc.CallOption) (*StaticSnapshot, error)
+	// ReloadConfig re-reads LOG_CONFIG, like SIGHUP does.
+	ReloadConfig(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*EmptyMessage, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) GetMaintenance(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*Maintenance, error) {
+	out := new(Maintenance)
+	err := c.cc.Invoke(ctx, "/cities.AdminService/GetMaintenance", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &citiesServiceListStreamClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
+	return out, nil
+}
+
+func (c *adminServiceClient) SetMaintenance(ctx context.Context, in *Maintenance, opts ...grpc.CallOption) (*Maintenance, error) {
+	out := new(Maintenance)
+	err := c.cc.Invoke(ctx, "/cities.AdminService/SetMaintenance", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
+	return out, nil
+}
+
+func (c *adminServiceClient) CancelCall(ctx context.Context, in *CancelCallRequest, opts ...grpc.CallOption) (*CancelCallResponse, error) {
+	out := new(CancelCallResponse)
+	err := c.cc.Invoke(ctx, "/cities.AdminService/CancelCall", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	return x, nil
+	return out, nil
 }
 
-type CitiesService_ListStreamClient interface {
-	Recv() (*CityStream, error)
-	grpc.ClientStream
+func (c *adminServiceClient) GetLatency(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*Latency, error) {
+	out := new(Latency)
+	err := c.cc.Invoke(ctx, "/cities.AdminService/GetLatency", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-type citiesServiceListStreamClient struct {
-	grpc.ClientStream
+func (c *adminServiceClient) SetLatency(ctx context.Context, in *Latency, opts ...grpc.CallOption) (*Latency, error) {
+	out := new(Latency)
+	err := c.cc.Invoke(ctx, "/cities.AdminService/SetLatency", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (x *citiesServiceListStreamClient) Recv() (*CityStream, error) {
-	m := new(CityStream)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
+func (c *adminServiceClient) GetStaticSnapshot(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*StaticSnapshot, error) {
+	out := new(StaticSnapshot)
+	err := c.cc.Invoke(ctx, "/cities.AdminService/GetStaticSnapshot", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	return m, nil
+	return out, nil
 }
 
-func (c *citiesServiceClient) List(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*Cities, error) {
-	out := new(Cities)
-	err := c.cc.Invoke(ctx, "/cities.CitiesService/List", in, out, opts...)
+func (c *adminServiceClient) SetStaticSnapshot(ctx context.Context, in *StaticSnapshot, opts ...grpc.CallOption) (*StaticSnapshot, error) {
+	out := new(StaticSnapshot)
+	err := c.cc.Invoke(ctx, "/cities.AdminService/SetStaticSnapshot", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// CitiesServiceServer is the server API for CitiesService service.
-type CitiesServiceServer interface {
-	ListStream(*EmptyMessage, CitiesService_ListStreamServer) error
-	List(context.Context, *EmptyMessage) (*Cities, error)
+func (c *adminServiceClient) ReloadConfig(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (*EmptyMessage, error) {
+	out := new(EmptyMessage)
+	err := c.cc.Invoke(ctx, "/cities.AdminService/ReloadConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-// UnimplementedCitiesServiceServer can be embedded to have forward compatible implementations.
-type UnimplementedCitiesServiceServer struct {
+// AdminServiceServer is the server API for AdminService service.
+type AdminServiceServer interface {
+	GetMaintenance(context.Context, *EmptyMessage) (*Maintenance, error)
+	SetMaintenance(context.Context, *Maintenance) (*Maintenance, error)
+	CancelCall(context.Context, *CancelCallRequest) (*CancelCallResponse, error)
+	GetLatency(context.Context, *EmptyMessage) (*Latency, error)
+	SetLatency(context.Context, *Latency) (*Latency, error)
+	GetStaticSnapshot(context.Context, *EmptyMessage) (*StaticSnapshot, error)
+	SetStaticSnapshot(context.Context, *StaticSnapshot) (*StaticSnapshot, error)
+	// ReloadConfig re-reads LOG_CONFIG, like SIGHUP does.
+	ReloadConfig(context.Context, *EmptyMessage) (*EmptyMessage, error)
 }
 
-func (*UnimplementedCitiesServiceServer) ListStream(*EmptyMessage, CitiesService_ListStreamServer) error {
-	return status.Errorf(codes.Unimplemented, "method ListStream not implemented")
+// UnimplementedAdminServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedAdminServiceServer struct {
 }
-func (*UnimplementedCitiesServiceServer) List(context.Context, *EmptyMessage) (*Cities, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+
+func (*UnimplementedAdminServiceServer) GetMaintenance(context.Context, *EmptyMessage) (*Maintenance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMaintenance not implemented")
+}
+func (*UnimplementedAdminServiceServer) SetMaintenance(context.Context, *Maintenance) (*Maintenance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMaintenance not implemented")
+}
+func (*UnimplementedAdminServiceServer) CancelCall(context.Context, *CancelCallRequest) (*CancelCallResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelCall not implemented")
+}
+func (*UnimplementedAdminServiceServer) GetLatency(context.Context, *EmptyMessage) (*Latency, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLatency not implemented")
+}
+func (*UnimplementedAdminServiceServer) SetLatency(context.Context, *Latency) (*Latency, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLatency not implemented")
+}
+func (*UnimplementedAdminServiceServer) GetStaticSnapshot(context.Context, *EmptyMessage) (*StaticSnapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStaticSnapshot not implemented")
+}
+func (*UnimplementedAdminServiceServer) SetStaticSnapshot(context.Context, *StaticSnapshot) (*StaticSnapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetStaticSnapshot not implemented")
+}
+func (*UnimplementedAdminServiceServer) ReloadConfig(context.Context, *EmptyMessage) (*EmptyMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadConfig not implemented")
 }
 
-func RegisterCitiesServiceServer(s *grpc.Server, srv CitiesServiceServer) {
-	s.RegisterService(&_CitiesService_serviceDesc, srv)
+func RegisterAdminServiceServer(s *grpc.Server, srv AdminServiceServer) {
+	s.RegisterService(&_AdminService_serviceDesc, srv)
 }
 
-func _CitiesService_ListStream_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(EmptyMessage)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
+func _AdminService_GetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyMessage)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	return srv.(CitiesServiceServer).ListStream(m, &citiesServiceListStreamServer{stream})
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.AdminService/GetMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetMaintenance(ctx, req.(*EmptyMessage))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type CitiesService_ListStreamServer interface {
-	Send(*CityStream) error
-	grpc.ServerStream
+func _AdminService_SetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Maintenance)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.AdminService/SetMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetMaintenance(ctx, req.(*Maintenance))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type citiesServiceListStreamServer struct {
-	grpc.ServerStream
+func _AdminService_CancelCall_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelCallRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CancelCall(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.AdminService/CancelCall",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CancelCall(ctx, req.(*CancelCallRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (x *citiesServiceListStreamServer) Send(m *CityStream) error {
-	return x.ServerStream.SendMsg(m)
+func _AdminService_GetLatency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetLatency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.AdminService/GetLatency",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetLatency(ctx, req.(*EmptyMessage))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _CitiesService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _AdminService_SetLatency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Latency)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetLatency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.AdminService/SetLatency",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetLatency(ctx, req.(*Latency))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetStaticSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(EmptyMessage)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CitiesServiceServer).List(ctx, in)
+		return srv.(AdminServiceServer).GetStaticSnapshot(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/cities.CitiesService/List",
+		FullMethod: "/cities.AdminService/GetStaticSnapshot",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CitiesServiceServer).List(ctx, req.(*EmptyMessage))
+		return srv.(AdminServiceServer).GetStaticSnapshot(ctx, req.(*EmptyMessage))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-var _CitiesService_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "cities.CitiesService",
-	HandlerType: (*CitiesServiceServer)(nil),
+func _AdminService_SetStaticSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StaticSnapshot)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetStaticSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.AdminService/SetStaticSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetStaticSnapshot(ctx, req.(*StaticSnapshot))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ReloadConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ReloadConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cities.AdminService/ReloadConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ReloadConfig(ctx, req.(*EmptyMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _AdminService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cities.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "List",
-			Handler:    _CitiesService_List_Handler,
+			MethodName: "GetMaintenance",
+			Handler:    _AdminService_GetMaintenance_Handler,
 		},
-	},
-	Streams: []grpc.StreamDesc{
 		{
-			StreamName:    "ListStream",
-			Handler:       _CitiesService_ListStream_Handler,
-			ServerStreams: true,
+			MethodName: "SetMaintenance",
+			Handler:    _AdminService_SetMaintenance_Handler,
+		},
+		{
+			MethodName: "CancelCall",
+			Handler:    _AdminService_CancelCall_Handler,
+		},
+		{
+			MethodName: "GetLatency",
+			Handler:    _AdminService_GetLatency_Handler,
+		},
+		{
+			MethodName: "SetLatency",
+			Handler:    _AdminService_SetLatency_Handler,
+		},
+		{
+			MethodName: "GetStaticSnapshot",
+			Handler:    _AdminService_GetStaticSnapshot_Handler,
+		},
+		{
+			MethodName: "SetStaticSnapshot",
+			Handler:    _AdminService_SetStaticSnapshot_Handler,
+		},
+		{
+			MethodName: "ReloadConfig",
+			Handler:    _AdminService_ReloadConfig_Handler,
 		},
 	},
+	Streams:  []grpc.StreamDesc{},
 	Metadata: "cities.proto",
 }
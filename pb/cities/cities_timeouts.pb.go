@@ -0,0 +1,29 @@
+// Code generated by protoc-gen-timeouts. DO NOT EDIT.
+// source: cities.proto
+
+package cities
+
+import (
+	time "time"
+)
+
+// MethodTimeouts maps the full name of every method setting (timeouts.max)
+// to that timeout.
+var MethodTimeouts = map[string]time.Duration{
+	"/cities.CitiesService/ListStream":       60000000000,  // 60s
+	"/cities.CitiesService/List":             10000000000,  // 10s
+	"/cities.CitiesService/ListPage":         10000000000,  // 10s
+	"/cities.CitiesService/Create":           5000000000,   // 5s
+	"/cities.CitiesService/Stats":            15000000000,  // 15s
+	"/cities.CitiesService/TransformCities":  300000000000, // 5m
+	"/cities.CitiesService/ExportCities":     300000000000, // 5m
+	"/cities.CitiesService/GetServerInfo":    5000000000,   // 5s
+	"/cities.AdminService/GetMaintenance":    5000000000,   // 5s
+	"/cities.AdminService/SetMaintenance":    5000000000,   // 5s
+	"/cities.AdminService/CancelCall":        5000000000,   // 5s
+	"/cities.AdminService/GetLatency":        5000000000,   // 5s
+	"/cities.AdminService/SetLatency":        5000000000,   // 5s
+	"/cities.AdminService/GetStaticSnapshot": 5000000000,   // 5s
+	"/cities.AdminService/SetStaticSnapshot": 5000000000,   // 5s
+	"/cities.AdminService/ReloadConfig":      5000000000,   // 5s
+}
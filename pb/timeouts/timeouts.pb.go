@@ -0,0 +1,94 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.13.0
+// source: timeouts/timeouts.proto
+
+package timeouts
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+var file_timeouts_timeouts_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50001,
+		Name:          "timeouts.max",
+		Tag:           "bytes,50001,opt,name=max",
+		Filename:      "timeouts/timeouts.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// max is the longest a handler may run, as a Go duration string ("2s").
+	// The server shortens the context of every call to this method to it.
+	//
+	// optional string max = 50001;
+	E_Max = &file_timeouts_timeouts_proto_extTypes[0]
+)
+
+var File_timeouts_timeouts_proto protoreflect.FileDescriptor
+
+var file_timeouts_timeouts_proto_rawDesc = []byte{
+	0x0a, 0x17, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x73, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x73, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3a, 0x32, 0x0a, 0x03, 0x6d, 0x61, 0x78, 0x12, 0x1e, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d,
+	0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd1, 0x86, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x61, 0x78, 0x42, 0x20, 0x5a, 0x1e, 0x67, 0x6f, 0x2d,
+	0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x2f, 0x70, 0x62, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75,
+	0x74, 0x73, 0x3b, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
+}
+
+var file_timeouts_timeouts_proto_goTypes = []interface{}{
+	(*descriptorpb.MethodOptions)(nil), // 0: google.protobuf.MethodOptions
+}
+var file_timeouts_timeouts_proto_depIdxs = []int32{
+	0, // 0: timeouts.max:extendee -> google.protobuf.MethodOptions
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_timeouts_timeouts_proto_init() }
+func file_timeouts_timeouts_proto_init() {
+	if File_timeouts_timeouts_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_timeouts_timeouts_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   0,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_timeouts_timeouts_proto_goTypes,
+		DependencyIndexes: file_timeouts_timeouts_proto_depIdxs,
+		ExtensionInfos:    file_timeouts_timeouts_proto_extTypes,
+	}.Build()
+	File_timeouts_timeouts_proto = out.File
+	file_timeouts_timeouts_proto_rawDesc = nil
+	file_timeouts_timeouts_proto_goTypes = nil
+	file_timeouts_timeouts_proto_depIdxs = nil
+}